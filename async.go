@@ -0,0 +1,61 @@
+package timesf
+
+import (
+	"sync"
+	"time"
+)
+
+// Call 是DoAsync返回的一次调用的句柄。
+type Call struct {
+	key    string
+	done   chan struct{}
+	stop   chan struct{}
+	once   sync.Once
+	cancel func()
+	// r 在done被关闭之前写入，之后不再改变。
+	r Result
+}
+
+// DoAsync 像DoChan方法，但是返回一个句柄而不是通道，可以在不取走结果的情况下检查调用是否
+// 完成，也可以取消对结果的等待，见Call。
+func (g *Group) DoAsync(key string, validTime time.Duration, fn func() (interface{}, error)) *Call {
+	ch, cancel := g.DoChanCancel(key, validTime, fn)
+	a := &Call{key: key, done: make(chan struct{}), stop: make(chan struct{}), cancel: cancel}
+	go func() {
+		select {
+		case a.r = <-ch:
+			close(a.done)
+		case <-a.stop:
+		}
+	}()
+	return a
+}
+
+// Key 返回调用的key。
+func (a *Call) Key() string {
+	return a.key
+}
+
+// Done 返回一个在结果可以拿到时被关闭的通道。调用被取消之后此通道不会被关闭。
+func (a *Call) Done() <-chan struct{} {
+	return a.done
+}
+
+// Result 返回调用的结果，ok为false表示调用还没有完成或者已经被取消，不会阻塞。
+func (a *Call) Result() (r Result, ok bool) {
+	select {
+	case <-a.done:
+		return a.r, true
+	default:
+		return Result{}, false
+	}
+}
+
+// Cancel 取消对结果的等待，见DoChanCancel：正在进行的调用不会被取消，其他调用者不受
+// 影响。已经拿到结果之后，或者重复调用时什么都不做。
+func (a *Call) Cancel() {
+	a.once.Do(func() {
+		a.cancel()
+		close(a.stop)
+	})
+}
@@ -0,0 +1,56 @@
+package timesf
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDoAsync(t *testing.T) {
+	var g Group
+	release := make(chan struct{})
+	fn := func() (interface{}, error) {
+		<-release
+		return "bar", nil
+	}
+	a := g.DoAsync("key", time.Hour, fn)
+	b := g.DoAsync("key", time.Hour, fn)
+	canceled := g.DoAsync("key", time.Hour, fn)
+	if a.Key() != "key" {
+		t.Errorf("Key = %q; want key", a.Key())
+	}
+	if _, ok := a.Result(); ok {
+		t.Error("Result before completion ok = true; want false")
+	}
+	canceled.Cancel()
+	canceled.Cancel()
+	if n := g.InFlight()["key"]; n != 1 {
+		t.Errorf("dups after Cancel = %d; want 1", n)
+	}
+	close(release)
+
+	for _, c := range []*Call{a, b} {
+		<-c.Done()
+		if r, ok := c.Result(); !ok || r.Val != "bar" || !r.Shared {
+			t.Errorf("Result = %+v, %v; want shared bar, true", r, ok)
+		}
+	}
+	if _, ok := canceled.Result(); ok {
+		t.Error("canceled Result ok = true; want false")
+	}
+	select {
+	case <-canceled.Done():
+		t.Error("canceled Done was closed")
+	default:
+	}
+
+	// 完成之后取消什么都不做
+	a.Cancel()
+	if r, ok := a.Result(); !ok || r.Val != "bar" {
+		t.Errorf("Result after Cancel = %+v, %v; want bar, true", r, ok)
+	}
+	hit := g.DoAsync("key", time.Hour, fn)
+	<-hit.Done()
+	if r, _ := hit.Result(); r.Role != RoleCachedHit {
+		t.Errorf("hit role = %v; want cached hit", r.Role)
+	}
+}
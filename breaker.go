@@ -0,0 +1,77 @@
+package timesf
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrCircuitOpen 在key的熔断器打开时返回给需要开始新调用的调用者，见WithCircuitBreaker。
+var ErrCircuitOpen = errors.New("timesf: circuit open")
+
+// breakerPolicy 是WithCircuitBreaker设置的熔断策略。
+type breakerPolicy struct {
+	threshold int
+	window    time.Duration
+	cooldown  time.Duration
+}
+
+// breaker 记录一个key连续失败的调用，只有拿到锁时才进行读写。
+type breaker struct {
+	// failures 是连续失败的次数，first 是其中第一次失败的时间。
+	failures int
+	first    int64
+	// openUntil 不为0时熔断器已经打开，在此之前不再开始新的调用。
+	openUntil int64
+}
+
+// WithCircuitBreaker 为每一个key开启熔断：window时长内连续threshold次调用返回错误之后，
+// 熔断器打开，cooldown时长内需要开始新调用的调用者立即拿到ErrCircuitOpen，而不执行
+// 方法。cooldown之后的第一个调用作为试探，其他调用者像平常一样共享其结果；试探成功时
+// 熔断器关闭，失败时再次打开cooldown时长。window不大于0时不限制连续失败的时长。还未
+// 过期的结果仍然可以被返回，后台刷新的结果不计入失败次数。
+func WithCircuitBreaker(threshold int, window, cooldown time.Duration) Option {
+	return func(g *Group) {
+		g.breaker = &breakerPolicy{threshold: threshold, window: window, cooldown: cooldown}
+	}
+}
+
+// circuitOpen 报告key的熔断器是否打开，调用者需要持有锁。
+func (g *Group) circuitOpen(key string, now int64) bool {
+	if g.breaker == nil {
+		return false
+	}
+	b := g.breakers[key]
+	return b != nil && now < b.openUntil
+}
+
+// recordOutcome 在key的调用完成时更新其熔断器，err是调用的错误，调用者需要持有锁。
+func (g *Group) recordOutcome(key string, err error) {
+	p := g.breaker
+	if p == nil {
+		return
+	}
+	if err == nil {
+		delete(g.breakers, key)
+		return
+	}
+	now := g.now()
+	b := g.breakers[key]
+	if b == nil {
+		if g.breakers == nil {
+			g.breakers = make(map[string]*breaker)
+		}
+		b = &breaker{}
+		g.breakers[key] = b
+	}
+	if b.openUntil != 0 { // 试探失败，再次打开
+		b.openUntil = now + int64(p.cooldown)
+		return
+	}
+	if b.failures == 0 || p.window > 0 && now-b.first > int64(p.window) {
+		b.failures, b.first = 0, now
+	}
+	b.failures++
+	if b.failures >= p.threshold {
+		b.openUntil = now + int64(p.cooldown)
+	}
+}
@@ -0,0 +1,82 @@
+package timesf
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithClock(clock), WithCircuitBreaker(3, time.Minute, 10*time.Second))
+	someErr := errors.New("some error")
+	calls := 0
+	failing := true
+	fn := func() (interface{}, error) {
+		calls++
+		if failing {
+			return nil, someErr
+		}
+		return "bar", nil
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err, _ := g.Do("key", time.Hour, fn); err != someErr {
+			t.Fatalf("Do #%d err = %v; want %v", i, err, someErr)
+		}
+	}
+	if _, err, _ := g.Do("key", time.Hour, fn); err != ErrCircuitOpen {
+		t.Errorf("Do with open circuit err = %v; want ErrCircuitOpen", err)
+	}
+	if r := <-g.DoChan("key", time.Hour, fn); r.Err != ErrCircuitOpen {
+		t.Errorf("DoChan with open circuit err = %v; want ErrCircuitOpen", r.Err)
+	}
+	if r := g.DoMulti([]string{"key"}, time.Hour, nil)["key"]; r.Err != ErrCircuitOpen {
+		t.Errorf("DoMulti with open circuit err = %v; want ErrCircuitOpen", r.Err)
+	}
+	if calls != 3 {
+		t.Errorf("fn called %d times while open; want 3", calls)
+	}
+	if _, err, _ := g.Do("other", time.Hour, fn); err != someErr {
+		t.Errorf("Do other key err = %v; want %v", err, someErr)
+	}
+
+	// 试探失败，再次打开。
+	clock.Advance(10 * time.Second)
+	if _, err, _ := g.Do("key", time.Hour, fn); err != someErr {
+		t.Errorf("failed trial err = %v; want %v", err, someErr)
+	}
+	if _, err, _ := g.Do("key", time.Hour, fn); err != ErrCircuitOpen {
+		t.Errorf("Do after failed trial err = %v; want ErrCircuitOpen", err)
+	}
+
+	// 试探成功，关闭。
+	clock.Advance(10 * time.Second)
+	failing = false
+	if v, err, _ := g.Do("key", time.Hour, fn); v != "bar" || err != nil {
+		t.Errorf("successful trial = %v, %v; want bar, nil", v, err)
+	}
+	g.Forget("key")
+	failing = true
+	if _, err, _ := g.Do("key", time.Hour, fn); err != someErr {
+		t.Errorf("Do after recovery err = %v; want %v", err, someErr)
+	}
+}
+
+func TestCircuitBreakerWindow(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithClock(clock), WithCircuitBreaker(2, time.Second, time.Minute))
+	someErr := errors.New("some error")
+	fn := func() (interface{}, error) {
+		return nil, someErr
+	}
+	g.Do("key", time.Hour, fn)
+	clock.Advance(2 * time.Second)
+	g.Do("key", time.Hour, fn)
+	if _, err, _ := g.Do("key", time.Hour, fn); err != someErr {
+		t.Errorf("Do with failures outside window err = %v; want %v", err, someErr)
+	}
+	if _, err, _ := g.Do("key", time.Hour, fn); err != ErrCircuitOpen {
+		t.Errorf("Do after failures within window err = %v; want ErrCircuitOpen", err)
+	}
+}
@@ -0,0 +1,30 @@
+package timesf
+
+import "time"
+
+// WithErrorClassifier 按错误决定是否缓存返回错误的结果，比如缓存“记录不存在”，而不缓存
+// 连接超时：对方法返回的每一个错误调用classify，cacheable为true并且ttl大于0时结果被缓存
+// ttl时长，否则结果立即被删除，之后的调用会重新执行方法。classify不会拿到nil错误，在
+// 不持有锁时被调用，panic时按不缓存处理。设置之后代替WithErrorCaching和WithErrorTTL，
+// 但是DoWithTTLs按调用指定的错误有效时长仍然优先。
+func WithErrorClassifier(classify func(err error) (cacheable bool, ttl time.Duration)) Option {
+	return func(g *Group) {
+		g.classifier = classify
+	}
+}
+
+// classify 按照WithErrorClassifier为返回了错误的调用c设置错误的有效时长。只由执行方法
+// 的协程在完成之前调用。
+func (g *Group) classify(c *call) {
+	if g.classifier == nil || c.err == nil || c.hasErrorTTL {
+		return
+	}
+	c.hasErrorTTL = true
+	c.errorTTL = 0
+	defer func() {
+		recover()
+	}()
+	if cacheable, ttl := g.classifier(c.err); cacheable && ttl > 0 {
+		c.errorTTL = ttl
+	}
+}
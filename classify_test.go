@@ -0,0 +1,76 @@
+package timesf
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWithErrorClassifier(t *testing.T) {
+	clock := newFakeClock()
+	errNotFound := errors.New("not found")
+	errTimeout := errors.New("timeout")
+	var classified []error
+	g := New(WithClock(clock), WithErrorClassifier(func(err error) (bool, time.Duration) {
+		classified = append(classified, err)
+		switch {
+		case errors.Is(err, errNotFound):
+			return true, 5 * time.Second
+		case err.Error() == "panic":
+			panic("classifier panicked")
+		}
+		return false, 0
+	}))
+	calls := 0
+	failWith := func(err error) func() (interface{}, error) {
+		return func() (interface{}, error) {
+			calls++
+			return nil, err
+		}
+	}
+
+	g.Do("missing", time.Hour, failWith(errNotFound))
+	if ttl, ok := g.TTL("missing"); !ok || ttl != 5*time.Second {
+		t.Errorf("TTL of not found = %v, %v; want 5s, true", ttl, ok)
+	}
+	if _, err, _ := g.Do("missing", time.Hour, failWith(errNotFound)); err != errNotFound || calls != 1 {
+		t.Errorf("Do(missing) = %v (calls %d); want cached not found", err, calls)
+	}
+	clock.Advance(6 * time.Second)
+	g.Do("missing", time.Hour, failWith(errNotFound))
+	if calls != 2 {
+		t.Errorf("calls after error TTL = %d; want 2", calls)
+	}
+
+	g.Do("slow", time.Hour, failWith(errTimeout))
+	if g.Has("slow") {
+		t.Error("non-cacheable error was cached")
+	}
+	g.Do("bad", time.Hour, failWith(errors.New("panic")))
+	if g.Has("bad") {
+		t.Error("error whose classifier panicked was cached")
+	}
+
+	// 成功的结果不会被分类，按调用指定的错误有效时长优先
+	g.Do("ok", time.Hour, func() (interface{}, error) { return 1, nil })
+	n := len(classified)
+	g.DoWithTTLs("explicit", time.Hour, time.Minute, failWith(errTimeout))
+	if ttl, ok := g.TTL("explicit"); !ok || ttl != time.Minute {
+		t.Errorf("TTL with DoWithTTLs = %v, %v; want 1m, true", ttl, ok)
+	}
+	if len(classified) != n {
+		t.Errorf("classifier called %d more times; want 0", len(classified)-n)
+	}
+	for _, err := range classified {
+		if err == nil {
+			t.Error("classifier called with nil error")
+		}
+	}
+
+	res := g.DoMulti([]string{"m"}, time.Hour, func([]string) (map[string]interface{}, error) {
+		return nil, errNotFound
+	})
+	if ttl, ok := g.TTL("m"); res["m"].Err != errNotFound || !ok || ttl != 5*time.Second {
+		t.Errorf("DoMulti not found TTL = %v, %v; want 5s, true", ttl, ok)
+	}
+}
@@ -0,0 +1,53 @@
+package timesf
+
+import "time"
+
+// Clock 提供Group计算有效期所用的当前时间，可以用来在测试中控制时间。
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc 将一个返回当前时间的函数转换为Clock。
+type ClockFunc func() time.Time
+
+// Now 调用f返回当前时间。
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// WithClock 设置Group使用的时钟，默认使用系统时间。和默认时钟一样，Now返回的时间
+// 带有单调时钟读数时（比如来自time.Now），有效期按照单调时钟计算，不受系统时间被向前或者
+// 向后调整的影响；没有单调时钟读数时按照其表示的时间计算。
+func WithClock(c Clock) Option {
+	return func(g *Group) {
+		g.clock = c
+	}
+}
+
+// monoBase 是计算纳秒时间戳的基准，见stamp。
+var monoBase = time.Now()
+
+// now 返回当前时间的纳秒时间戳，见stamp。
+func (g *Group) now() int64 {
+	if g.clock == nil {
+		return stamp(time.Now())
+	}
+	return stamp(g.clock.Now())
+}
+
+// deadline 返回时刻t在now的时间戳中对应的值。t通常没有单调时钟读数，比如来自HTTP响应
+// 的Expires，不能直接用t.UnixNano()和now比较：系统时间被调整之后两者会相差调整的时长。
+// 这里按照Group时钟的当前时间计算距离t的时长，再加到当前的时间戳上。
+func (g *Group) deadline(t time.Time) int64 {
+	now := time.Now()
+	if g.clock != nil {
+		now = g.clock.Now()
+	}
+	return addTime(stamp(now), t.Sub(now))
+}
+
+// stamp 返回t的纳秒时间戳。t带有单调时钟读数时，时间戳是monoBase加上经过的单调时间，
+// 系统时间的调整不会让时间戳跳变；否则等于t.UnixNano()。
+func stamp(t time.Time) int64 {
+	return monoBase.UnixNano() + int64(t.Sub(monoBase))
+}
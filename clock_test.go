@@ -0,0 +1,110 @@
+package timesf
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock 是测试用的时钟，只有调用Advance时时间才会前进。
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Unix(1000, 0)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+// waitFor 等待后台协程使cond成立，超时则测试失败。
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestWithClock(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithClock(clock))
+	var calls int
+	fn := func() (interface{}, error) {
+		calls++
+		return calls, nil
+	}
+
+	g.Do("key", time.Minute, fn)
+	clock.Advance(59 * time.Second)
+	if v, _, _ := g.Do("key", time.Minute, fn); v != 1 {
+		t.Errorf("Do before expiry = %v; want 1", v)
+	}
+	if ttl, ok := g.TTL("key"); !ok || ttl != time.Second {
+		t.Errorf("TTL = %v, %v; want 1s, true", ttl, ok)
+	}
+	clock.Advance(time.Second)
+	if v, _, _ := g.Do("key", time.Minute, fn); v != 2 {
+		t.Errorf("Do at expiry = %v; want 2", v)
+	}
+}
+
+func TestClockFunc(t *testing.T) {
+	now := time.Unix(1000, 0)
+	g := New(WithClock(ClockFunc(func() time.Time { return now })))
+	g.Do("key", time.Minute, func() (interface{}, error) {
+		return nil, nil
+	})
+	now = now.Add(30 * time.Second)
+	if ttl, ok := g.TTL("key"); !ok || ttl != 30*time.Second {
+		t.Errorf("TTL = %v, %v; want 30s, true", ttl, ok)
+	}
+}
+
+func TestClockMonotonic(t *testing.T) {
+	// 带有单调时钟读数的时间按照经过的单调时间计算时间戳
+	start := time.Now()
+	later := start.Add(time.Minute)
+	if d := stamp(later) - stamp(start); d != int64(time.Minute) {
+		t.Errorf("stamp difference = %v; want 1m", time.Duration(d))
+	}
+	// 没有单调时钟读数的时间按照其表示的时间计算，和UnixNano一致
+	if wall := later.Round(0); stamp(wall) != wall.UnixNano() {
+		t.Errorf("stamp of wall time = %d; want %d", stamp(wall), wall.UnixNano())
+	}
+
+	// 返回带有单调时钟读数的时间的时钟，结果按照经过的单调时间过期
+	var mu sync.Mutex
+	now := start
+	g := New(WithClock(ClockFunc(func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	})))
+	calls := 0
+	fn := func() (interface{}, error) {
+		calls++
+		return calls, nil
+	}
+	g.Do("key", time.Minute, fn)
+	mu.Lock()
+	now = now.Add(time.Minute)
+	mu.Unlock()
+	if v, _, _ := g.Do("key", time.Minute, fn); v != 2 {
+		t.Errorf("Do after a minute = %v; want 2", v)
+	}
+}
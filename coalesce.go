@@ -0,0 +1,32 @@
+package timesf
+
+import "time"
+
+// WithCoalesceWindow 在调用完成之后的d时长内保留其结果，即使结果不会被缓存，比如没有
+// 开启错误缓存时返回的错误、严格模式下有效时长为0的结果，或者有效时长比d更短的结果。
+// 在调用完成前后几乎同时到达的调用者共享刚刚完成的结果，而不是再执行一次方法。被调用者
+// 的ctx取消的结果不会被保留，见DoCtx。d不大于0时不保留，这是默认值。
+func WithCoalesceWindow(d time.Duration) Option {
+	return func(g *Group) {
+		g.coalesceWindow = d
+	}
+}
+
+// release 删除key不应该被缓存的结果c；设置了WithCoalesceWindow时改为保留到窗口结束。
+// 调用者需要持有锁。
+func (g *Group) release(key string, c *call) {
+	if g.coalesceWindow > 0 {
+		g.t[key] = addTime(c.doneAt, g.coalesceWindow)
+		return
+	}
+	g.drop(key, c)
+}
+
+// graced 将已完成的调用c的过期时间t延长到WithCoalesceWindow的窗口结束，调用者需要
+// 持有锁。
+func (g *Group) graced(c *call, t int64) int64 {
+	if w := addTime(c.doneAt, g.coalesceWindow); g.coalesceWindow > 0 && t < w {
+		return w
+	}
+	return t
+}
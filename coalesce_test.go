@@ -0,0 +1,67 @@
+package timesf
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCoalesceWindow(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithClock(clock), WithCoalesceWindow(10*time.Millisecond))
+	someErr := errors.New("some error")
+	var calls int32
+	release := make(chan struct{})
+	started := make(chan struct{})
+	fn := func() (interface{}, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(started)
+			<-release
+		}
+		return nil, someErr
+	}
+
+	// 一半的调用者在完成之前到达，一半在完成之后到达。
+	var wg sync.WaitGroup
+	call := func() {
+		defer wg.Done()
+		if _, err, _ := g.Do("key", time.Hour, fn); err != someErr {
+			t.Errorf("Do err = %v; want %v", err, someErr)
+		}
+	}
+	wg.Add(1)
+	go call()
+	<-started
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go call()
+	}
+	waitFor(t, func() bool { return g.InFlight()["key"] == 5 })
+	close(release)
+	wg.Wait()
+	clock.Advance(5 * time.Millisecond)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go call()
+	}
+	wg.Wait()
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("fn called %d times inside the window; want 1", n)
+	}
+
+	clock.Advance(5 * time.Millisecond)
+	g.Do("key", time.Hour, fn)
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Errorf("fn called %d times after the window; want 2", n)
+	}
+
+	// 有效时长比窗口短的结果同样保留到窗口结束。
+	g.Do("short", time.Millisecond, func() (interface{}, error) {
+		return "v", nil
+	})
+	if ttl, _ := g.TTL("short"); ttl != 10*time.Millisecond {
+		t.Errorf("TTL of short result = %v; want 10ms", ttl)
+	}
+}
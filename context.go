@@ -0,0 +1,185 @@
+package timesf
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// DoCtx 像Do方法，但是fn接收一个上下文。fn拿到的上下文保留执行方法的调用者的ctx中的值，
+// 比如追踪信息，但是不随ctx被取消，也没有其截止时间：任何一个调用者的ctx被取消时，只有
+// 这个调用者不再等待并拿到ctx.Err()，方法继续为其他调用者执行。所有等待此调用的调用者
+// 都离开之后，fn的上下文才被取消，结果不会被缓存，之后的调用会重新执行方法。Do和DoChan
+// 等没有上下文的调用者会一直等待，因此方法不会被取消，DoChanCancel的取消同样算作离开。
+// 方法使用其上下文再次调用自己的key时拿到ErrRecursiveDo，而不是死锁。
+func (g *Group) DoCtx(ctx context.Context, key string, validTime time.Duration, fn func(ctx context.Context) (interface{}, error)) (v interface{}, err error, shared bool) {
+	r, _, _ := g.do(key, params{validTime: validTime, ctxFn: fn, ctx: ctx}, nil)
+	return r.Val, r.Err, r.Shared
+}
+
+// ForgetAndCancel 像ForgetAndNotify方法，同时取消key正在执行的方法的上下文，包括后台的
+// 刷新，让已经没有意义的方法尽早停止。等待其结果的调用者立即拿到ErrForgotten，执行方法
+// 的调用者拿到方法的结果，通常是其上下文的错误context.Canceled，结果不会被缓存。只有
+// DoCtx执行的方法能够感知取消，其他的调用像ForgetAndNotify一样继续执行到完成。Forget
+// 和ForgetAndNotify不会取消方法。返回key是否存在。
+func (g *Group) ForgetAndCancel(key string) bool {
+	return g.forgetAndNotify(key, true)
+}
+
+// ForgetAndWait 像Forget方法，同时等到key正在执行的方法完成之后再返回，包括后台的刷新，
+// 返回之后不会再有这些方法在执行，被遗忘的调用也不会写入结果。key不存在或者没有正在执行
+// 的方法时立即返回nil。ctx被取消时不再等待，返回ctx.Err()。在DoCtx执行的方法中使用其
+// 上下文调用时不会等待调用本身，因此不会死锁；其他方法中调用时需要使用有超时的ctx。
+func (g *Group) ForgetAndWait(ctx context.Context, key string) error {
+	key = g.normalize(key)
+	var evs []eviction
+	var ready []<-chan struct{}
+	g.mu.Lock()
+	c, ok := g.m[key]
+	if ok {
+		for _, rc := range []*call{c, c.refreshing} {
+			if rc != nil && !rc.done && current(ctx) != rc {
+				ready = append(ready, rc.readyChan())
+			}
+		}
+		evs = g.forget(evs, key, c)
+	}
+	g.mu.Unlock()
+	g.notifyEvicted(evs)
+	for _, ch := range ready {
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// callKey 是传给方法的上下文中保存其调用的键，值是*frame，见ForgetAndWait。
+type callKey struct{}
+
+// frame 是方法的上下文中的调用c，up 是在其方法中开始c的调用的frame，没有时为nil。
+type frame struct {
+	c  *call
+	up *frame
+}
+
+// current 返回方法的上下文ctx属于的调用，不是方法的上下文时返回nil。
+func current(ctx context.Context) *call {
+	if f, _ := ctx.Value(callKey{}).(*frame); f != nil {
+		return f.c
+	}
+	return nil
+}
+
+// cancelable 在c的方法接收上下文时，创建传给方法的可以取消的上下文，其值来自c.ctx，但是
+// 不随其被取消。c.ctx不为nil时开始对调用者计数，见DoCtx。调用者需要持有锁，并且c刚刚
+// 被创建。
+func (c *call) cancelable() {
+	if c.ctxFn == nil {
+		return
+	}
+	var parent context.Context = context.Background()
+	if c.ctx != nil {
+		parent = detached{c.ctx}
+		c.interest = 1
+	}
+	up, _ := parent.Value(callKey{}).(*frame)
+	c.fnCtx, c.cancel = context.WithCancel(context.WithValue(parent, callKey{}, &frame{c, up}))
+}
+
+// ErrRecursiveDo 在DoCtx执行的方法使用其上下文，再次调用正在等待的key时返回，比如
+// 方法直接或者经过其他key间接地调用了自己的key，等待只会造成死锁。没有使用方法的上下文
+// 的调用无法被识别。
+var ErrRecursiveDo = errors.New("timesf: recursive call for a key being computed")
+
+// within 报告ctx是否属于正在进行的调用c的方法，包括c的方法中开始的调用的方法。
+func within(ctx context.Context, c *call) bool {
+	if ctx == nil {
+		return false
+	}
+	f, _ := ctx.Value(callKey{}).(*frame)
+	for ; f != nil; f = f.up {
+		if f.c == c {
+			return true
+		}
+	}
+	return false
+}
+
+// detached 保留parent的值，但是不会随其被取消，也没有截止时间，相当于Go 1.21的
+// context.WithoutCancel。
+type detached struct {
+	parent context.Context
+}
+
+func (detached) Deadline() (time.Time, bool)         { return time.Time{}, false }
+func (detached) Done() <-chan struct{}               { return nil }
+func (detached) Err() error                          { return nil }
+func (d detached) Value(key interface{}) interface{} { return d.parent.Value(key) }
+
+// join 记录又一个调用者开始等待正在进行的调用c，调用者需要持有锁。
+func (c *call) join() {
+	if c.interest > 0 && !c.done {
+		c.interest++
+	}
+}
+
+// letGo 记录n个调用者不再等待调用c，err是最后离开的调用者拿到的错误。所有调用者都离开
+// 之后取消方法的上下文，并把c从Group中移除，之后的调用者重新执行方法。调用者需要持有锁。
+func (g *Group) letGo(key string, c *call, n int, err error) {
+	if c.interest <= 0 || c.done {
+		return
+	}
+	if c.interest -= n; c.interest > 0 {
+		return
+	}
+	c.abandoned = err
+	c.cancel()
+	if g.m[key] == c {
+		g.pending--
+		g.untrack(c)
+		delete(g.m, key)
+		delete(g.t, key)
+		g.unpublish(key)
+	}
+}
+
+// leave 像letGo，记录一个调用者因为err不再等待调用c，调用者不能持有锁。
+func (g *Group) leave(key string, c *call, err error) {
+	g.mu.Lock()
+	g.letGo(key, c, 1, err)
+	g.mu.Unlock()
+}
+
+// doCallCtx 像doCall，但是执行方法的调用者在ctx被取消时不再等待，拿到ctx.Err()，方法在
+// 后台继续为其他调用者执行，见DoCtx。
+func (g *Group) doCallCtx(ctx context.Context, c *call, key string, fn func() (interface{}, error)) Result {
+	done := make(chan Result, 1)
+	go func() {
+		done <- g.doCall(c, key, fn)
+	}()
+	select {
+	case r := <-done:
+		return r
+	case <-ctx.Done():
+	}
+	select {
+	case r := <-done:
+		return r
+	default:
+	}
+	g.leave(key, c, ctx.Err())
+	return Result{Err: ctx.Err()}
+}
+
+// cancelRunning 取消c以及其后台刷新正在执行的方法的上下文，调用者需要持有锁。
+func (c *call) cancelRunning() {
+	if !c.done && c.cancel != nil {
+		c.cancel()
+	}
+	if rc := c.refreshing; rc != nil && rc.cancel != nil {
+		rc.cancel()
+	}
+}
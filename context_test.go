@@ -0,0 +1,357 @@
+package timesf
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDoCtxLeaderDeadline(t *testing.T) {
+	g := New(WithErrorCaching(true))
+	release := make(chan struct{})
+	started := make(chan struct{})
+	type traceKey struct{}
+	fn := func(ctx context.Context) (interface{}, error) {
+		close(started)
+		select {
+		case <-release:
+			return ctx.Value(traceKey{}), nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	// 执行方法的调用者的截止时间不会传给方法，其他调用者仍然拿到结果
+	ctx, cancel := context.WithTimeout(context.WithValue(context.Background(), traceKey{}, "trace"), 20*time.Millisecond)
+	defer cancel()
+	leader := make(chan error)
+	go func() {
+		_, err, _ := g.DoCtx(ctx, "key", time.Hour, fn)
+		leader <- err
+	}()
+	<-started
+	dup := g.DoChan("key", time.Hour, nil)
+	if err := <-leader; err != context.DeadlineExceeded {
+		t.Errorf("leader DoCtx err = %v; want DeadlineExceeded", err)
+	}
+	close(release)
+	if r := <-dup; r.Err != nil || r.Val != "trace" {
+		t.Errorf("DoChan sharer = %+v; want the leader's trace value", r)
+	}
+	if v, _, _ := g.Peek("key"); v != "trace" {
+		t.Errorf("Peek = %v; want trace", v)
+	}
+}
+
+func TestDoCtxRefCount(t *testing.T) {
+	var g Group
+	var calls int32
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	canceled := make(chan struct{})
+	fn := func(ctx context.Context) (interface{}, error) {
+		if atomic.AddInt32(&calls, 1) > 1 {
+			return "second", nil
+		}
+		started <- struct{}{}
+		select {
+		case <-release:
+			return "bar", nil
+		case <-ctx.Done():
+			close(canceled)
+			return nil, ctx.Err()
+		}
+	}
+
+	run := func(key string) ([]context.CancelFunc, []chan error) {
+		cancels := make([]context.CancelFunc, 3)
+		errs := make([]chan error, 3)
+		for i := range cancels {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancels[i], errs[i] = cancel, make(chan error, 1)
+			go func(i int) {
+				v, err, _ := g.DoCtx(ctx, key, time.Hour, fn)
+				if err == nil && v != "bar" {
+					err = errors.New("unexpected value")
+				}
+				errs[i] <- err
+			}(i)
+			if i == 0 {
+				<-started
+			}
+		}
+		waitFor(t, func() bool { return g.InFlight()[key] == 2 })
+		return cancels, errs
+	}
+
+	// 取消两个调用者，方法继续执行，剩下的调用者拿到结果
+	cancels, errs := run("key")
+	cancels[0]()
+	cancels[1]()
+	for _, i := range []int{0, 1} {
+		if err := <-errs[i]; err != context.Canceled {
+			t.Errorf("canceled caller %d err = %v; want Canceled", i, err)
+		}
+	}
+	close(release)
+	if err := <-errs[2]; err != nil {
+		t.Errorf("surviving caller err = %v; want nil", err)
+	}
+	if v, _, _ := g.Peek("key"); v != "bar" {
+		t.Errorf("Peek = %v; want bar", v)
+	}
+
+	// 全部取消之后方法的上下文被取消，结果不会被缓存
+	atomic.StoreInt32(&calls, 0)
+	release = make(chan struct{})
+	cancels, errs = run("all")
+	for i, cancel := range cancels {
+		cancel()
+		if err := <-errs[i]; err != context.Canceled {
+			t.Errorf("caller %d err = %v; want Canceled", i, err)
+		}
+	}
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("fn's context was not canceled after every caller left")
+	}
+	if g.Has("all") {
+		t.Error("abandoned call is still recorded")
+	}
+	v, err, _ := g.DoCtx(context.Background(), "all", time.Hour, fn)
+	if v != "second" || err != nil {
+		t.Errorf("DoCtx after abandon = %v, %v; want a new execution", v, err)
+	}
+}
+
+func TestDoCtxLeaderLeaves(t *testing.T) {
+	var g Group
+	var calls int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	fn := func(ctx context.Context) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		close(started)
+		select {
+		case <-release:
+			return "bar", nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	leader := make(chan error)
+	go func() {
+		_, err, _ := g.DoCtx(ctx, "key", time.Hour, fn)
+		leader <- err
+	}()
+	<-started
+	waiter := make(chan interface{})
+	go func() {
+		v, _, _ := g.DoCtx(context.Background(), "key", time.Hour, fn)
+		waiter <- v
+	}()
+	waitFor(t, func() bool { return g.InFlight()["key"] == 1 })
+	cancel()
+	if err := <-leader; err != context.Canceled {
+		t.Errorf("leader err = %v; want Canceled", err)
+	}
+
+	// 执行方法的调用者离开之后，之后到达的调用者仍然共享同一次执行
+	late := g.DoChan("key", time.Hour, nil)
+	close(release)
+	if v := <-waiter; v != "bar" {
+		t.Errorf("waiter = %v; want bar", v)
+	}
+	if r := <-late; r.Val != "bar" || !r.Shared {
+		t.Errorf("late caller = %+v; want shared bar", r)
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("fn called %d times; want 1", n)
+	}
+}
+
+func TestDoCtxWaiterCancel(t *testing.T) {
+	var g Group
+	release := make(chan struct{})
+	started := make(chan struct{})
+	ch := g.DoChan("key", time.Hour, func() (interface{}, error) {
+		close(started)
+		<-release
+		return "bar", nil
+	})
+	<-started
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err, _ := g.DoCtx(ctx, "key", time.Hour, nil); err != context.Canceled {
+		t.Errorf("canceled waiter err = %v; want Canceled", err)
+	}
+	close(release)
+	if r := <-ch; r.Val != "bar" || r.Err != nil {
+		t.Errorf("leader result = %+v; want bar", r)
+	}
+	if v, _, _ := g.Peek("key"); v != "bar" {
+		t.Errorf("Peek = %v; want bar", v)
+	}
+}
+
+func TestForgetAndCancel(t *testing.T) {
+	var g Group
+	started := make(chan struct{})
+	fn := func(ctx context.Context) (interface{}, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	owner := make(chan error)
+	go func() {
+		_, err, _ := g.DoCtx(context.Background(), "key", time.Hour, fn)
+		owner <- err
+	}()
+	<-started
+	waiter := make(chan error)
+	go func() {
+		_, err, _ := g.DoCtx(context.Background(), "key", time.Hour, fn)
+		waiter <- err
+	}()
+	waitFor(t, func() bool { return g.InFlight()["key"] == 1 })
+	ch := g.DoChan("key", time.Hour, nil)
+
+	if !g.ForgetAndCancel("key") {
+		t.Fatal("ForgetAndCancel = false; want true")
+	}
+	if err := <-owner; err != context.Canceled {
+		t.Errorf("owner err = %v; want context.Canceled", err)
+	}
+	if err := <-waiter; err != ErrForgotten {
+		t.Errorf("waiter err = %v; want ErrForgotten", err)
+	}
+	if r := <-ch; r.Err != ErrForgotten {
+		t.Errorf("DoChan err = %v; want ErrForgotten", r.Err)
+	}
+	if g.Has("key") {
+		t.Error("canceled result was cached")
+	}
+	if g.ForgetAndCancel("key") {
+		t.Error("ForgetAndCancel on missing key = true; want false")
+	}
+
+	// Forget不取消方法
+	release := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		_, err, _ := g.DoCtx(context.Background(), "plain", time.Hour, func(ctx context.Context) (interface{}, error) {
+			select {
+			case <-release:
+				return "bar", nil
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		})
+		done <- err
+	}()
+	waitFor(t, func() bool { return len(g.InFlightKeys()) == 1 })
+	g.Forget("plain")
+	close(release)
+	if err := <-done; err != nil {
+		t.Errorf("DoCtx after Forget err = %v; want nil", err)
+	}
+}
+
+func TestForgetAndWait(t *testing.T) {
+	var g Group
+	if err := g.ForgetAndWait(context.Background(), "key"); err != nil {
+		t.Errorf("ForgetAndWait without a call = %v; want nil", err)
+	}
+
+	var finished atomic.Bool
+	release := make(chan struct{})
+	ch := g.DoChan("key", time.Hour, func() (interface{}, error) {
+		<-release
+		time.Sleep(time.Millisecond)
+		finished.Store(true)
+		return "bar", nil
+	})
+	waitFor(t, func() bool { return g.Stats().InFlight == 1 })
+	done := make(chan error)
+	go func() {
+		done <- g.ForgetAndWait(context.Background(), "key")
+	}()
+	select {
+	case <-done:
+		t.Fatal("ForgetAndWait returned while the call was running")
+	case <-time.After(10 * time.Millisecond):
+	}
+	close(release)
+	if err := <-done; err != nil || !finished.Load() {
+		t.Errorf("ForgetAndWait = %v, finished %v; want nil after the call finished", err, finished.Load())
+	}
+	<-ch
+	if g.Has("key") {
+		t.Error("forgotten call was cached")
+	}
+
+	// 在方法中使用其上下文调用时不等待调用本身
+	v, err, _ := g.DoCtx(context.Background(), "self", time.Hour, func(ctx context.Context) (interface{}, error) {
+		return "bar", g.ForgetAndWait(ctx, "self")
+	})
+	if v != "bar" || err != nil || g.Has("self") {
+		t.Errorf("DoCtx = %v, %v, cached %v; want bar, nil, not cached", v, err, g.Has("self"))
+	}
+
+	// ctx被取消时不再等待
+	block := make(chan struct{})
+	defer close(block)
+	g.DoChan("blocked", time.Hour, func() (interface{}, error) {
+		<-block
+		return nil, nil
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if err := g.ForgetAndWait(ctx, "blocked"); err != context.DeadlineExceeded {
+		t.Errorf("ForgetAndWait with expired ctx = %v; want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestDoCtxRecursive(t *testing.T) {
+	var g Group
+	done := make(chan struct{})
+	var direct, indirect error
+	go func() {
+		defer close(done)
+		g.DoCtx(context.Background(), "x", time.Minute, func(ctx context.Context) (interface{}, error) {
+			_, direct, _ = g.DoCtx(ctx, "x", time.Minute, func(context.Context) (interface{}, error) {
+				return "inner", nil
+			})
+			return "outer", nil
+		})
+		// 经过其他key间接地调用自己
+		g.DoCtx(context.Background(), "a", time.Minute, func(ctx context.Context) (interface{}, error) {
+			v, err, _ := g.DoCtx(ctx, "b", time.Minute, func(ctx context.Context) (interface{}, error) {
+				_, indirect, _ = g.DoCtx(ctx, "a", time.Minute, func(context.Context) (interface{}, error) {
+					return nil, nil
+				})
+				return "b", nil
+			})
+			return v, err
+		})
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("recursive DoCtx deadlocked")
+	}
+	if direct != ErrRecursiveDo {
+		t.Errorf("direct recursion err = %v; want ErrRecursiveDo", direct)
+	}
+	if indirect != ErrRecursiveDo {
+		t.Errorf("indirect recursion err = %v; want ErrRecursiveDo", indirect)
+	}
+	if v, _, _ := g.DoCtx(context.Background(), "x", time.Minute, nil); v != "outer" {
+		t.Errorf("x = %v; want outer cached", v)
+	}
+}
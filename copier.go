@@ -0,0 +1,20 @@
+package timesf
+
+// WithCopier 设置结果的复制方法：共享同一个调用结果的每一个调用者，包括命中已完成的
+// 结果、等待正在进行的调用以及DoChan的每一个通道，拿到的都是copy返回的独立副本，
+// 避免一个调用者修改了结果（比如原地排序切片）被其他调用者看到。执行方法的调用者拿到
+// 原始的值。copy在释放锁之后调用，只作用于不为nil的值。默认不复制，所有调用者共享
+// 同一个值。
+func WithCopier(copy func(v interface{}) interface{}) Option {
+	return func(g *Group) {
+		g.copier = copy
+	}
+}
+
+// copied 返回r，其值按照WithCopier进行了复制。调用者不能持有锁。
+func (g *Group) copied(r Result) Result {
+	if g.copier != nil && r.Val != nil {
+		r.Val = g.copier(r.Val)
+	}
+	return r
+}
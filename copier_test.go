@@ -0,0 +1,85 @@
+package timesf
+
+import (
+	"errors"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestWithCopier(t *testing.T) {
+	copies := 0
+	g := New(WithCopier(func(v interface{}) interface{} {
+		copies++
+		s := v.(*[]int)
+		c := append([]int(nil), *s...)
+		return &c
+	}))
+	orig := &[]int{3, 1, 2}
+	v, _, _ := g.Do("key", time.Hour, func() (interface{}, error) {
+		return orig, nil
+	})
+	if v.(*[]int) != orig || copies != 0 {
+		t.Fatalf("owner got a copy (copies %d); want the original", copies)
+	}
+
+	// 命中的调用者拿到独立的副本，修改不影响其他调用者
+	hit, _, _ := g.Do("key", time.Hour, nil)
+	sort.Ints(*hit.(*[]int))
+	r := <-g.DoChan("key", time.Hour, nil)
+	if got := *r.Val.(*[]int); got[0] != 3 || got[1] != 1 || got[2] != 2 {
+		t.Errorf("DoChan hit = %v; want unsorted [3 1 2]", got)
+	}
+	if r.Val.(*[]int) == orig || hit.(*[]int) == orig {
+		t.Error("hit returned the original value")
+	}
+	if copies != 2 {
+		t.Errorf("copies = %d; want 2", copies)
+	}
+	// 缓存的结果保持不变，之后的命中仍然拿到原来的顺序
+	if v, _, _ := g.Peek("key"); v.(*[]int) != orig || (*orig)[0] != 3 {
+		t.Errorf("cached value = %v; want the original unsorted slice", *v.(*[]int))
+	}
+
+	// 错误结果没有值，不会被复制
+	g.Do("err", time.Hour, func() (interface{}, error) {
+		return nil, errors.New("boom")
+	})
+	if copies != 2 {
+		t.Errorf("copies after nil value = %d; want 2", copies)
+	}
+}
+
+func TestWithCopierWaiters(t *testing.T) {
+	g := New(WithCopier(func(v interface{}) interface{} {
+		s := v.(*[]int)
+		c := append([]int(nil), *s...)
+		return &c
+	}))
+	orig := &[]int{1}
+	release := make(chan struct{})
+	started := make(chan struct{})
+	ch := g.DoChan("key", time.Hour, func() (interface{}, error) {
+		close(started)
+		<-release
+		return orig, nil
+	})
+	<-started
+	ch2 := g.DoChan("key", time.Hour, nil)
+	done := make(chan interface{})
+	go func() {
+		v, _, _ := g.Do("key", time.Hour, nil)
+		done <- v
+	}()
+	waitFor(t, func() bool { return g.InFlight()["key"] == 2 })
+	close(release)
+
+	seen := map[*[]int]bool{orig: true}
+	for _, v := range []interface{}{(<-ch).Val, (<-ch2).Val, <-done} {
+		p := v.(*[]int)
+		if seen[p] {
+			t.Errorf("value %p shared between callers", p)
+		}
+		seen[p] = true
+	}
+}
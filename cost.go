@@ -0,0 +1,42 @@
+package timesf
+
+// WithMaxCost 按结果的开销限制Group的大小：cost计算每一个已完成结果的开销，比如其占用
+// 的字节数。已完成的结果总开销超过maxCost时，最久没有被访问的已完成结果会被移除，直到
+// 不超过maxCost。正在调用中的key不会被移除，其开销在调用完成时才被计算；开销单独就
+// 超过maxCost的结果不会被保留，也不会导致其他结果被移除。被移除的结果以EvictLRU
+// 通知WithOnEvict设置的回调，并计入Stats的Evictions。可以和WithCapacity同时使用，超过
+// 任意一个限制都会进行移除。
+func WithMaxCost(maxCost int64, cost func(val interface{}) int64) Option {
+	return func(g *Group) {
+		g.maxCost = maxCost
+		g.costFn = cost
+	}
+}
+
+// Cost 返回当前记录的已完成结果的总开销，没有设置WithMaxCost时为0。
+func (g *Group) Cost() int64 {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.cost
+}
+
+// overBudget 报告是否超过了容量或者开销上限，正在调用中的key不计入容量。调用者需要
+// 持有锁。
+func (g *Group) overBudget() bool {
+	return g.capacity > 0 && len(g.m)-g.pending > g.capacity || g.maxCost > 0 && g.cost > g.maxCost
+}
+
+// charge 计算已完成的调用c的开销并计入总开销，调用者需要持有锁。
+func (g *Group) charge(c *call) {
+	if g.maxCost <= 0 || g.costFn == nil {
+		return
+	}
+	c.cost = g.costFn(c.val)
+	g.cost += c.cost
+}
+
+// uncharge 将调用c的开销从总开销中移除，调用者需要持有锁。
+func (g *Group) uncharge(c *call) {
+	g.cost -= c.cost
+	c.cost = 0
+}
@@ -0,0 +1,126 @@
+package timesf
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestWithMaxCost(t *testing.T) {
+	var rec evictRecorder
+	g := New(WithMaxCost(10, func(val interface{}) int64 {
+		return int64(len(val.(string)))
+	}), WithOnEvict(rec.onEvict))
+	do := func(key, val string) {
+		g.Do(key, time.Hour, func() (interface{}, error) {
+			return val, nil
+		})
+	}
+
+	do("a", "aaaa")
+	do("b", "bbbb")
+	if c := g.Cost(); c != 8 {
+		t.Errorf("Cost = %d; want 8", c)
+	}
+	// 访问a使b成为最久没有被访问的结果
+	do("a", "")
+	do("c", "cccccc")
+	keys := g.Keys()
+	sort.Strings(keys)
+	if got, want := fmt.Sprint(keys), "[a c]"; got != want {
+		t.Errorf("Keys = %v; want %v", got, want)
+	}
+	if c := g.Cost(); c != 10 {
+		t.Errorf("Cost = %d; want 10", c)
+	}
+	if got, want := fmt.Sprint(rec.take()), "[b=bbbb:lru]"; got != want {
+		t.Errorf("evictions = %v; want %v", got, want)
+	}
+
+	// 很多小的结果不会因为数量被移除
+	g.Forget("a")
+	g.Forget("c")
+	for i := 0; i < 10; i++ {
+		do(fmt.Sprint("small", i), "s")
+	}
+	if n, c := g.Len(), g.Cost(); n != 10 || c != 10 {
+		t.Errorf("Len, Cost = %d, %d; want 10, 10", n, c)
+	}
+	g.Set("big", "bbbbbbbbb", time.Hour)
+	if n, c := g.Len(), g.Cost(); n != 2 || c != 10 {
+		t.Errorf("Len, Cost after Set = %d, %d; want 2, 10", n, c)
+	}
+	if n := g.ForgetAll(); n != 2 || g.Cost() != 0 {
+		t.Errorf("ForgetAll = %d, Cost = %d; want 2, 0", n, g.Cost())
+	}
+}
+
+func TestMaxCostInFlight(t *testing.T) {
+	g := New(WithMaxCost(1, func(val interface{}) int64 {
+		return 1
+	}))
+	release := make(chan struct{})
+	fn := func() (interface{}, error) {
+		<-release
+		return nil, nil
+	}
+	// 正在调用中的key没有开销，也不会被移除
+	ch1 := g.DoChan("a", time.Hour, fn)
+	ch2 := g.DoChan("b", time.Hour, fn)
+	if n, c := g.Len(), g.Cost(); n != 2 || c != 0 {
+		t.Errorf("Len, Cost while in flight = %d, %d; want 2, 0", n, c)
+	}
+	close(release)
+	<-ch1
+	<-ch2
+	if n, c := g.Len(), g.Cost(); n != 1 || c != 1 {
+		t.Errorf("Len, Cost after completion = %d, %d; want 1, 1", n, c)
+	}
+}
+
+func TestMaxCostOversized(t *testing.T) {
+	var rec evictRecorder
+	g := New(WithMaxCost(10, func(val interface{}) int64 {
+		return int64(len(val.(string)))
+	}), WithOnEvict(rec.onEvict))
+	calls := 0
+	do := func(key, val string) {
+		g.Do(key, time.Hour, func() (interface{}, error) {
+			calls++
+			return val, nil
+		})
+	}
+
+	do("a", "aaaa")
+	do("huge", "hhhhhhhhhhhh")
+	if n, c := g.Len(), g.Cost(); n != 1 || c != 4 {
+		t.Errorf("Len, Cost = %d, %d; want 1, 4", n, c)
+	}
+	if got, want := fmt.Sprint(rec.take()), "[huge=hhhhhhhhhhhh:lru]"; got != want {
+		t.Errorf("evictions = %v; want %v", got, want)
+	}
+	// 太大的结果不被缓存，每次都重新执行
+	do("huge", "hhhhhhhhhhhh")
+	if calls != 3 {
+		t.Errorf("calls = %d; want 3", calls)
+	}
+}
+
+func BenchmarkDoHitWithMaxCost(b *testing.B) {
+	g := New(WithMaxCost(1<<20, func(val interface{}) int64 {
+		return 1
+	}))
+	fn := func() (interface{}, error) {
+		return nil, nil
+	}
+	for i := 0; i < 1000; i++ {
+		g.Do(fmt.Sprint(i), time.Hour, fn)
+	}
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			g.Do("500", time.Hour, fn)
+		}
+	})
+}
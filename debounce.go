@@ -0,0 +1,49 @@
+package timesf
+
+import "time"
+
+// WithDebounce 让新调用等待d时长之后再执行方法，期间到达的调用者像平常一样共享这个调用，
+// 突发的调用者以及先Forget再读取的调用合并为一次执行。命中已完成结果的调用者不受影响，
+// 调用者可以通过Hurry结束等待。等待的时长计入Stats的Debounced并且触发Hooks的
+// OnDebounce钩子。只作用于Do和DoChan系列方法，DoMulti的批量调用和后台刷新不会等待。
+// d不大于0时不等待，这是默认值。
+func WithDebounce(d time.Duration) Option {
+	return func(g *Group) {
+		g.debounce = d
+	}
+}
+
+// Hurry 让key正在WithDebounce窗口中等待的调用立即执行方法。返回是否有这样的调用。
+func (g *Group) Hurry(key string) bool {
+	key = g.normalize(key)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	c, ok := g.m[key]
+	if !ok || c.done || c.hurry == nil || c.hurried {
+		return false
+	}
+	c.hurried = true
+	close(c.hurry)
+	return true
+}
+
+// debounced 在设置了WithDebounce时为刚刚创建的调用c设置等待的窗口，和WithMinInterval
+// 的间隔同时存在时等到两者中较晚的一个。调用者需要持有锁。
+func (g *Group) debounced(c *call) {
+	if g.debounce <= 0 {
+		return
+	}
+	if until := c.startedAt + int64(g.debounce); until > c.notBefore {
+		c.notBefore = until
+	}
+	c.hurry = make(chan struct{})
+}
+
+// settled 记录key的调用在执行方法之前等待了waited时长，调用OnDebounce钩子。调用者不能
+// 持有锁。
+func (g *Group) settled(key string, waited time.Duration) {
+	g.stats.debounced.Add(1)
+	if h := g.hooks; h != nil && h.OnDebounce != nil {
+		h.OnDebounce(key, waited)
+	}
+}
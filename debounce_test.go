@@ -0,0 +1,83 @@
+package timesf
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDebounce(t *testing.T) {
+	var waited atomic.Int64
+	g := New(WithDebounce(5*time.Millisecond), WithHooks(Hooks{
+		OnDebounce: func(key string, d time.Duration) {
+			waited.Store(int64(d))
+		},
+	}))
+	var calls int32
+	fn := func() (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		return "bar", nil
+	}
+
+	// 50个调用者分散在3ms内到达，全部加入窗口中等待的调用
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			time.Sleep(time.Duration(i) * 60 * time.Microsecond)
+			if i%2 == 0 {
+				g.Do("key", time.Hour, fn)
+			} else {
+				<-g.DoChan("key", time.Hour, fn)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if calls != 1 {
+		t.Errorf("calls = %d; want 1", calls)
+	}
+	if s := g.Stats(); s.Executions != 1 || s.Debounced != 1 {
+		t.Errorf("Stats = %+v; want one execution and one debounced call", s)
+	}
+	if d := time.Duration(waited.Load()); d < 4*time.Millisecond {
+		t.Errorf("OnDebounce waited = %v; want about 5ms", d)
+	}
+
+	// 命中已完成的结果不等待
+	start := time.Now()
+	if v, _, _ := g.Do("key", time.Hour, fn); v != "bar" {
+		t.Errorf("hit = %v; want bar", v)
+	}
+	if d := time.Since(start); d >= 5*time.Millisecond {
+		t.Errorf("hit took %v; want no debounce", d)
+	}
+	if s := g.Stats(); s.Debounced != 1 {
+		t.Errorf("Debounced after hit = %d; want 1", s.Debounced)
+	}
+}
+
+func TestDebounceHurry(t *testing.T) {
+	g := New(WithDebounce(time.Hour))
+	if g.Hurry("key") {
+		t.Error("Hurry without a pending call = true; want false")
+	}
+	ch := g.DoChan("key", time.Hour, func() (interface{}, error) {
+		return "bar", nil
+	})
+	if !g.Hurry("key") {
+		t.Fatal("Hurry = false; want true")
+	}
+	if g.Hurry("key") {
+		t.Error("second Hurry = true; want false")
+	}
+	select {
+	case r := <-ch:
+		if r.Val != "bar" {
+			t.Errorf("DoChan = %v; want bar", r.Val)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Hurry did not end the debounce window")
+	}
+}
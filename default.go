@@ -0,0 +1,40 @@
+package timesf
+
+import "time"
+
+// DoOrDefault 像Do方法，但是fn返回错误时返回def和这个错误，错误不会被缓存，下一个调用者
+// 重新执行方法。key之前的结果已经过期但还没有被删除时，返回这个过期的值而不是def，
+// WithStaleFallback(false)时总是返回def。fn返回错误时其返回的值被忽略。
+func (g *Group) DoOrDefault(key string, validTime time.Duration, def interface{}, fn func() (interface{}, error)) (v interface{}, err error) {
+	fallback := !g.noStaleFallback
+	r, _, _ := g.do(key, params{validTime: validTime, hasErrorTTL: true, fallback: fallback}, fn)
+	if r.Err != nil && (r.Val == nil || !fallback) {
+		return def, r.Err
+	}
+	return r.Val, r.Err
+}
+
+// WithStaleFallback 设置DoOrDefault的方法返回错误时，是否优先返回key已经过期的上一次的值，
+// 默认为true；为false时总是返回DoOrDefault的默认值。
+func WithStaleFallback(enabled bool) Option {
+	return func(g *Group) {
+		g.noStaleFallback = !enabled
+	}
+}
+
+// orKept 返回执行fn的方法，fn返回错误时值改为kept的值，没有kept时为nil。只由执行方法的
+// 协程调用。
+func (c *call) orKept(fn func() (interface{}, error)) func() (interface{}, error) {
+	return func() (interface{}, error) {
+		kept := c.kept
+		c.kept = nil
+		v, err := fn()
+		if err == nil {
+			return v, nil
+		}
+		if kept != nil {
+			return kept.val, err
+		}
+		return nil, err
+	}
+}
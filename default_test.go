@@ -0,0 +1,51 @@
+package timesf
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDoOrDefault(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithClock(clock), WithErrorCaching(true))
+	boom := errors.New("boom")
+	calls := 0
+	failing := func() (interface{}, error) {
+		calls++
+		return "partial", boom
+	}
+
+	// 没有之前的结果，返回默认值，错误不被缓存
+	if v, err := g.DoOrDefault("key", time.Minute, "def", failing); v != "def" || err != boom {
+		t.Errorf("DoOrDefault without value = %v, %v; want def, boom", v, err)
+	}
+	if g.Has("key") {
+		t.Error("error from DoOrDefault was cached")
+	}
+	g.DoOrDefault("key", time.Minute, "def", failing)
+	if calls != 2 {
+		t.Errorf("fn called %d times; want 2", calls)
+	}
+
+	// 成功的结果正常缓存
+	if v, err := g.DoOrDefault("key", time.Minute, "def", func() (interface{}, error) { return "fresh", nil }); v != "fresh" || err != nil {
+		t.Errorf("DoOrDefault success = %v, %v; want fresh, nil", v, err)
+	}
+	if v, err := g.DoOrDefault("key", time.Minute, "def", failing); v != "fresh" || err != nil {
+		t.Errorf("DoOrDefault hit = %v, %v; want fresh, nil", v, err)
+	}
+
+	// 过期的值优先于默认值
+	clock.Advance(time.Minute)
+	if v, err := g.DoOrDefault("key", time.Minute, "def", failing); v != "fresh" || err != boom {
+		t.Errorf("DoOrDefault with stale value = %v, %v; want fresh, boom", v, err)
+	}
+
+	g = New(WithClock(clock), WithStaleFallback(false))
+	g.Do("key", time.Minute, func() (interface{}, error) { return "fresh", nil })
+	clock.Advance(time.Minute)
+	if v, err := g.DoOrDefault("key", time.Minute, "def", failing); v != "def" || err != boom {
+		t.Errorf("DoOrDefault without stale fallback = %v, %v; want def, boom", v, err)
+	}
+}
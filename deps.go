@@ -0,0 +1,46 @@
+package timesf
+
+// AddDependency 登记key依赖dependsOn：遗忘或者失效dependsOn时，key以及依赖key的其他key
+// 也一起被遗忘或者失效，见Forget和Invalidate。依赖关系在key被遗忘之后仍然保留，可以
+// 形成环，传递时每个key只处理一次。key和dependsOn相同时什么都不做。
+func (g *Group) AddDependency(key, dependsOn string) {
+	key, dependsOn = g.normalize(key), g.normalize(dependsOn)
+	if key == dependsOn {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.deps == nil {
+		g.deps = make(map[string]map[string]struct{})
+	}
+	dependents := g.deps[dependsOn]
+	if dependents == nil {
+		dependents = make(map[string]struct{})
+		g.deps[dependsOn] = dependents
+	}
+	dependents[key] = struct{}{}
+}
+
+// cascade 对直接或者间接依赖key的每一个存在的key调用drop，key本身不包括在内。调用者
+// 需要持有锁，drop可以删除g.m中的key。
+func (g *Group) cascade(key string, drop func(key string, c *call)) {
+	if len(g.deps) == 0 {
+		return
+	}
+	seen := map[string]bool{key: true}
+	queue := []string{key}
+	for len(queue) > 0 {
+		k := queue[0]
+		queue = queue[1:]
+		for d := range g.deps[k] {
+			if seen[d] {
+				continue
+			}
+			seen[d] = true
+			queue = append(queue, d)
+			if c, ok := g.m[d]; ok {
+				drop(d, c)
+			}
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package timesf
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAddDependency(t *testing.T) {
+	var g Group
+	load := func() (interface{}, error) { return 1, nil }
+	for _, key := range []string{"user:1", "profile:user:1", "page:user:1", "user:2", "profile:user:2"} {
+		g.Do(key, time.Minute, load)
+	}
+	g.AddDependency("profile:user:1", "user:1")
+	g.AddDependency("page:user:1", "profile:user:1")
+	g.AddDependency("user:1", "page:user:1") // 环
+	g.AddDependency("profile:user:2", "user:2")
+
+	g.Forget("user:1")
+	for _, key := range []string{"user:1", "profile:user:1", "page:user:1"} {
+		if g.Has(key) {
+			t.Errorf("%s still cached after forgetting user:1", key)
+		}
+	}
+	for _, key := range []string{"user:2", "profile:user:2"} {
+		if !g.Has(key) {
+			t.Errorf("unrelated %s was forgotten", key)
+		}
+	}
+
+	// 依赖关系在遗忘之后仍然有效，Invalidate同样传递
+	g.Do("profile:user:1", time.Minute, load)
+	if g.Invalidate("user:1") {
+		t.Error("Invalidate of absent root = true; want false")
+	}
+	if g.Has("profile:user:1") {
+		t.Error("dependent still cached after invalidating user:1")
+	}
+	if !g.Invalidate("user:2") || g.Has("profile:user:2") {
+		t.Error("Invalidate did not cascade to profile:user:2")
+	}
+}
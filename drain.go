@@ -0,0 +1,75 @@
+package timesf
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrFrozen 在Group被Freeze之后，返回给需要开始新调用的调用者。
+var ErrFrozen = errors.New("timesf: group is frozen")
+
+// ErrClosed 在Group被Close之后，返回给需要开始新调用的调用者。
+var ErrClosed = errors.New("timesf: group is closed")
+
+// Close 结束Group的生命周期：像Freeze一样不再开始新的调用，但返回ErrClosed，然后停止
+// 后台清理协程并等待其退出，等待所有正在进行的调用完成，最后关闭Notify的通道。可以重复调用，也可以和
+// 正在进行的调用并发调用，总是返回nil。
+func (g *Group) Close() error {
+	g.mu.Lock()
+	g.refused = ErrClosed
+	g.mu.Unlock()
+	g.Stop()
+	if g.janitor != nil {
+		<-g.janitor.done
+	}
+	err := g.Wait(context.Background())
+	g.stopNotifyAll()
+	return err
+}
+
+// Freeze 让Group不再开始新的调用：之后需要执行方法的调用者立即拿到ErrFrozen，过期但
+// 可以返回旧值的结果也不再在后台刷新。还未过期的结果和正在进行的调用仍然可以被共享。
+// 通常和Wait一起使用，在退出之前让正在进行的调用完成。
+func (g *Group) Freeze() {
+	g.mu.Lock()
+	if g.refused == nil {
+		g.refused = ErrFrozen
+	}
+	g.mu.Unlock()
+}
+
+// Wait 阻塞直到所有正在进行的调用（包括后台刷新）都已经完成，或者ctx被取消，此时返回
+// ctx.Err()。Wait期间开始的新调用同样会被等待，需要先调用Freeze才能保证Wait返回。
+// 等待时不持有锁。
+func (g *Group) Wait(ctx context.Context) error {
+	g.mu.Lock()
+	if g.running == 0 {
+		g.mu.Unlock()
+		return nil
+	}
+	if g.idle == nil {
+		g.idle = make(chan struct{})
+	}
+	idle := g.idle
+	g.mu.Unlock()
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// begin 记录一个开始执行方法的调用，调用者需要持有锁。
+func (g *Group) begin() {
+	g.running++
+}
+
+// end 记录一个执行完成的调用，最后一个调用完成时唤醒Wait，调用者需要持有锁。
+func (g *Group) end() {
+	g.running--
+	if g.running == 0 && g.idle != nil {
+		close(g.idle)
+		g.idle = nil
+	}
+}
@@ -0,0 +1,145 @@
+package timesf
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWaitAndFreeze(t *testing.T) {
+	g := New()
+	if err := g.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait on idle group = %v", err)
+	}
+	g.Do("cached", time.Hour, func() (interface{}, error) {
+		return "bar", nil
+	})
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	ch := g.DoChan("slow", time.Hour, func() (interface{}, error) {
+		close(started)
+		<-release
+		return "done", nil
+	})
+	<-started
+	g.Freeze()
+
+	if _, err, _ := g.Do("new", time.Hour, func() (interface{}, error) {
+		t.Error("fn called after Freeze")
+		return nil, nil
+	}); err != ErrFrozen {
+		t.Errorf("Do after Freeze err = %v; want ErrFrozen", err)
+	}
+	if r := <-g.DoChan("new", time.Hour, nil); r.Err != ErrFrozen {
+		t.Errorf("DoChan after Freeze err = %v; want ErrFrozen", r.Err)
+	}
+	if v, err, _ := g.Do("cached", time.Hour, nil); v != "bar" || err != nil {
+		t.Errorf("cached Do after Freeze = %v, %v; want bar, nil", v, err)
+	}
+	dup := g.DoChan("slow", time.Hour, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := g.Wait(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Wait with in-flight call = %v; want DeadlineExceeded", err)
+	}
+
+	waited := make(chan error)
+	go func() {
+		waited <- g.Wait(context.Background())
+	}()
+	select {
+	case err := <-waited:
+		t.Fatalf("Wait returned %v before the call completed", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	close(release)
+	if err := <-waited; err != nil {
+		t.Errorf("Wait = %v", err)
+	}
+	if r := <-ch; r.Val != "done" {
+		t.Errorf("owner result = %v; want done", r.Val)
+	}
+	if r := <-dup; r.Val != "done" {
+		t.Errorf("joined result = %v; want done", r.Val)
+	}
+}
+
+func TestWaitRefresh(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithClock(clock))
+	release := make(chan struct{})
+	g.DoStale("key", time.Second, time.Hour, func() (interface{}, error) {
+		return "old", nil
+	})
+	clock.Advance(2 * time.Second)
+	g.DoStale("key", time.Second, time.Hour, func() (interface{}, error) {
+		<-release
+		return "new", nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := g.Wait(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Wait with refresh in flight = %v; want DeadlineExceeded", err)
+	}
+	close(release)
+	if err := g.Wait(context.Background()); err != nil {
+		t.Errorf("Wait = %v", err)
+	}
+	if v, _, _ := g.Peek("key"); v != "new" {
+		t.Errorf("Peek after Wait = %v; want new", v)
+	}
+}
+
+func TestClose(t *testing.T) {
+	g := New(WithJanitor(time.Millisecond))
+	release := make(chan struct{})
+	started := make(chan struct{})
+	ch := g.DoChan("slow", time.Hour, func() (interface{}, error) {
+		close(started)
+		<-release
+		return "done", nil
+	})
+	<-started
+
+	closed := make(chan error)
+	go func() {
+		closed <- g.Close()
+	}()
+	select {
+	case <-g.janitor.done:
+	case <-time.After(time.Second):
+		t.Fatal("janitor still running after Close")
+	}
+	select {
+	case err := <-closed:
+		t.Fatalf("Close returned %v before the call completed", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	if _, err, _ := g.Do("new", time.Hour, func() (interface{}, error) {
+		t.Error("fn called after Close")
+		return nil, nil
+	}); err != ErrClosed {
+		t.Errorf("Do after Close err = %v; want ErrClosed", err)
+	}
+
+	close(release)
+	if err := <-closed; err != nil {
+		t.Errorf("Close = %v", err)
+	}
+	if r := <-ch; r.Val != "done" {
+		t.Errorf("owner result = %v; want done", r.Val)
+	}
+	g.Freeze()
+	if err := g.Close(); err != nil {
+		t.Errorf("second Close = %v", err)
+	}
+	if _, err, _ := g.Do("new", time.Hour, nil); err != ErrClosed {
+		t.Errorf("Do after second Close err = %v; want ErrClosed", err)
+	}
+
+	var zero Group
+	zero.Close()
+}
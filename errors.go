@@ -0,0 +1,21 @@
+package timesf
+
+import "fmt"
+
+// WithWrapErrors 设置是否为方法返回的错误加上key：开启时调用者拿到的错误形如
+// `timesf: key "foo": <原始错误>`，仍然可以用errors.Is和errors.As找到原始的错误。包自身
+// 产生的错误，比如ErrWaitTimeout、ErrForgotten、ErrTooManyWaiters和ErrClosed，不会被
+// 包装。默认不包装，调用者拿到方法返回的原始错误。
+func WithWrapErrors(wrap bool) Option {
+	return func(g *Group) {
+		g.wrapErrors = wrap
+	}
+}
+
+// wrapped 按照WithWrapErrors为key的方法返回的错误err加上key，err为nil时返回nil。
+func (g *Group) wrapped(key string, err error) error {
+	if !g.wrapErrors || err == nil {
+		return err
+	}
+	return fmt.Errorf("timesf: key %q: %w", key, err)
+}
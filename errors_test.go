@@ -0,0 +1,45 @@
+package timesf
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWithWrapErrors(t *testing.T) {
+	errBoom := errors.New("boom")
+	fn := func() (interface{}, error) {
+		return nil, errBoom
+	}
+
+	_, err, _ := New().Do("key", time.Hour, fn)
+	if err != errBoom {
+		t.Errorf("Do without WithWrapErrors err = %v; want boom unwrapped", err)
+	}
+
+	g := New(WithWrapErrors(true))
+	_, err, _ = g.Do("key", time.Hour, fn)
+	if !errors.Is(err, errBoom) || err.Error() != `timesf: key "key": boom` {
+		t.Errorf("Do err = %v; want wrapped boom", err)
+	}
+	r := <-g.DoChan("chan", time.Hour, fn)
+	if !errors.Is(r.Err, errBoom) || r.Err.Error() != `timesf: key "chan": boom` {
+		t.Errorf("DoChan err = %v; want wrapped boom", r.Err)
+	}
+	if _, err, _ := g.Do("ok", time.Hour, func() (interface{}, error) { return 1, nil }); err != nil {
+		t.Errorf("Do success err = %v; want nil", err)
+	}
+
+	res := g.DoMulti([]string{"a", "b"}, time.Hour, func(missing []string) (map[string]interface{}, error) {
+		return nil, errBoom
+	})
+	if err := res["b"].Err; !errors.Is(err, errBoom) || err.Error() != `timesf: key "b": boom` {
+		t.Errorf("DoMulti err = %v; want wrapped boom", err)
+	}
+
+	// 包自身的错误不被包装
+	g.Freeze()
+	if _, err, _ := g.Do("frozen", time.Hour, fn); err != ErrFrozen {
+		t.Errorf("Do on frozen group err = %v; want ErrFrozen", err)
+	}
+}
@@ -0,0 +1,94 @@
+package timesf
+
+import "time"
+
+// EvictReason 标识一个结果离开Group的原因。
+type EvictReason int
+
+const (
+	// EvictExpired 标识结果过期之后被新的调用替换。
+	EvictExpired EvictReason = iota
+	// EvictForgotten 标识结果被Forget系列方法遗忘。
+	EvictForgotten
+	// EvictReplaced 标识结果被Set或者刷新得到的新结果替换。
+	EvictReplaced
+	// EvictLRU 标识结果因为超过WithCapacity或者WithMaxCost的上限被移除。
+	EvictLRU
+	// EvictReset 标识结果随着ForgetAll被全部遗忘。
+	EvictReset
+
+	// evictReasons 是原因的数量。
+	evictReasons = iota
+)
+
+// String 返回原因的名称。
+func (r EvictReason) String() string {
+	switch r {
+	case EvictExpired:
+		return "expired"
+	case EvictForgotten:
+		return "forgotten"
+	case EvictReplaced:
+		return "replaced"
+	case EvictLRU:
+		return "lru"
+	case EvictReset:
+		return "reset"
+	}
+	return "unknown"
+}
+
+// WithOnEvict 设置结果离开Group时的回调，每一个离开的结果恰好通知一次。还在调用中的
+// 结果被移除时val为nil。回调在释放锁之后被调用，因此可以在回调中再次调用Group的方法。
+// 不论是否设置回调，每一种原因的次数都计入Stats的Evicted。
+func WithOnEvict(fn func(key string, val interface{}, reason EvictReason)) Option {
+	return func(g *Group) {
+		g.onEvict = fn
+	}
+}
+
+// eviction 是一次等待通知的结果移除。
+type eviction struct {
+	key    string
+	val    interface{}
+	reason EvictReason
+	// age 是已完成的结果完成至今的时长，还在调用中的结果为-1。
+	age time.Duration
+}
+
+// evict 记录调用c因为reason的移除，在设置了回调、日志或者有Notify的订阅者时将其记录到
+// evs中。调用者需要持有锁，并在释放锁之后调用notifyEvicted。
+func (g *Group) evict(evs []eviction, key string, c *call, reason EvictReason) []eviction {
+	g.stats.evicted[reason].Add(1)
+	if g.onEvict == nil && g.logger == nil && len(g.watchers) == 0 && !g.tracksLifetime() {
+		return evs
+	}
+	var val interface{}
+	age := time.Duration(-1)
+	if c.done {
+		val = c.val
+		age = time.Duration(g.now() - c.doneAt)
+	}
+	return append(evs, eviction{key, val, reason, age})
+}
+
+// tracksLifetime 报告是否设置了OnEntryLifetime钩子。
+func (g *Group) tracksLifetime() bool {
+	return g.hooks != nil && g.hooks.OnEntryLifetime != nil
+}
+
+// notifyEvicted 调用回调并通知订阅者evs中的移除，调用者不能持有锁。
+func (g *Group) notifyEvicted(evs []eviction) {
+	for _, ev := range evs {
+		g.broadcast(ev)
+		if g.logger != nil {
+			g.logger.Logf("timesf: key %q %v", ev.key, ev.reason)
+		}
+		if g.onEvict != nil {
+			g.onEvict(ev.key, ev.val, ev.reason)
+		}
+		if ev.age >= 0 && g.tracksLifetime() {
+			g.hooks.OnEntryLifetime(ev.key, ev.age, ev.reason)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package timesf
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// evictRecorder 记录OnEvict回调收到的通知。
+type evictRecorder struct {
+	mu  sync.Mutex
+	evs []string
+}
+
+func (r *evictRecorder) onEvict(key string, val interface{}, reason EvictReason) {
+	r.mu.Lock()
+	r.evs = append(r.evs, fmt.Sprintf("%s=%v:%v", key, val, reason))
+	r.mu.Unlock()
+}
+
+func (r *evictRecorder) take() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	evs := r.evs
+	r.evs = nil
+	sort.Strings(evs)
+	return evs
+}
+
+func TestOnEvict(t *testing.T) {
+	clock := newFakeClock()
+	var rec evictRecorder
+	g := New(WithClock(clock), WithOnEvict(rec.onEvict))
+	val := func(v interface{}) func() (interface{}, error) {
+		return func() (interface{}, error) {
+			return v, nil
+		}
+	}
+
+	g.Do("key", time.Second, val(1))
+	clock.Advance(time.Second)
+	g.Do("key", time.Second, val(2))
+	if got, want := fmt.Sprint(rec.take()), "[key=1:expired]"; got != want {
+		t.Errorf("evictions after expiry = %v; want %v", got, want)
+	}
+
+	g.Set("key", 3, time.Second)
+	if got, want := fmt.Sprint(rec.take()), "[key=2:replaced]"; got != want {
+		t.Errorf("evictions after Set = %v; want %v", got, want)
+	}
+
+	g.Forget("key")
+	if got, want := fmt.Sprint(rec.take()), "[key=3:forgotten]"; got != want {
+		t.Errorf("evictions after Forget = %v; want %v", got, want)
+	}
+
+	g.Do("a", time.Second, val("a"))
+	release := make(chan struct{})
+	ch := g.DoChan("b", time.Second, func() (interface{}, error) {
+		<-release
+		return "b", nil
+	})
+	g.ForgetAll()
+	close(release)
+	<-ch
+	if got, want := fmt.Sprint(rec.take()), "[a=a:reset b=<nil>:reset]"; got != want {
+		t.Errorf("evictions after ForgetAll = %v; want %v", got, want)
+	}
+
+	var want [evictReasons]uint64
+	want[EvictExpired], want[EvictReplaced], want[EvictForgotten], want[EvictReset] = 1, 1, 1, 2
+	if s := g.Stats(); s.Evicted != want {
+		t.Errorf("Stats.Evicted = %v; want %v", s.Evicted, want)
+	}
+}
+
+func TestOnEvictReentrant(t *testing.T) {
+	var g *Group
+	g = New(WithOnEvict(func(key string, val interface{}, reason EvictReason) {
+		g.Set(key+"-evicted", val, time.Second)
+	}))
+	g.Set("key", 1, time.Second)
+	g.Forget("key")
+	if v, _, ok := g.Peek("key-evicted"); !ok || v != 1 {
+		t.Errorf("Peek(key-evicted) = %v, %v; want 1, true", v, ok)
+	}
+}
+
+func TestOnEntryLifetime(t *testing.T) {
+	clock := newFakeClock()
+	var got []string
+	g := New(WithClock(clock), WithHooks(Hooks{
+		OnEntryLifetime: func(key string, age time.Duration, reason EvictReason) {
+			got = append(got, fmt.Sprintf("%s:%v:%v", key, age, reason))
+		},
+	}))
+	load := func() (interface{}, error) { return 1, nil }
+	g.Do("expired", time.Minute, load)
+	g.Do("forgotten", time.Minute, load)
+	g.Do("replaced", time.Minute, load)
+	clock.Advance(20 * time.Second)
+	g.Forget("forgotten")
+	g.Set("replaced", 2, time.Minute)
+	clock.Advance(time.Minute)
+	g.Do("expired", time.Minute, load)
+
+	// 正在调用中的结果被遗忘时不触发
+	release := make(chan struct{})
+	ch := g.DoChan("inflight", time.Minute, func() (interface{}, error) {
+		<-release
+		return 1, nil
+	})
+	g.Forget("inflight")
+	close(release)
+	<-ch
+
+	want := []string{"forgotten:20s:forgotten", "replaced:20s:replaced", "expired:1m20s:expired"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("lifetimes = %v; want %v", got, want)
+	}
+}
@@ -0,0 +1,51 @@
+package timesf
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// KeyExpiry 是一个key和其结果的过期时间，见ExpiringSoon。
+type KeyExpiry struct {
+	Key       string
+	ExpiresAt time.Time
+}
+
+// ExpiringSoon 返回最先过期的至多n个已完成并且还未过期的结果，按照过期时间从早到晚
+// 排列，过期时间相同时按照key排列，用于预估即将到来的重新执行。永不过期的结果和正在
+// 调用中的key不包括在内，n不大于0时返回nil。只在复制时持有锁，排序在释放锁之后进行，
+// 返回的切片是新分配的。
+func (g *Group) ExpiringSoon(n int) []KeyExpiry {
+	if n <= 0 {
+		return nil
+	}
+	type entry struct {
+		key string
+		t   int64
+	}
+	g.mu.RLock()
+	now := g.now()
+	entries := make([]entry, 0, len(g.m))
+	for key, c := range g.m {
+		if t := g.t[key]; c.done && t > now && t != math.MaxInt64 {
+			entries = append(entries, entry{key, t})
+		}
+	}
+	g.mu.RUnlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].t != entries[j].t {
+			return entries[i].t < entries[j].t
+		}
+		return entries[i].key < entries[j].key
+	})
+	if len(entries) > n {
+		entries = entries[:n]
+	}
+	soon := make([]KeyExpiry, len(entries))
+	for i, e := range entries {
+		soon[i] = KeyExpiry{e.key, time.Unix(0, e.t)}
+	}
+	return soon
+}
@@ -0,0 +1,50 @@
+package timesf
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestExpiringSoon(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithClock(clock))
+	start := clock.Now()
+	fn := func() (interface{}, error) {
+		return nil, nil
+	}
+	for _, k := range []struct {
+		key string
+		ttl time.Duration
+	}{{"c", 3 * time.Second}, {"a", 5 * time.Second}, {"b", time.Second}, {"d", 3 * time.Second}, {"gone", time.Millisecond}} {
+		g.Do(k.key, k.ttl, fn)
+	}
+	g.Set("forever", "v", NoExpiration)
+	release := make(chan struct{})
+	ch := g.DoChan("inflight", time.Millisecond, func() (interface{}, error) {
+		<-release
+		return nil, nil
+	})
+	defer func() {
+		close(release)
+		<-ch
+	}()
+	clock.Advance(time.Millisecond)
+
+	want := []KeyExpiry{
+		{"b", start.Add(time.Second)},
+		{"c", start.Add(3 * time.Second)},
+		{"d", start.Add(3 * time.Second)},
+		{"a", start.Add(5 * time.Second)},
+	}
+	for _, n := range []int{0, 1, 3, 4, 10} {
+		got := g.ExpiringSoon(n)
+		w := want
+		if n < len(w) {
+			w = w[:n]
+		}
+		if fmt.Sprint(got) != fmt.Sprint(w) {
+			t.Errorf("ExpiringSoon(%d) = %v; want %v", n, got, w)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package timesf
+
+import "time"
+
+// fastHit 是发布到无锁读取路径上的已完成的结果，t是其过期时间，r是发布时命中的结果。
+// 发布之后不再修改。
+type fastHit struct {
+	c *call
+	t int64
+	r Result
+}
+
+// result 返回在now命中h的结果，并记录一次命中。
+func (h *fastHit) result(now int64) Result {
+	h.c.accessed(now)
+	r := h.r
+	r.HitAge = time.Duration(now - h.c.doneAt)
+	return r
+}
+
+// fastPath 报告命中是否可以不加锁返回。LRU、提前刷新和滑动过期在命中时需要修改状态，
+// 开启时只使用加锁的路径。
+func (g *Group) fastPath() bool {
+	return !g.bounded() && g.refreshAhead <= 0 && g.refreshBefore <= 0 && !g.sliding
+}
+
+// loadHit 不加锁地查找key还未过期的已完成结果。
+func (g *Group) loadHit(key string) (h *fastHit, now int64, ok bool) {
+	v, ok := g.fast.Load(key)
+	if !ok {
+		return nil, 0, false
+	}
+	h = v.(*fastHit)
+	now = g.now()
+	if h.t <= now {
+		return nil, 0, false
+	}
+	return h, now, true
+}
+
+// fastDo 在无锁读取路径上命中normalize之后的key时，像Do方法一样记录命中并返回结果。
+func (g *Group) fastDo(key string) (r Result, ok bool) {
+	h, now, ok := g.loadHit(key)
+	if !ok {
+		return Result{}, false
+	}
+	g.stats.hits.Add(1)
+	g.looked(true)
+	r = h.result(now)
+	g.hit(key, r.HitAge)
+	return g.copied(r), true
+}
+
+// publish 将key已完成的调用c发布到无锁读取路径上，t是其过期时间。只有已经被共享过的
+// 调用才会被发布，因此不需要再修改c.dups。调用者需要持有锁。
+func (g *Group) publish(key string, c *call, t int64) {
+	if g.fastPath() {
+		g.fast.Store(key, &fastHit{c, t, c.cachedResult(t, c.doneAt)})
+	}
+}
+
+// unpublish 在key的调用或者过期时间改变时，将其从无锁读取路径上移除。调用者需要持有锁。
+func (g *Group) unpublish(key string) {
+	g.fast.Delete(key)
+}
+
+// unpublishAll 移除所有发布的结果，调用者需要持有锁。
+func (g *Group) unpublishAll() {
+	g.fast.Range(func(key, _ interface{}) bool {
+		g.fast.Delete(key)
+		return true
+	})
+}
@@ -0,0 +1,262 @@
+package timesf
+
+import (
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFastPathStress(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithClock(clock))
+	var version int64
+	fn := func() (interface{}, error) {
+		return atomic.AddInt64(&version, 1), nil
+	}
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	worker := func(op func(r *rand.Rand, key string)) {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(seed))
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				op(r, strconv.Itoa(r.Intn(8)))
+			}
+		}(rand.Int63())
+	}
+	for i := 0; i < 8; i++ {
+		worker(func(r *rand.Rand, key string) {
+			v, err, _ := g.Do(key, time.Second, fn)
+			if _, ok := v.(int64); !ok || err != nil {
+				t.Errorf("Do(%q) = %v, %v; want an int64 version", key, v, err)
+			}
+		})
+	}
+	worker(func(r *rand.Rand, key string) {
+		if res := <-g.DoChan(key, time.Second, fn); res.Err != nil {
+			t.Errorf("DoChan(%q) err = %v", key, res.Err)
+		}
+	})
+	worker(func(r *rand.Rand, key string) { g.Forget(key) })
+	worker(func(r *rand.Rand, key string) { g.Set(key, int64(-1), time.Second) })
+	worker(func(r *rand.Rand, key string) { g.Touch(key, time.Second) })
+	worker(func(r *rand.Rand, key string) {
+		if r.Intn(100) == 0 {
+			g.ForgetAll()
+		}
+		clock.Advance(time.Duration(r.Intn(10)) * time.Millisecond)
+	})
+	time.Sleep(200 * time.Millisecond)
+	close(stop)
+	wg.Wait()
+}
+
+func TestFastPathForget(t *testing.T) {
+	var g Group
+	var calls int32
+	fn := func() (interface{}, error) {
+		return atomic.AddInt32(&calls, 1), nil
+	}
+	g.Do("key", time.Hour, fn)
+	// 第二次命中后结果被发布到无锁路径上
+	g.Do("key", time.Hour, fn)
+	if v, _, _ := g.Do("key", time.Hour, fn); v != int32(1) {
+		t.Errorf("fast hit = %v; want 1", v)
+	}
+	g.Forget("key")
+	if v, _, _ := g.Do("key", time.Hour, fn); v != int32(2) {
+		t.Errorf("Do after Forget = %v; want 2", v)
+	}
+	g.Do("key", time.Hour, fn)
+	g.Set("key", int32(10), time.Hour)
+	if v, _, _ := g.Do("key", time.Hour, fn); v != int32(10) {
+		t.Errorf("Do after Set = %v; want 10", v)
+	}
+	if st := g.Stats(); st.Hits != 4 || st.Misses != 2 {
+		t.Errorf("Stats = %+v; want 4 hits and 2 misses", st)
+	}
+}
+
+func TestHotKeyDoesNotBlockColdKeys(t *testing.T) {
+	release := make(chan struct{})
+	evicting := make(chan struct{})
+	var once sync.Once
+	g := New(WithOnEvict(func(key string, val interface{}, reason EvictReason) {
+		if key == "slow-evict" {
+			once.Do(func() { close(evicting) })
+			<-release
+		}
+	}))
+
+	// 一个正在执行的慢调用，大量调用者在等待其结果。
+	started := make(chan struct{})
+	hot := g.DoChan("hot", time.Hour, func() (interface{}, error) {
+		close(started)
+		<-release
+		return "hot", nil
+	})
+	<-started
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, _, _ := g.Do("hot", time.Hour, nil); v != "hot" {
+				t.Errorf("hot Do = %v; want hot", v)
+			}
+		}()
+	}
+	// 一个很慢的移除回调。
+	g.Set("slow-evict", 1, time.Hour)
+	go g.Forget("slow-evict")
+	<-evicting
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			key := "cold" + strconv.Itoa(i)
+			v, err, _ := g.Do(key, time.Hour, func() (interface{}, error) {
+				return key, nil
+			})
+			if v != key || err != nil {
+				t.Errorf("Do(%q) = %v, %v", key, v, err)
+			}
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("cold keys blocked by a slow hot key")
+	}
+	close(release)
+	<-hot
+	wg.Wait()
+}
+
+// BenchmarkColdKeyWithHotKey 测量一个key被大量并发命中时，其他key未命中的延迟，p99-ns
+// 是尾部延迟。locked使用加锁的命中路径作为对比。
+func BenchmarkColdKeyWithHotKey(b *testing.B) {
+	for _, bb := range []struct {
+		name string
+		opts []Option
+	}{
+		{"fast", nil},
+		{"locked", []Option{WithCapacity(1 << 30)}},
+	} {
+		b.Run(bb.name, func(b *testing.B) {
+			g := New(bb.opts...)
+			fn := func() (interface{}, error) {
+				return nil, nil
+			}
+			g.Do("hot", time.Hour, fn)
+			stop := make(chan struct{})
+			var wg sync.WaitGroup
+			for i := 0; i < 4; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for {
+						select {
+						case <-stop:
+							return
+						default:
+							g.Do("hot", time.Hour, fn)
+						}
+					}
+				}()
+			}
+
+			lat := make([]time.Duration, b.N)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				start := time.Now()
+				g.Do(strconv.Itoa(i), time.Hour, fn)
+				lat[i] = time.Since(start)
+			}
+			b.StopTimer()
+			close(stop)
+			wg.Wait()
+			sort.Slice(lat, func(i, j int) bool { return lat[i] < lat[j] })
+			b.ReportMetric(float64(lat[len(lat)*99/100]), "p99-ns")
+		})
+	}
+}
+
+func TestDoMissAllocs(t *testing.T) {
+	var g Group
+	fn := func() (interface{}, error) {
+		return nil, nil
+	}
+	allocs := testing.AllocsPerRun(100, func() {
+		g.Do("key", time.Hour, fn)
+		g.Forget("key")
+	})
+	// 只有调用本身需要分配
+	if allocs != 1 {
+		t.Errorf("Do and Forget of a cold key allocated %v times; want 1", allocs)
+	}
+}
+
+func BenchmarkDoHit(b *testing.B) {
+	var g Group
+	fn := func() (interface{}, error) {
+		return nil, nil
+	}
+	g.Do("key", time.Hour, fn)
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			g.Do("key", time.Hour, fn)
+		}
+	})
+}
+
+func BenchmarkDoMiss(b *testing.B) {
+	var g Group
+	fn := func() (interface{}, error) {
+		return nil, nil
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		g.Do("key", time.Hour, fn)
+		g.Forget("key")
+	}
+}
+
+func BenchmarkDoChan(b *testing.B) {
+	var g Group
+	fn := func() (interface{}, error) {
+		return nil, nil
+	}
+	g.Do("key", time.Hour, fn)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		<-g.DoChan("key", time.Hour, fn)
+	}
+}
+
+func BenchmarkPeekParallel(b *testing.B) {
+	var g Group
+	g.Do("key", time.Hour, func() (interface{}, error) {
+		return "bar", nil
+	})
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			g.Peek("key")
+			g.TTL("key")
+		}
+	})
+}
@@ -0,0 +1,132 @@
+package timesf
+
+// ForgetMode 决定Forget系列方法遗忘正在进行的调用时，已经在等待其结果的DoChan通道拿到
+// 什么，见WithForgetMode。
+type ForgetMode int
+
+const (
+	// ForgetKeep 让等待的通道仍然拿到被遗忘的调用的结果，这是默认值。
+	ForgetKeep ForgetMode = iota
+	// ForgetNotify 让等待的调用者立即拿到ErrForgotten，像ForgetAndNotify一样。
+	ForgetNotify
+	// ForgetReattach 让等待的通道转而等待key的下一次执行：遗忘时用同样的方法为key开始新
+	// 的调用，通道拿到新调用的结果。之后到达的调用者同样共享这个调用。Do系列方法的等待者
+	// 仍然拿到被遗忘的调用的结果。DoMulti的批量调用没有单独的方法，关闭的Group不再开始
+	// 新调用，这些情况下像ForgetKeep一样处理。
+	ForgetReattach
+)
+
+// WithForgetMode 设置Forget、ForgetStatus、ForgetFunc、ForgetPrefix和ForgetAll遗忘正在
+// 进行的调用时等待其结果的调用者的处理方式，见ForgetMode。ForgetAndNotify和
+// ForgetAndCancel不受影响。
+func WithForgetMode(mode ForgetMode) Option {
+	return func(g *Group) {
+		g.forgetMode = mode
+	}
+}
+
+// orphan 按照WithForgetMode处理刚刚被遗忘的key的调用c的等待者，返回需要在释放锁之后
+// 发送的结果。c已经从map中删除，调用者需要持有锁，并在释放锁之后调用notifyEvicted。
+func (g *Group) orphan(evs []eviction, key string, c *call) ([]eviction, delivery) {
+	if c.done {
+		return evs, delivery{}
+	}
+	switch g.forgetMode {
+	case ForgetNotify:
+		return evs, g.abort(key, c)
+	case ForgetReattach:
+		return g.reattach(evs, key, c), delivery{}
+	}
+	return evs, delivery{}
+}
+
+// abort 中止key的调用c，等待者立即拿到ErrForgotten，返回需要发送给等待通道的结果。调用者
+// 需要持有锁。
+func (g *Group) abort(key string, c *call) delivery {
+	c.aborted = true
+	c.closeReady()
+	d := delivery{chans: c.chans, r: Result{Err: ErrForgotten, Shared: true}}
+	g.letGo(key, c, len(c.chans), ErrForgotten)
+	c.chans = nil
+	return d
+}
+
+// reattach 为key开始一个和被遗忘的调用old使用同样方法的新调用，并把old等待的通道转移
+// 过去。调用者需要持有锁。
+func (g *Group) reattach(evs []eviction, key string, old *call) []eviction {
+	p := old.rerun()
+	if len(old.chans) == 0 || g.refused != nil || old.fn == nil && p.ttlFn == nil && p.ctxFn == nil && p.seedFn == nil && p.versionFn == nil {
+		return evs
+	}
+	moved := len(old.chans)
+	if old.chanOwner {
+		moved--
+	}
+	c := &call{fn: old.fn, params: p, startedAt: g.now(), chans: old.chans, dups: len(old.chans)}
+	old.chans, old.chanOwner = nil, false
+	old.dups -= moved
+	g.letGo(key, old, moved, ErrForgotten)
+	c.cancelable()
+	if g.m == nil {
+		g.m = make(map[string]*call)
+		g.t = make(map[string]int64)
+	}
+	g.begin()
+	g.m[key] = c
+	g.pending++
+	g.t[key] = g.pendingValidTime(p.validTime)
+	g.unpublish(key)
+	g.track(key, nil, c)
+	evs = g.trim(evs, c)
+	go g.doCall(c, key, c.fn)
+	return evs
+}
+
+// pend 在设置了WithForgetMode时，将被遗忘的还在进行中的调用c记录到pending中，之后由
+// orphanAll处理。调用者需要持有锁。
+func (g *Group) pend(pending map[string]*call, key string, c *call) map[string]*call {
+	if c.done || g.forgetMode == ForgetKeep {
+		return pending
+	}
+	if pending == nil {
+		pending = make(map[string]*call)
+	}
+	pending[key] = c
+	return pending
+}
+
+// rerun 返回用同样的方法重新执行c时使用的参数，不包括调用者的上下文。c.ctx由执行方法
+// 的协程写入，因此不能整体复制c.params。调用者需要持有锁。
+func (c *call) rerun() params {
+	return params{
+		validTime:   c.validTime,
+		staleFor:    c.staleFor,
+		fresh:       c.fresh,
+		errorTTL:    c.errorTTL,
+		hasErrorTTL: c.hasErrorTTL,
+		maxWait:     c.maxWait,
+		ownerWait:   c.ownerWait,
+		noWait:      c.noWait,
+		force:       c.force,
+		ttlFn:       c.ttlFn,
+		seedFn:      c.seedFn,
+		fallback:    c.fallback,
+		expireAt:    c.expireAt,
+		versionFn:   c.versionFn,
+		version:     c.version,
+		ctxFn:       c.ctxFn,
+	}
+}
+
+// orphanAll 像orphan一样处理pending中所有被遗忘的调用，evs收集新的移除。调用者需要持有锁。
+func (g *Group) orphanAll(evs *[]eviction, pending map[string]*call) []delivery {
+	var ds []delivery
+	for key, c := range pending {
+		var d delivery
+		*evs, d = g.orphan(*evs, key, c)
+		if len(d.chans) > 0 {
+			ds = append(ds, d)
+		}
+	}
+	return ds
+}
@@ -0,0 +1,68 @@
+package timesf
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// forgetPending 在key有一个阻塞的调用和两个等待的通道时遗忘key，返回两个通道和让第一次
+// 执行完成的函数。方法返回其是第几次执行。
+func forgetPending(g *Group, forget func()) (owner, waiter <-chan Result, release func()) {
+	var calls int32
+	block, started := make(chan struct{}), make(chan struct{})
+	fn := func() (interface{}, error) {
+		n := atomic.AddInt32(&calls, 1)
+		if n == 1 {
+			close(started)
+			<-block
+		}
+		return int(n), nil
+	}
+	owner = g.DoChan("key", time.Hour, fn)
+	waiter = g.DoChan("key", time.Hour, fn)
+	<-started
+	forget()
+	return owner, waiter, func() { close(block) }
+}
+
+func TestForgetKeep(t *testing.T) {
+	g := New()
+	owner, waiter, release := forgetPending(g, func() { g.Forget("key") })
+	release()
+	for _, ch := range []<-chan Result{owner, waiter} {
+		if r := <-ch; r.Val != 1 || !r.Forgotten {
+			t.Errorf("result = %+v; want the forgotten execution's 1", r)
+		}
+	}
+	if g.Has("key") {
+		t.Error("forgotten result was cached")
+	}
+}
+
+func TestForgetNotify(t *testing.T) {
+	g := New(WithForgetMode(ForgetNotify))
+	owner, waiter, release := forgetPending(g, func() { g.ForgetPrefix("k") })
+	defer release()
+	for _, ch := range []<-chan Result{owner, waiter} {
+		if r := <-ch; r.Err != ErrForgotten {
+			t.Errorf("result = %+v; want ErrForgotten before the call finishes", r)
+		}
+	}
+}
+
+func TestForgetReattach(t *testing.T) {
+	g := New(WithForgetMode(ForgetReattach))
+	owner, waiter, release := forgetPending(g, func() { g.ForgetAll() })
+	// 新调用在被遗忘的调用完成之前就开始了
+	for _, ch := range []<-chan Result{owner, waiter} {
+		if r := <-ch; r.Val != 2 || r.Err != nil {
+			t.Errorf("result = %+v; want the next execution's 2", r)
+		}
+	}
+	release()
+	waitFor(t, func() bool { return g.Stats().InFlight == 0 })
+	if v, _, _ := g.Peek("key"); v != 2 {
+		t.Errorf("cached value = %v; want 2", v)
+	}
+}
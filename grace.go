@@ -0,0 +1,29 @@
+package timesf
+
+import "time"
+
+// WithStaleIfError 在DoStale或者提前刷新的后台刷新失败之后，将旧的结果仍然可以返回的
+// 时间延长到失败之后的grace时长，即使DoStale的staleFor已经耗尽，后端持续不可用时调用
+// 者仍然拿到最后一次成功的结果，而不是重新执行方法并拿到错误。每一次刷新失败都重新延长，
+// 刷新的错误只通过Hooks的OnRefreshError报告。grace不大于0时不延长。
+func WithStaleIfError(grace time.Duration) Option {
+	return func(g *Group) {
+		g.staleIfError = grace
+	}
+}
+
+// staleUntil 返回已完成的调用c在过期时间t之后仍然可以返回旧值的截止时间，调用者需要持有锁。
+func (c *call) staleUntil(t int64) int64 {
+	until := addTime(t, c.staleFor)
+	if c.graceUntil > until {
+		return c.graceUntil
+	}
+	return until
+}
+
+// refreshFailed 调用OnRefreshError钩子，调用者不能持有锁。
+func (g *Group) refreshFailed(key string, err error) {
+	if h := g.hooks; h != nil && h.OnRefreshError != nil {
+		h.OnRefreshError(key, err)
+	}
+}
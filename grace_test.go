@@ -0,0 +1,49 @@
+package timesf
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWithStaleIfError(t *testing.T) {
+	clock := newFakeClock()
+	boom := errors.New("backend down")
+	failures := make(chan error, 1)
+	g := New(WithClock(clock), WithStaleIfError(time.Minute), WithHooks(Hooks{
+		OnRefreshError: func(key string, err error) { failures <- err },
+	}))
+	fail := false
+	fn := func() (interface{}, error) {
+		if fail {
+			return nil, boom
+		}
+		return "good", nil
+	}
+	g.DoStale("key", time.Minute, 10*time.Second, fn)
+	fail = true
+
+	// staleFor早已耗尽，但是每一次刷新失败都延长了可以返回旧值的时间
+	clock.Advance(time.Minute + time.Second)
+	for i := 0; i < 3; i++ {
+		v, err, _, stale := g.DoStale("key", time.Minute, 10*time.Second, fn)
+		if v != "good" || err != nil || !stale {
+			t.Fatalf("DoStale #%d during outage = %v, %v, stale=%v; want the last good value", i, v, err, stale)
+		}
+		select {
+		case err := <-failures:
+			if err != boom {
+				t.Errorf("OnRefreshError err = %v; want %v", err, boom)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("OnRefreshError was not called for refresh #%d", i)
+		}
+		clock.Advance(30 * time.Second)
+	}
+
+	// 没有调用者的时间超过grace之后，错误像平常一样返回
+	clock.Advance(time.Minute)
+	if _, err, _, stale := g.DoStale("key", time.Minute, 10*time.Second, fn); err != boom || stale {
+		t.Errorf("DoStale after grace = %v, stale=%v; want %v", err, stale, boom)
+	}
+}
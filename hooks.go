@@ -0,0 +1,44 @@
+package timesf
+
+import "time"
+
+// Hooks 是Group在关键位置调用的钩子，可以用来接入链路追踪等观测系统。为nil的字段
+// 不会被调用。所有钩子都在释放锁之后被调用，耗时的钩子不会阻塞其他key的调用。
+type Hooks struct {
+	// BeforeCall 在方法执行之前被调用，包括后台刷新。
+	BeforeCall func(key string)
+	// AfterCall 在方法执行之后被调用，dur是方法的执行时长，err是方法返回的错误。
+	AfterCall func(key string, dur time.Duration, err error)
+	// OnHit 在调用者直接拿到已完成的结果时被调用，age是结果完成至今的时长。
+	OnHit func(key string, age time.Duration)
+	// OnWait 在Do系列方法等待正在进行的调用完成之后被调用，waited是等待的时长。
+	// DoChan的调用者不在方法内等待，因此不会触发OnWait。
+	OnWait func(key string, waited time.Duration)
+	// OnDebounce 在新调用等待WithDebounce的窗口之后、执行方法之前被调用，waited是等待
+	// 的时长。
+	OnDebounce func(key string, waited time.Duration)
+	// OnHotKey 在正在进行的调用的重复调用者超过WithHotKeyThreshold时被调用，dups是此时
+	// 重复调用者的数量，每一次调用最多触发一次。
+	OnHotKey func(key string, dups int)
+	// OnEntryLifetime 在已完成的结果离开Group时被调用，age是结果完成至今的时长，reason
+	// 和WithOnEvict的相同，可以据此统计结果实际存活的时长来调整有效时长。还在调用中的
+	// 结果被移除时不会触发。
+	OnEntryLifetime func(key string, age time.Duration, reason EvictReason)
+	// OnRefreshError 在后台刷新失败时被调用，err是刷新的错误。刷新失败时调用者继续拿到
+	// 旧的结果，这个错误不会返回给任何调用者，见WithStaleIfError。
+	OnRefreshError func(key string, err error)
+}
+
+// WithHooks 设置Group的钩子，见Hooks。
+func WithHooks(h Hooks) Option {
+	return func(g *Group) {
+		g.hooks = &h
+	}
+}
+
+// hit 调用OnHit钩子，age是结果完成至今的纳秒数。调用者不能持有锁。
+func (g *Group) hit(key string, age time.Duration) {
+	if h := g.hooks; h != nil && h.OnHit != nil {
+		h.OnHit(key, age)
+	}
+}
@@ -0,0 +1,96 @@
+package timesf
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// hookRecorder 记录钩子的调用，供测试检查。
+type hookRecorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *hookRecorder) add(format string, args ...interface{}) {
+	r.mu.Lock()
+	r.events = append(r.events, fmt.Sprintf(format, args...))
+	r.mu.Unlock()
+}
+
+func (r *hookRecorder) take() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	evs := r.events
+	r.events = nil
+	sort.Strings(evs)
+	return evs
+}
+
+func (r *hookRecorder) hooks() Hooks {
+	return Hooks{
+		BeforeCall: func(key string) { r.add("before %s", key) },
+		AfterCall:  func(key string, dur time.Duration, err error) { r.add("after %s %v %v", key, dur, err) },
+		OnHit:      func(key string, age time.Duration) { r.add("hit %s %v", key, age) },
+		OnWait:     func(key string, waited time.Duration) { r.add("wait %s %v", key, waited) },
+	}
+}
+
+func TestHooks(t *testing.T) {
+	clock := newFakeClock()
+	var rec hookRecorder
+	g := New(WithClock(clock), WithHooks(rec.hooks()))
+
+	g.Do("key", time.Minute, func() (interface{}, error) {
+		clock.Advance(time.Second)
+		return "v", nil
+	})
+	if got, want := fmt.Sprint(rec.take()), "[after key 1s <nil> before key]"; got != want {
+		t.Errorf("miss hooks = %v; want %v", got, want)
+	}
+
+	clock.Advance(2 * time.Second)
+	g.Do("key", time.Minute, nil)
+	<-g.DoChan("key", time.Minute, nil)
+	if got, want := fmt.Sprint(rec.take()), "[hit key 2s hit key 2s]"; got != want {
+		t.Errorf("hit hooks = %v; want %v", got, want)
+	}
+
+	err := errors.New("boom")
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go g.Do("err", time.Minute, func() (interface{}, error) {
+		close(started)
+		<-release
+		return nil, err
+	})
+	<-started
+	done := make(chan struct{})
+	go func() {
+		g.Do("err", time.Minute, nil)
+		close(done)
+	}()
+	waitFor(t, func() bool { return g.Stats().Coalesced == 1 })
+	clock.Advance(3 * time.Second)
+	close(release)
+	<-done
+	if got, want := fmt.Sprint(rec.take()), "[after err 3s boom before err wait err 3s]"; got != want {
+		t.Errorf("wait hooks = %v; want %v", got, want)
+	}
+}
+
+func TestHooksPartial(t *testing.T) {
+	var hits int
+	g := New(WithHooks(Hooks{OnHit: func(string, time.Duration) { hits++ }}))
+	fn := func() (interface{}, error) {
+		return nil, nil
+	}
+	g.Do("key", time.Minute, fn)
+	g.Do("key", time.Minute, fn)
+	if hits != 1 {
+		t.Errorf("hits = %d; want 1", hits)
+	}
+}
@@ -0,0 +1,202 @@
+// Package httpdedupe 提供基于timesf.Group的http.RoundTripper，合并并缓存相同的GET和HEAD
+// 请求。
+package httpdedupe
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ChangsongLiQD/timesf"
+)
+
+// DefaultMaxBodySize 是默认的可以缓存的响应体的最大字节数，见WithMaxBodySize。
+const DefaultMaxBodySize = 1 << 20
+
+// keyHeaders 是会影响响应的请求头，其值的摘要是key的一部分，不同凭证或者范围的请求不会
+// 共享响应，摘要也不会让凭证出现在日志中。
+var keyHeaders = []string{"Authorization", "Cookie", "Range", "Accept"}
+
+// Option 配置NewTransport创建的Transport。
+type Option func(*transport)
+
+// WithMaxBodySize 设置可以缓存的响应体的最大字节数，n不大于0时使用DefaultMaxBodySize。
+// 响应体超过n的请求不被缓存：执行请求的调用者和共享其结果的调用者都改为直接发送自己的
+// 请求，之后ttl时长内的请求同样直接发送。
+func WithMaxBodySize(n int64) Option {
+	return func(t *transport) {
+		if n > 0 {
+			t.maxBodySize = n
+		}
+	}
+}
+
+type transport struct {
+	g           *timesf.Group
+	ttl         time.Duration
+	next        http.RoundTripper
+	maxBodySize int64
+}
+
+// NewTransport 创建一个使用g合并并缓存请求的http.RoundTripper，请求由next发送，next为
+// nil时使用http.DefaultTransport；g为nil时使用一个新的Group。只有GET和HEAD请求被合并，
+// key由方法、URL以及Authorization、Cookie、Range和Accept请求头决定，成功的响应缓存ttl
+// 时长。其他方法、带有请求体或者带有Cache-Control: no-store请求头的请求直接发送。
+//
+// 缓存的是完整读取的响应：状态、响应头和响应体，每一个调用者拿到独立的*http.Response。
+// 非2xx的响应作为错误交给Group，是否缓存由g的错误缓存配置决定，调用者拿到的仍然是响应
+// 而不是错误。请求的上下文按照timesf.Group.DoCtx的方式处理：一个调用者的请求被取消不会
+// 影响其他调用者。
+func NewTransport(g *timesf.Group, ttl time.Duration, next http.RoundTripper, opts ...Option) http.RoundTripper {
+	if g == nil {
+		g = timesf.New()
+	}
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	t := &transport{g: g, ttl: ttl, next: next, maxBodySize: DefaultMaxBodySize}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// RoundTrip 实现http.RoundTripper。
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !cacheable(req) {
+		return t.next.RoundTrip(req)
+	}
+	v, err, _ := t.g.DoCtx(req.Context(), key(req), t.ttl, func(ctx context.Context) (interface{}, error) {
+		return t.fetch(req.Clone(ctx))
+	})
+	var se *statusError
+	if errors.As(err, &se) {
+		return se.b.response(req), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	b := v.(*buffered)
+	if b.tooLarge {
+		return t.next.RoundTrip(req)
+	}
+	return b.response(req), nil
+}
+
+// cacheable 报告req是否可以被合并和缓存。
+func cacheable(req *http.Request) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+	if req.Body != nil && req.Body != http.NoBody {
+		return false
+	}
+	for _, v := range req.Header.Values("Cache-Control") {
+		for _, d := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(d), "no-store") {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// key 返回req在Group中的key。
+func key(req *http.Request) string {
+	k := req.Method + " " + req.URL.String()
+	h := sha256.New()
+	var found bool
+	for _, name := range keyHeaders {
+		vs := req.Header.Values(name)
+		found = found || len(vs) > 0
+		fmt.Fprintf(h, "%s:%q\n", name, vs)
+	}
+	if !found {
+		return k
+	}
+	return k + " #" + hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// fetch 发送req并完整读取响应，非2xx的响应返回*statusError。
+func (t *transport) fetch(req *http.Request) (interface{}, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.ContentLength > t.maxBodySize {
+		return &buffered{tooLarge: true}, nil
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, t.maxBodySize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > t.maxBodySize {
+		return &buffered{tooLarge: true}, nil
+	}
+	b := &buffered{
+		status:        resp.Status,
+		statusCode:    resp.StatusCode,
+		proto:         resp.Proto,
+		protoMajor:    resp.ProtoMajor,
+		protoMinor:    resp.ProtoMinor,
+		header:        resp.Header,
+		trailer:       resp.Trailer,
+		contentLength: resp.ContentLength,
+		body:          body,
+	}
+	if req.Method == http.MethodGet {
+		b.contentLength = int64(len(body))
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &statusError{b}
+	}
+	return b, nil
+}
+
+// buffered 是完整读取的响应，创建之后不再改变。tooLarge 标识响应体超过了最大字节数，
+// 没有被读取。
+type buffered struct {
+	status        string
+	statusCode    int
+	proto         string
+	protoMajor    int
+	protoMinor    int
+	header        http.Header
+	trailer       http.Header
+	contentLength int64
+	body          []byte
+	tooLarge      bool
+}
+
+// response 返回b对于req的一个独立的副本。
+func (b *buffered) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        b.status,
+		StatusCode:    b.statusCode,
+		Proto:         b.proto,
+		ProtoMajor:    b.protoMajor,
+		ProtoMinor:    b.protoMinor,
+		Header:        b.header.Clone(),
+		Trailer:       b.trailer.Clone(),
+		ContentLength: b.contentLength,
+		Body:          io.NopCloser(bytes.NewReader(b.body)),
+		Request:       req,
+	}
+}
+
+// statusError 是非2xx的响应，作为错误交给Group，以便遵循其错误缓存的配置。
+type statusError struct {
+	b *buffered
+}
+
+func (e *statusError) Error() string {
+	return "httpdedupe: unexpected status " + e.b.status
+}
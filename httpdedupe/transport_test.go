@@ -0,0 +1,131 @@
+package httpdedupe
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ChangsongLiQD/timesf"
+)
+
+func get(t *testing.T, c *http.Client, url string, header ...string) (int, string) {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Errorf("GET %s: %v", url, err)
+		return 0, ""
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestTransport(t *testing.T) {
+	var hits int32
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		if r.URL.Path == "/slow" {
+			<-release
+		}
+		w.Header().Set("X-Path", r.URL.Path)
+		io.WriteString(w, "body of "+r.URL.Path)
+	}))
+	defer srv.Close()
+	c := &http.Client{Transport: NewTransport(nil, time.Hour, nil)}
+
+	const n = 10
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if code, body := get(t, c, srv.URL+"/slow"); code != http.StatusOK || body != "body of /slow" {
+				t.Errorf("GET /slow = %d %q; want 200 body of /slow", code, body)
+			}
+		}()
+	}
+	for atomic.LoadInt32(&hits) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if _, body := get(t, c, srv.URL+"/slow"); body != "body of /slow" {
+		t.Errorf("cached GET body = %q", body)
+	}
+	if n := atomic.LoadInt32(&hits); n != 1 {
+		t.Errorf("server hits = %d; want 1", n)
+	}
+
+	// 不同凭证的请求不共享响应
+	get(t, c, srv.URL+"/slow", "Authorization", "Bearer other")
+	// POST和no-store的请求直接发送
+	resp, err := c.Post(srv.URL+"/slow", "text/plain", strings.NewReader("x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	get(t, c, srv.URL+"/slow", "Cache-Control", "no-store")
+	if n := atomic.LoadInt32(&hits); n != 4 {
+		t.Errorf("server hits = %d; want 4", n)
+	}
+}
+
+func TestTransportStatus(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	c := &http.Client{Transport: NewTransport(nil, time.Hour, nil)}
+	for i := 0; i < 2; i++ {
+		if code, _ := get(t, c, srv.URL); code != http.StatusNotFound {
+			t.Errorf("status = %d; want 404", code)
+		}
+	}
+	if n := atomic.LoadInt32(&hits); n != 2 {
+		t.Errorf("server hits without error caching = %d; want 2", n)
+	}
+
+	c = &http.Client{Transport: NewTransport(timesf.New(timesf.WithErrorCaching(true)), time.Hour, nil)}
+	for i := 0; i < 2; i++ {
+		if code, body := get(t, c, srv.URL); code != http.StatusNotFound || !strings.Contains(body, "not found") {
+			t.Errorf("response = %d %q; want 404 page", code, body)
+		}
+	}
+	if n := atomic.LoadInt32(&hits); n != 3 {
+		t.Errorf("server hits with error caching = %d; want 3", n)
+	}
+}
+
+func TestTransportMaxBodySize(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		io.WriteString(w, "longer than four")
+	}))
+	defer srv.Close()
+
+	c := &http.Client{Transport: NewTransport(nil, time.Hour, nil, WithMaxBodySize(4))}
+	for i := 0; i < 2; i++ {
+		if _, body := get(t, c, srv.URL); body != "longer than four" {
+			t.Errorf("body = %q; want the full body", body)
+		}
+	}
+	// 第一次读取超过限制之后直接发送，之后的请求同样直接发送
+	if n := atomic.LoadInt32(&hits); n != 3 {
+		t.Errorf("server hits = %d; want 3", n)
+	}
+}
@@ -0,0 +1,71 @@
+package timesf
+
+import (
+	"context"
+	"time"
+)
+
+// WithMinInterval 限制每一个key重新执行方法的频率：key最近一次执行方法完成之后的d时长
+// 内，需要开始新调用的调用者（比如结果过期得太快或者被遗忘）不会再执行方法，而是直接
+// 拿到最近一次的结果，包括其错误，其ExpiresAt是间隔结束的时间。wait为true时改为开始
+// 新调用，但是等到间隔结束之后才执行方法，期间其他调用者像平常一样共享这个调用。只作用
+// 于Do和DoChan系列方法，DoMulti的批量调用和后台刷新不受限制，但同样被记录为最近一次
+// 执行。d不大于0时不限制。
+func WithMinInterval(d time.Duration, wait bool) Option {
+	return func(g *Group) {
+		g.minInterval = d
+		g.minIntervalWait = wait
+	}
+}
+
+// throttled 返回key在WithMinInterval的间隔之内最近一次完成的调用，没有时返回nil。调用者
+// 需要持有锁。
+func (g *Group) throttled(key string, now int64) *call {
+	if g.minInterval <= 0 {
+		return nil
+	}
+	c := g.computed[key]
+	if c == nil {
+		return nil
+	}
+	if now-c.doneAt >= int64(g.minInterval) {
+		delete(g.computed, key)
+		return nil
+	}
+	return c
+}
+
+// remember 记录c是key最近一次完成的调用，调用者需要持有锁。
+func (g *Group) remember(key string, c *call) {
+	if g.minInterval <= 0 {
+		return
+	}
+	if g.computed == nil {
+		g.computed = make(map[string]*call)
+	}
+	g.computed[key] = c
+}
+
+// pruneComputed 删除所有已经超出间隔的记录，调用者需要持有锁。
+func (g *Group) pruneComputed(now int64) {
+	for key, c := range g.computed {
+		if now-c.doneAt >= int64(g.minInterval) {
+			delete(g.computed, key)
+		}
+	}
+}
+
+// pause 等到until之后再返回，ctx被取消或者hurry被关闭时提前返回。调用者不能持有锁。
+func (g *Group) pause(ctx context.Context, until int64, hurry <-chan struct{}) {
+	d := time.Duration(until - g.now())
+	if d <= 0 {
+		return
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	case <-hurry:
+	}
+}
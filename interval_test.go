@@ -0,0 +1,59 @@
+package timesf
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithMinInterval(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithMinInterval(100*time.Millisecond, false), WithClock(clock))
+	calls := 0
+	fn := func() (interface{}, error) {
+		calls++
+		return calls, nil
+	}
+
+	for i := 0; i < 50; i++ {
+		g.DoChan("key", time.Millisecond, fn)
+		v, _, _ := g.Do("key", time.Millisecond, fn)
+		if want := i/10 + 1; v != want {
+			t.Fatalf("Do at %dms = %v; want %d", i*10, v, want)
+		}
+		g.Forget("key")
+		clock.Advance(10 * time.Millisecond)
+	}
+	if calls != 5 {
+		t.Errorf("calls = %d; want 5, one per interval", calls)
+	}
+
+	r := g.DoDetailed("key", time.Millisecond, fn)
+	if r.Val != 6 || r.Shared {
+		t.Errorf("DoDetailed after interval = %v, shared %v; want 6, false", r.Val, r.Shared)
+	}
+	clock.Advance(time.Millisecond)
+	r = g.DoDetailed("key", time.Millisecond, fn)
+	if want := clock.Now().Add(99 * time.Millisecond); r.Val != 6 || !r.ExpiresAt.Equal(want) {
+		t.Errorf("throttled DoDetailed = %v, expires %v; want 6, %v", r.Val, r.ExpiresAt, want)
+	}
+}
+
+func TestWithMinIntervalWait(t *testing.T) {
+	const interval = 50 * time.Millisecond
+	g := New(WithMinInterval(interval, true))
+	calls := 0
+	fn := func() (interface{}, error) {
+		calls++
+		return calls, nil
+	}
+	g.Do("key", time.Millisecond, fn)
+	g.Forget("key")
+	start := time.Now()
+	v, _, _ := g.Do("key", time.Millisecond, fn)
+	if v != 2 || calls != 2 {
+		t.Errorf("Do after Forget = %v (calls %d); want 2", v, calls)
+	}
+	if d := time.Since(start); d < interval/2 {
+		t.Errorf("Do returned after %v; want it to wait for the interval", d)
+	}
+}
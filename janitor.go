@@ -0,0 +1,78 @@
+package timesf
+
+import (
+	"sync"
+	"time"
+)
+
+// janitor 定期清理Group中已经过期的结果。
+type janitor struct {
+	interval time.Duration
+	stop     chan struct{}
+	once     sync.Once
+	// done 在后台协程退出时被关闭，见Close。
+	done chan struct{}
+}
+
+// WithJanitor 开启一个每隔interval清理一次过期结果的后台协程，不再被访问的key也会
+// 被及时移除。使用完Group后需要调用Stop停止此协程。
+func WithJanitor(interval time.Duration) Option {
+	return func(g *Group) {
+		g.janitor = &janitor{interval: interval, stop: make(chan struct{}), done: make(chan struct{})}
+	}
+}
+
+// NewWithJanitor 创建一个每隔interval清理一次过期结果的Group，见WithJanitor。
+func NewWithJanitor(interval time.Duration) *Group {
+	return New(WithJanitor(interval))
+}
+
+// Stop 停止WithJanitor开启的后台协程，可以重复调用。没有开启时什么都不做。
+func (g *Group) Stop() {
+	if g.janitor != nil {
+		g.janitor.once.Do(func() {
+			close(g.janitor.stop)
+		})
+	}
+}
+
+// runJanitor 定期调用deleteExpired，直到Stop被调用。
+func (g *Group) runJanitor(j *janitor) {
+	defer close(j.done)
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			g.deleteExpired()
+		case <-j.stop:
+			return
+		}
+	}
+}
+
+// deleteExpired 删除所有已经完成、已经过期并且不能再作为旧值返回的结果，返回删除的
+// 数量。被ExpireNow保留的结果等待新的结果替换，不会被删除。
+func (g *Group) deleteExpired() int {
+	var evs []eviction
+	g.mu.Lock()
+	now := g.now()
+	n := 0
+	for key, c := range g.m {
+		if !c.done || c.refreshing != nil || c.expiredEarly || c.staleUntil(g.t[key]) > now {
+			continue
+		}
+		g.untrack(c)
+		delete(g.m, key)
+		delete(g.t, key)
+		g.unpublish(key)
+		g.stats.evictions.Add(1)
+		evs = g.evict(evs, key, c, EvictExpired)
+		n++
+	}
+	g.pruneComputed(now)
+	g.sweepTombs(now)
+	g.mu.Unlock()
+	g.notifyEvicted(evs)
+	return n
+}
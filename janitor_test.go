@@ -0,0 +1,56 @@
+package timesf
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestJanitor(t *testing.T) {
+	clock := newFakeClock()
+	var rec evictRecorder
+	g := New(WithJanitor(time.Millisecond), WithClock(clock), WithOnEvict(rec.onEvict))
+	defer g.Stop()
+	fn := func() (interface{}, error) {
+		return "v", nil
+	}
+
+	g.Do("short", time.Second, fn)
+	g.Do("long", time.Hour, fn)
+	g.DoStale("stale", time.Second, time.Hour, fn)
+	release := make(chan struct{})
+	ch := g.DoChan("inflight", time.Second, func() (interface{}, error) {
+		<-release
+		return nil, nil
+	})
+
+	clock.Advance(2 * time.Second)
+	waitFor(t, func() bool { return g.Len() == 3 })
+	if got, want := fmt.Sprint(rec.take()), "[short=v:expired]"; got != want {
+		t.Errorf("evictions = %v; want %v", got, want)
+	}
+
+	close(release)
+	<-ch
+	waitFor(t, func() bool { return g.Len() == 2 })
+}
+
+func TestJanitorStop(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithJanitor(time.Millisecond), WithClock(clock))
+	g.Stop()
+	g.Stop()
+
+	g.Do("key", time.Second, func() (interface{}, error) {
+		return nil, nil
+	})
+	clock.Advance(2 * time.Second)
+	time.Sleep(10 * time.Millisecond)
+	if n := g.Len(); n != 1 {
+		t.Errorf("Len after Stop = %d; want 1", n)
+	}
+
+	var zero Group
+	zero.Stop()
+	NewWithJanitor(time.Hour).Stop()
+}
@@ -0,0 +1,59 @@
+package timesf
+
+import "time"
+
+// WithKeyFunc 设置key的转换方法，比如把由很多字段拼接而成的很长的key哈希为固定长度的
+// key，以减少内存占用和比较的开销。Do、DoChan、DoMulti、Forget、Set、Peek等接收key的
+// 方法都先用fn转换key，转换之后相同的key共享同一个调用和结果。DoKey等方法的fn、以及
+// DoMulti的fn仍然拿到调用者的原始key。Keys、ForEach、Entries、Export、钩子、日志和
+// 移除回调拿到的是转换之后的key，Import和ForgetFunc等按key匹配的方法也使用转换之后的
+// key。fn需要是确定的，并且可以被并发调用。
+func WithKeyFunc(fn func(key string) string) Option {
+	return func(g *Group) {
+		g.keyFn = fn
+	}
+}
+
+// normalize 按照WithKeyFunc转换调用者的key。
+func (g *Group) normalize(key string) string {
+	if g.keyFn == nil {
+		return key
+	}
+	return g.keyFn(key)
+}
+
+// multi 按照WithKeyFunc转换keys之后调用doMulti：fn拿到并返回原始的key，返回的结果也
+// 以原始的key保存，转换之后相同的key得到同一个结果。
+func (g *Group) multi(keys []string, validTime time.Duration, fn func(missing []string) (map[string]interface{}, error), notLoaded error) (map[string]Result, error) {
+	if g.keyFn == nil {
+		return g.doMulti(keys, validTime, fn, notLoaded)
+	}
+	internal := make([]string, len(keys))
+	raw := make(map[string]string, len(keys))
+	for i, key := range keys {
+		internal[i] = g.keyFn(key)
+		if _, ok := raw[internal[i]]; !ok {
+			raw[internal[i]] = key
+		}
+	}
+	res, err := g.doMulti(internal, validTime, func(missing []string) (map[string]interface{}, error) {
+		rawMissing := make([]string, len(missing))
+		for i, key := range missing {
+			rawMissing[i] = raw[key]
+		}
+		vals, err := fn(rawMissing)
+		if vals == nil {
+			return nil, err
+		}
+		converted := make(map[string]interface{}, len(vals))
+		for key, v := range vals {
+			converted[g.keyFn(key)] = v
+		}
+		return converted, err
+	}, notLoaded)
+	results := make(map[string]Result, len(keys))
+	for i, key := range keys {
+		results[key] = res[internal[i]]
+	}
+	return results, err
+}
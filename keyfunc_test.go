@@ -0,0 +1,81 @@
+package timesf
+
+import (
+	"crypto/sha256"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithKeyFunc(t *testing.T) {
+	g := New(WithKeyFunc(strings.ToLower))
+	release := make(chan struct{})
+	started := make(chan struct{})
+	calls := 0
+	fn := func() (interface{}, error) {
+		calls++
+		close(started)
+		<-release
+		return "bar", nil
+	}
+	ch := g.DoChan("Key", time.Hour, fn)
+	<-started
+	done := make(chan bool)
+	go func() {
+		_, _, shared := g.Do("KEY", time.Hour, fn)
+		done <- shared
+	}()
+	waitFor(t, func() bool { return g.InFlight()["key"] == 1 })
+	close(release)
+	if r := <-ch; r.Val != "bar" || !r.Shared {
+		t.Errorf("DoChan(Key) = %+v; want shared bar", r)
+	}
+	if !<-done || calls != 1 {
+		t.Errorf("Do(KEY) did not coalesce with DoChan(Key), calls = %d", calls)
+	}
+	if v, _, ok := g.Peek("kEy"); !ok || v != "bar" {
+		t.Errorf("Peek(kEy) = %v, %v; want bar, true", v, ok)
+	}
+	keys := g.Keys()
+	if len(keys) != 1 || keys[0] != "key" {
+		t.Errorf("Keys = %v; want [key]", keys)
+	}
+
+	g.Forget("KEY")
+	if g.Has("key") || g.Len() != 0 {
+		t.Error("Forget on the raw key did not clear the normalized entry")
+	}
+}
+
+func TestWithKeyFuncMulti(t *testing.T) {
+	hash := func(key string) string {
+		sum := sha256.Sum256([]byte(key))
+		return string(sum[:])
+	}
+	g := New(WithKeyFunc(hash))
+	g.Set("a", "cached", time.Hour)
+	var got []string
+	res := g.DoMulti([]string{"a", "b", "c"}, time.Hour, func(missing []string) (map[string]interface{}, error) {
+		got = missing
+		vals := make(map[string]interface{})
+		for _, key := range missing {
+			vals[key] = "v" + key
+		}
+		return vals, nil
+	})
+	if len(got) != 2 || got[0] != "b" || got[1] != "c" {
+		t.Errorf("missing = %q; want raw keys [b c]", got)
+	}
+	if res["a"].Val != "cached" || res["b"].Val != "vb" || res["c"].Val != "vc" {
+		t.Errorf("DoMulti = %+v; want cached, vb, vc", res)
+	}
+	if v, _, _ := g.Do("b", time.Hour, nil); v != "vb" {
+		t.Errorf("Do(b) after DoMulti = %v; want vb", v)
+	}
+
+	s := NewSharded(8, WithKeyFunc(strings.ToLower))
+	s.Do("Key", time.Hour, func() (interface{}, error) { return "bar", nil })
+	if v, _, _ := s.Do("KEY", time.Hour, nil); v != "bar" {
+		t.Errorf("ShardedGroup Do(KEY) = %v; want bar", v)
+	}
+}
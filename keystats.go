@@ -0,0 +1,41 @@
+package timesf
+
+import "time"
+
+// KeyStats 是Group中一个已完成结果的统计数据，见Group.KeyStats。
+type KeyStats struct {
+	// Hits 是调用者直接命中此结果的次数，不包括等待其完成的调用者。
+	Hits uint64
+	// LastAccess 是最近一次命中的时间，还没有被命中时为结果完成的时间。
+	LastAccess time.Time
+	// ComputeTime 是得到此结果的方法的执行时长，Set写入或者从共享存储读取的结果为0。
+	ComputeTime time.Duration
+}
+
+// KeyStats 返回key已完成的结果的统计数据，结果的开始和完成时间见Entries。key不存在或者
+// 还在调用中时返回false。
+func (g *Group) KeyStats(key string) (KeyStats, bool) {
+	key = g.normalize(key)
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	c, ok := g.m[key]
+	if !ok || !c.done {
+		return KeyStats{}, false
+	}
+	return c.keyStats(), true
+}
+
+// accessed 记录在now命中c一次，不需要持有锁。
+func (c *call) accessed(now int64) {
+	c.hits.Add(1)
+	c.lastHit.Store(now)
+}
+
+// keyStats 返回已完成的调用c的统计数据，调用者需要持有锁。
+func (c *call) keyStats() KeyStats {
+	s := KeyStats{Hits: c.hits.Load(), LastAccess: time.Unix(0, c.doneAt), ComputeTime: c.took}
+	if t := c.lastHit.Load(); t != 0 {
+		s.LastAccess = time.Unix(0, t)
+	}
+	return s
+}
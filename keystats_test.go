@@ -0,0 +1,43 @@
+package timesf
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKeyStats(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithClock(clock))
+	if _, ok := g.KeyStats("key"); ok {
+		t.Error("KeyStats of absent key = true; want false")
+	}
+	start := clock.Now()
+	g.Do("key", time.Hour, func() (interface{}, error) {
+		clock.Advance(50 * time.Millisecond)
+		return "bar", nil
+	})
+	s, ok := g.KeyStats("key")
+	if !ok || s.Hits != 0 || s.ComputeTime != 50*time.Millisecond || !s.LastAccess.Equal(start.Add(50*time.Millisecond)) {
+		t.Errorf("KeyStats after miss = %+v, %v; want no hits, 50ms compute, last access at completion", s, ok)
+	}
+
+	for i := 0; i < 3; i++ {
+		clock.Advance(time.Second)
+		g.Do("key", time.Hour, nil)
+	}
+	<-g.DoChan("key", time.Hour, nil)
+	s, _ = g.KeyStats("key")
+	if want := start.Add(50*time.Millisecond + 3*time.Second); s.Hits != 4 || !s.LastAccess.Equal(want) {
+		t.Errorf("KeyStats after hits = %+v; want 4 hits, last access %v", s, want)
+	}
+
+	entries := g.Entries()
+	if len(entries) != 1 || entries[0].KeyStats != s {
+		t.Errorf("Entries = %+v; want KeyStats %+v", entries, s)
+	}
+
+	g.Set("set", 1, time.Hour)
+	if s, ok := g.KeyStats("set"); !ok || s.ComputeTime != 0 {
+		t.Errorf("KeyStats of Set value = %+v, %v; want zero compute time", s, ok)
+	}
+}
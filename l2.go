@@ -0,0 +1,60 @@
+package timesf
+
+import (
+	"math"
+	"time"
+)
+
+// L2 是Group之下的第二级缓存，比如进程之外的Redis，值不需要编解码，见WithL2。
+type L2 interface {
+	// Get 返回key的值，found为false表示不存在。
+	Get(key string) (val interface{}, found bool, err error)
+	// Set 保存key的值ttl时长，ttl为0表示永不过期。
+	Set(key string, val interface{}, ttl time.Duration) error
+}
+
+// WithL2 像WithStore，但是使用不需要编解码的第二级缓存l：Group中的结果缺失时，执行方法的
+// 调用者先从l中查找，找到时使用其值，本地的有效时长从此时开始按照调用的validTime计算；
+// 找不到时执行方法，并把成功的结果以相同的有效时长写回l。同时缺失的调用者仍然共享一次
+// 查找和方法的执行。l返回错误时只输出日志，像没有设置l一样执行方法。和WithStore同时
+// 设置时后设置的生效。
+func WithL2(l L2) Option {
+	return func(g *Group) {
+		g.store = &storeConfig{l2: l}
+	}
+}
+
+// loadL2 像load方法，但是从第二级缓存中查找。
+func (g *Group) loadL2(c *call, key string) bool {
+	val, found, err := g.store.l2.Get(key)
+	if err != nil {
+		g.logStore("get", key, err)
+		return false
+	}
+	if !found {
+		return false
+	}
+	g.mu.Lock()
+	t := g.getValidTime(c.validTime)
+	g.mu.Unlock()
+	c.val, c.err, c.expiresAt = val, nil, t
+	return true
+}
+
+// saveL2 像save方法，但是写入第二级缓存。
+func (g *Group) saveL2(c *call, key string, ttl time.Duration) {
+	if c.val == nil && g.skipNil {
+		return
+	}
+	g.mu.Lock()
+	t := g.getValidTime(ttl)
+	g.mu.Unlock()
+	c.expiresAt = t
+	var d time.Duration
+	if t != math.MaxInt64 {
+		d = time.Duration(t - g.now())
+	}
+	if err := g.store.l2.Set(key, c.val, d); err != nil {
+		g.logStore("set", key, err)
+	}
+}
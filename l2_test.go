@@ -0,0 +1,83 @@
+package timesf
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memL2 是记录访问顺序的L2，用于测试。
+type memL2 struct {
+	mu  sync.Mutex
+	m   map[string]interface{}
+	ops []string
+}
+
+func (l *memL2) Get(key string) (interface{}, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.ops = append(l.ops, "get "+key)
+	v, ok := l.m[key]
+	return v, ok, nil
+}
+
+func (l *memL2) Set(key string, val interface{}, ttl time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.ops = append(l.ops, fmt.Sprintf("set %s=%v %v", key, val, ttl))
+	l.m[key] = val
+	return nil
+}
+
+func (l *memL2) log(op string) {
+	l.mu.Lock()
+	l.ops = append(l.ops, op)
+	l.mu.Unlock()
+}
+
+func TestWithL2(t *testing.T) {
+	clock := newFakeClock()
+	l2 := &memL2{m: map[string]interface{}{"remote": "from l2"}}
+	g := New(WithClock(clock), WithL2(l2))
+	fn := func(key string) func() (interface{}, error) {
+		return func() (interface{}, error) {
+			l2.log("fn " + key)
+			return "computed", nil
+		}
+	}
+
+	if v, _, _ := g.Do("remote", time.Minute, fn("remote")); v != "from l2" {
+		t.Errorf("Do with L2 hit = %v; want from l2", v)
+	}
+	if ttl, _ := g.TTL("remote"); ttl != time.Minute {
+		t.Errorf("TTL of L2 hit = %v; want 1m", ttl)
+	}
+	if v, _, _ := g.Do("local", time.Minute, fn("local")); v != "computed" {
+		t.Errorf("Do with L2 miss = %v; want computed", v)
+	}
+	// 本地命中不访问L2
+	g.Do("local", time.Minute, fn("local"))
+
+	want := "[get remote get local fn local set local=computed 1m0s]"
+	if got := fmt.Sprint(l2.ops); got != want {
+		t.Errorf("ops = %v; want %v", got, want)
+	}
+}
+
+func TestWithL2SkipsNil(t *testing.T) {
+	l2 := &memL2{m: map[string]interface{}{}}
+	g := New(WithL2(l2), WithNilCaching(false))
+	calls := 0
+	fn := func() (interface{}, error) {
+		calls++
+		return nil, nil
+	}
+	g.Do("k", time.Minute, fn)
+	if _, ok := l2.m["k"]; ok {
+		t.Error("nil result was written to L2 with WithNilCaching(false)")
+	}
+	if v, _, _ := g.Do("k", time.Minute, fn); v != nil || calls != 2 {
+		t.Errorf("second Do = %v (calls %d); want nil, 2 calls", v, calls)
+	}
+}
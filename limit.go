@@ -0,0 +1,59 @@
+package timesf
+
+import "errors"
+
+// ErrTooManyComputes 在开启了快速失败，并且已经有WithMaxConcurrency设置的数量的方法
+// 正在执行时，返回给需要执行方法的调用者。
+var ErrTooManyComputes = errors.New("timesf: too many concurrent computes")
+
+// WithMaxConcurrency 限制所有key同时执行的方法最多n个，包括后台刷新和DoMulti的批量
+// 调用，为0时不限制。超过之后需要执行方法的调用者等待空闲的位置；failFast为true时不
+// 等待，而是立即拿到ErrTooManyComputes，此结果不会被缓存。等待其他调用结果的重复
+// 调用者不占用位置，像平常一样等待排队的调用。方法panic时同样释放位置。正在排队的方法
+// 数量见Stats的Queued。排队的调用被遗忘之后，其方法仍然在拿到位置之后执行，结果不会被
+// 缓存。
+func WithMaxConcurrency(n int, failFast bool) Option {
+	return func(g *Group) {
+		if n > 0 {
+			g.sem = make(chan struct{}, n)
+		}
+		g.failFast = failFast
+	}
+}
+
+// NewWithMaxConcurrency 创建一个最多同时执行n个方法的Group，见WithMaxConcurrency。
+func NewWithMaxConcurrency(n int) *Group {
+	return New(WithMaxConcurrency(n, false))
+}
+
+// limited 在WithMaxConcurrency的限制之内执行fn，ok为false表示快速失败，fn没有被执行。
+// priority 见WithComputePriority。
+func (g *Group) limited(priority int, fn func() (interface{}, error)) (v interface{}, err error, ok bool) {
+	if g.sem == nil {
+		v, err = fn()
+		return v, err, true
+	}
+	free := func() { <-g.sem }
+	switch {
+	case g.failFast:
+		select {
+		case g.sem <- struct{}{}:
+		default:
+			return nil, ErrTooManyComputes, false
+		}
+	case g.priority != nil:
+		g.admit(priority)
+		free = g.yield
+	default:
+		select {
+		case g.sem <- struct{}{}:
+		default:
+			g.stats.queued.Add(1)
+			g.sem <- struct{}{}
+			g.stats.queued.Add(-1)
+		}
+	}
+	defer free()
+	v, err = fn()
+	return v, err, true
+}
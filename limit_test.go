@@ -0,0 +1,129 @@
+package timesf
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMaxConcurrency(t *testing.T) {
+	const n = 3
+	g := NewWithMaxConcurrency(n)
+	var running, peak int32
+	fn := func() (interface{}, error) {
+		cur := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if cur <= p || atomic.CompareAndSwapInt32(&peak, p, cur) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return nil, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		key := strconv.Itoa(i)
+		go func() {
+			defer wg.Done()
+			g.Do(key, time.Hour, fn)
+		}()
+		go func() {
+			defer wg.Done()
+			<-g.DoChan(key, time.Hour, fn)
+		}()
+	}
+	wg.Wait()
+	if p := atomic.LoadInt32(&peak); p > n || p == 0 {
+		t.Errorf("peak concurrent computes = %d; want between 1 and %d", p, n)
+	}
+	if l := g.Len(); l != 50 {
+		t.Errorf("Len = %d; want 50", l)
+	}
+
+	// 方法panic时释放位置
+	for i := 0; i < n+1; i++ {
+		func() {
+			defer func() { recover() }()
+			g.Do("panic"+strconv.Itoa(i), time.Hour, func() (interface{}, error) {
+				panic("boom")
+			})
+		}()
+	}
+	done := make(chan struct{})
+	go func() {
+		g.Do("after-panic", time.Hour, fn)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("slots not released after panics")
+	}
+}
+
+func TestMaxConcurrencyFailFast(t *testing.T) {
+	g := New(WithMaxConcurrency(1, true), WithErrorCaching(true))
+	release := make(chan struct{})
+	started := make(chan struct{})
+	ch := g.DoChan("slow", time.Hour, func() (interface{}, error) {
+		close(started)
+		<-release
+		return "slow", nil
+	})
+	<-started
+
+	calls := 0
+	fn := func() (interface{}, error) {
+		calls++
+		return "bar", nil
+	}
+	if _, err, _ := g.Do("other", time.Hour, fn); err != ErrTooManyComputes {
+		t.Errorf("Do while full err = %v; want ErrTooManyComputes", err)
+	}
+	if g.Has("other") {
+		t.Error("ErrTooManyComputes was cached")
+	}
+	close(release)
+	<-ch
+	if v, err, _ := g.Do("other", time.Hour, fn); v != "bar" || err != nil || calls != 1 {
+		t.Errorf("Do after slot freed = %v, %v (calls %d); want bar, nil, 1 call", v, err, calls)
+	}
+}
+
+func TestMaxConcurrencyQueued(t *testing.T) {
+	g := NewWithMaxConcurrency(1)
+	release := make(chan struct{})
+	started := make(chan struct{}, 3)
+	fn := func() (interface{}, error) {
+		started <- struct{}{}
+		<-release
+		return "bar", nil
+	}
+	first := g.DoChan("a", time.Hour, fn)
+	<-started
+	second := g.DoChan("b", time.Hour, fn)
+	third := g.DoChan("c", time.Hour, fn)
+	waiter := g.DoChan("b", time.Hour, fn)
+	waitFor(t, func() bool { return g.Stats().Queued == 2 })
+
+	// 遗忘排队的调用不影响其他调用，其等待者仍然拿到结果
+	g.Forget("b")
+	close(release)
+	for _, ch := range []<-chan Result{first, second, third, waiter} {
+		if r := <-ch; r.Val != "bar" || r.Err != nil {
+			t.Errorf("result = %+v; want bar", r)
+		}
+	}
+	if s := g.Stats(); s.Queued != 0 || s.InFlight != 0 {
+		t.Errorf("Stats after completion = %+v; want nothing queued or in flight", s)
+	}
+	if g.Has("b") || !g.Has("c") {
+		t.Error("forgotten queued call was cached, or another call was not")
+	}
+}
@@ -0,0 +1,39 @@
+package timesf
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrNoLoader 在没有用WithLoader设置加载方法的Group上调用Get时返回。
+var ErrNoLoader = errors.New("timesf: no loader configured")
+
+// DoKey 像Do方法，但是fn接收key作为参数，同一个加载方法可以用于所有的key，而不需要
+// 为每一个key创建闭包。
+func (g *Group) DoKey(key string, validTime time.Duration, fn func(key string) (interface{}, error)) (v interface{}, err error, shared bool) {
+	return g.Do(key, validTime, func() (interface{}, error) {
+		return fn(key)
+	})
+}
+
+// DoChanKey 像DoChan方法，但是fn接收key作为参数，见DoKey。
+func (g *Group) DoChanKey(key string, validTime time.Duration, fn func(key string) (interface{}, error)) <-chan Result {
+	return g.DoChan(key, validTime, func() (interface{}, error) {
+		return fn(key)
+	})
+}
+
+// WithLoader 设置Group默认的加载方法，Get使用它来获取缺失的key。
+func WithLoader(fn func(key string) (interface{}, error)) Option {
+	return func(g *Group) {
+		g.loader = fn
+	}
+}
+
+// Get 像DoKey方法，但是使用WithLoader设置的加载方法，没有设置时返回ErrNoLoader。
+func (g *Group) Get(key string, validTime time.Duration) (v interface{}, err error, shared bool) {
+	if g.loader == nil {
+		return nil, ErrNoLoader, false
+	}
+	return g.DoKey(key, validTime, g.loader)
+}
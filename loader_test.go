@@ -0,0 +1,44 @@
+package timesf
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDoKeyAndGet(t *testing.T) {
+	calls := map[string]int{}
+	someErr := errors.New("some error")
+	load := func(key string) (interface{}, error) {
+		calls[key]++
+		if key == "bad" {
+			return nil, someErr
+		}
+		return "val:" + key, nil
+	}
+
+	var g Group
+	if v, err, _ := g.DoKey("a", time.Hour, load); v != "val:a" || err != nil {
+		t.Errorf("DoKey = %v, %v; want val:a, nil", v, err)
+	}
+	if r := <-g.DoChanKey("b", time.Hour, load); r.Val != "val:b" || r.Err != nil {
+		t.Errorf("DoChanKey = %+v; want val:b", r)
+	}
+	g.DoKey("a", time.Hour, load)
+	if _, err, _ := g.Get("a", time.Hour); err != ErrNoLoader {
+		t.Errorf("Get without loader err = %v; want ErrNoLoader", err)
+	}
+
+	l := New(WithLoader(load))
+	if v, err, _ := l.Get("a", time.Hour); v != "val:a" || err != nil {
+		t.Errorf("Get = %v, %v; want val:a, nil", v, err)
+	}
+	if _, err, _ := l.Get("bad", time.Hour); err != someErr {
+		t.Errorf("Get(bad) err = %v; want %v", err, someErr)
+	}
+	l.Get("a", time.Hour)
+	l.Get("bad", time.Hour)
+	if calls["a"] != 2 || calls["b"] != 1 || calls["bad"] != 2 {
+		t.Errorf("calls = %v; want a:2 b:1 bad:2", calls)
+	}
+}
@@ -0,0 +1,15 @@
+package timesf
+
+// Logger 接收Group生命周期中的调试事件，例如新建调用、合并重复调用、结果移除以及方法
+// 返回错误。*log.Logger满足此接口。
+type Logger interface {
+	Logf(format string, args ...interface{})
+}
+
+// WithLogger 设置Group的调试日志。没有设置时Group不输出任何日志，也不会因此产生额外
+// 的内存分配。
+func WithLogger(l Logger) Option {
+	return func(g *Group) {
+		g.logger = l
+	}
+}
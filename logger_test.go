@@ -0,0 +1,103 @@
+package timesf
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// logRecorder 记录输出的日志，供测试检查。
+type logRecorder struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (r *logRecorder) Logf(format string, args ...interface{}) {
+	r.mu.Lock()
+	r.lines = append(r.lines, fmt.Sprintf(format, args...))
+	r.mu.Unlock()
+}
+
+func (r *logRecorder) take() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := strings.Join(r.lines, "\n")
+	r.lines = nil
+	return s
+}
+
+func TestLogger(t *testing.T) {
+	clock := newFakeClock()
+	var rec logRecorder
+	g := New(WithClock(clock), WithLogger(&rec))
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go g.Do("key", time.Minute, func() (interface{}, error) {
+		close(started)
+		<-release
+		clock.Advance(time.Second)
+		return nil, errors.New("boom")
+	})
+	<-started
+	ch := g.DoChan("key", time.Minute, nil)
+	close(release)
+	<-ch
+	waitFor(t, func() bool { return g.Len() == 0 })
+	g.Do("key", time.Minute, func() (interface{}, error) {
+		return nil, nil
+	})
+	clock.Advance(time.Minute)
+	g.Do("key", time.Minute, func() (interface{}, error) {
+		return nil, nil
+	})
+	g.Forget("key")
+
+	got := rec.take()
+	for _, want := range []string{
+		`timesf: new call for key "key", valid for 1m0s`,
+		`timesf: duplicate call for key "key" suppressed`,
+		`timesf: call for key "key" failed after 1s: boom`,
+		`timesf: key "key" expired`,
+		`timesf: key "key" forgotten`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("log = %q; want it to contain %q", got, want)
+		}
+	}
+}
+
+func TestLoggerPanic(t *testing.T) {
+	var rec logRecorder
+	g := New(WithClock(newFakeClock()), WithLogger(&rec))
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Errorf("recover() = %v; want boom", r)
+			}
+		}()
+		g.Do("key", time.Minute, func() (interface{}, error) {
+			panic("boom")
+		})
+	}()
+	if got, want := rec.take(), `timesf: call for key "key" panicked after 0s: boom`; !strings.Contains(got, want) {
+		t.Errorf("log = %q; want it to contain %q", got, want)
+	}
+}
+
+func TestNoLoggerAllocs(t *testing.T) {
+	var g Group
+	fn := func() (interface{}, error) {
+		return nil, nil
+	}
+	g.Do("key", time.Hour, fn)
+	allocs := testing.AllocsPerRun(100, func() {
+		g.Do("key", time.Hour, fn)
+	})
+	if allocs != 0 {
+		t.Errorf("Do on a cached key allocated %v times; want 0", allocs)
+	}
+}
@@ -0,0 +1,89 @@
+package timesf
+
+import "container/list"
+
+// WithCapacity 设置Group最多记录的已完成结果的数量，为0时不限制。写入新的结果超过容量时，
+// 最久没有被访问的已完成结果会被移除。正在调用中的key不计入容量，也不会被移除。
+func WithCapacity(n int) Option {
+	return func(g *Group) {
+		g.capacity = n
+	}
+}
+
+// NewWithCapacity 创建一个最多记录n个key的Group，见WithCapacity。
+func NewWithCapacity(n int) *Group {
+	return New(WithCapacity(n))
+}
+
+// bounded 报告是否设置了容量或者开销上限，只有这时才需要维护LRU列表。
+func (g *Group) bounded() bool {
+	return g.capacity > 0 || g.maxCost > 0
+}
+
+// track 在设置了容量时，将新写入key的调用c记录为最近使用，old是被c替换的调用。
+// 调用者需要持有锁。
+func (g *Group) track(key string, old, c *call) {
+	if !g.bounded() {
+		return
+	}
+	if old != nil {
+		g.uncharge(old)
+	}
+	if g.lru == nil {
+		g.lru = list.New()
+	}
+	if old != nil && old.elem != nil {
+		c.elem, old.elem = old.elem, nil
+		g.lru.MoveToFront(c.elem)
+		return
+	}
+	c.elem = g.lru.PushFront(key)
+}
+
+// touch 将调用c标记为最近使用，调用者需要持有锁。
+func (g *Group) touch(c *call) {
+	if c.elem != nil {
+		g.lru.MoveToFront(c.elem)
+	}
+}
+
+// untrack 停止追踪已经从map中删除的调用c，调用者需要持有锁。
+func (g *Group) untrack(c *call) {
+	g.uncharge(c)
+	if c.elem != nil {
+		g.lru.Remove(c.elem)
+		c.elem = nil
+	}
+}
+
+// trim 在超过容量或者开销上限时，从最久没有被访问的一端开始移除除了keep之外已完成的
+// 结果，直到不再超过或者没有可以移除的结果。keep是刚刚写入的调用，其开销单独就超过
+// 开销上限时只移除它本身。调用者需要持有锁。
+func (g *Group) trim(evs []eviction, keep *call) []eviction {
+	if !g.bounded() || g.lru == nil {
+		return evs
+	}
+	if keep != nil && keep.done && keep.elem != nil && g.maxCost > 0 && keep.cost > g.maxCost {
+		return g.remove(evs, keep.elem.Value.(string), keep)
+	}
+	e := g.lru.Back()
+	for g.overBudget() && e != nil {
+		prev := e.Prev()
+		key := e.Value.(string)
+		if c := g.m[key]; c.done && c != keep {
+			evs = g.remove(evs, key, c)
+		}
+		e = prev
+	}
+	return evs
+}
+
+// remove 因为超过容量或者开销上限移除key已完成的结果c，调用者需要持有锁。
+func (g *Group) remove(evs []eviction, key string, c *call) []eviction {
+	g.untrack(c)
+	delete(g.m, key)
+	delete(g.t, key)
+	g.unpublish(key)
+	g.stats.evictions.Add(1)
+	return g.evict(evs, key, c, EvictLRU)
+}
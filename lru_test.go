@@ -0,0 +1,160 @@
+package timesf
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestCapacityEvictsLeastRecentlyUsed(t *testing.T) {
+	var rec evictRecorder
+	g := New(WithCapacity(3), WithOnEvict(rec.onEvict))
+	val := func(v interface{}) func() (interface{}, error) {
+		return func() (interface{}, error) {
+			return v, nil
+		}
+	}
+
+	g.Do("a", time.Hour, val("a"))
+	g.Do("b", time.Hour, val("b"))
+	g.Do("c", time.Hour, val("c"))
+	g.Do("a", time.Hour, val("a")) // a成为最近访问的key
+	g.Do("d", time.Hour, val("d"))
+	g.Set("e", "e", time.Hour)
+
+	keys := g.Keys()
+	sort.Strings(keys)
+	if got, want := fmt.Sprint(keys), "[a d e]"; got != want {
+		t.Errorf("Keys = %v; want %v", got, want)
+	}
+	if got, want := fmt.Sprint(rec.take()), "[b=b:lru c=c:lru]"; got != want {
+		t.Errorf("evictions = %v; want %v", got, want)
+	}
+	if s := g.Stats(); s.Evictions != 2 {
+		t.Errorf("Stats.Evictions = %d; want 2", s.Evictions)
+	}
+}
+
+func TestCapacityKeepsInFlight(t *testing.T) {
+	g := NewWithCapacity(1)
+	release := make(chan struct{})
+	ch := g.DoChan("inflight", time.Hour, func() (interface{}, error) {
+		<-release
+		return nil, nil
+	})
+	g.Set("a", "a", time.Hour)
+	g.Set("b", "b", time.Hour)
+
+	keys := g.Keys()
+	sort.Strings(keys)
+	if got, want := fmt.Sprint(keys), "[b inflight]"; got != want {
+		t.Errorf("Keys = %v; want %v", got, want)
+	}
+	close(release)
+	<-ch
+
+	g.Forget("b")
+	g.Set("c", "c", time.Hour)
+	keys = g.Keys()
+	sort.Strings(keys)
+	if got, want := fmt.Sprint(keys), "[c]"; got != want {
+		t.Errorf("Keys after completion = %v; want %v", got, want)
+	}
+	if n := g.lru.Len(); n != g.Len() {
+		t.Errorf("lru length = %d; want %d", n, g.Len())
+	}
+}
+
+func TestCapacityCountsCompletedOnly(t *testing.T) {
+	g := NewWithCapacity(2)
+	release := make(chan struct{})
+	ch := g.DoChan("inflight", time.Hour, func() (interface{}, error) {
+		<-release
+		return nil, nil
+	})
+	g.Set("a", "a", time.Hour)
+	g.Set("b", "b", time.Hour)
+
+	// 正在调用中的key不占用容量
+	keys := g.Keys()
+	sort.Strings(keys)
+	if got, want := fmt.Sprint(keys), "[a b inflight]"; got != want {
+		t.Errorf("Keys = %v; want %v", got, want)
+	}
+	close(release)
+	<-ch
+	keys = g.Keys()
+	sort.Strings(keys)
+	if got, want := fmt.Sprint(keys), "[b inflight]"; got != want {
+		t.Errorf("Keys after completion = %v; want %v", got, want)
+	}
+}
+
+func TestCapacityRandomOps(t *testing.T) {
+	const capacity = 8
+	clock := newFakeClock()
+	g := New(WithCapacity(capacity), WithClock(clock))
+	rnd := rand.New(rand.NewSource(1))
+	release := make(chan struct{})
+	var pending []<-chan Result
+	waiting := make(map[string]bool)
+	for i := 0; i < 5000; i++ {
+		key := fmt.Sprint(rnd.Intn(20))
+		switch rnd.Intn(7) {
+		case 0, 1:
+			if waiting[key] { // 不等待被阻塞的调用
+				continue
+			}
+			g.Do(key, time.Duration(rnd.Intn(5))*time.Second, func() (interface{}, error) {
+				return key, nil
+			})
+		case 2:
+			if len(pending) < 4 {
+				waiting[key] = true
+				release := release
+				pending = append(pending, g.DoChan(key, time.Hour, func() (interface{}, error) {
+					<-release
+					return key, nil
+				}))
+			}
+		case 3:
+			g.Forget(key)
+		case 4:
+			g.Set(key, key, time.Hour)
+		case 5:
+			clock.Advance(time.Second)
+		case 6:
+			close(release)
+			for _, ch := range pending {
+				<-ch
+			}
+			pending, release = nil, make(chan struct{})
+			waiting = make(map[string]bool)
+		}
+
+		g.mu.Lock()
+		inFlight := 0
+		for _, c := range g.m {
+			if c.elem == nil {
+				t.Fatalf("op %d: call for key not tracked in lru", i)
+			}
+			if !c.done {
+				inFlight++
+			}
+		}
+		n, l := len(g.m), g.lru.Len()
+		g.mu.Unlock()
+		if n != l {
+			t.Fatalf("op %d: len(m) = %d, lru length = %d", i, n, l)
+		}
+		if completed := n - inFlight; completed > capacity {
+			t.Fatalf("op %d: %d completed entries with %d in flight; want at most %d completed", i, completed, inFlight, capacity)
+		}
+	}
+	close(release)
+	for _, ch := range pending {
+		<-ch
+	}
+}
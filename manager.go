@@ -0,0 +1,195 @@
+package timesf
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Manager 管理多个命名的Namespace，比如用户、会话和商品各自的缓存：它们的key互不冲突，
+// 但是共享同一个Group，因此共享其容量、开销上限、清理协程和统计数据，超过上限时按照
+// 所有Namespace共同的最近使用顺序进行移除。
+type Manager struct {
+	g *Group
+}
+
+// NewManager 创建一个Manager，opts作用于所有Namespace共享的Group。Namespace的key在
+// 底层的Group中带有其名称作为前缀，WithKeyFunc、钩子和移除回调看到的都是带前缀的key，
+// 因此WithKeyFunc需要保留前缀，Namespace.Reset才能找到其key。
+func NewManager(opts ...Option) *Manager {
+	return &Manager{g: New(opts...)}
+}
+
+// Group 返回名为name的Namespace，同一个名称总是返回同一个Namespace，见Group.Sub。
+func (m *Manager) Group(name string) *Namespace {
+	return m.g.Sub(name)
+}
+
+// Sub 返回g中名为name的Namespace，其key在g中带有名称作为前缀，调用者看不到前缀。同一个
+// 名称总是返回同一个Namespace，它们共享g的配置、锁、容量和统计数据，见Manager。
+func (g *Group) Sub(name string) *Namespace {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return sub(&g.subs, g, nil, name)
+}
+
+// sub 返回subs中名为name的Namespace，不存在时创建，parent是上一级的Namespace，为nil时
+// 直接属于g。调用者需要持有保护subs的锁。
+func sub(subs *map[string]*Namespace, g *Group, parent *Namespace, name string) *Namespace {
+	if n, ok := (*subs)[name]; ok {
+		return n
+	}
+	// 名称的长度作为前缀的一部分，不同的名称得到的前缀不会互为前缀。
+	prefix := strconv.Itoa(len(name)) + ":" + name + ":"
+	if parent != nil {
+		prefix = parent.prefix + prefix
+	}
+	if *subs == nil {
+		*subs = make(map[string]*Namespace)
+	}
+	n := &Namespace{g: g, name: name, prefix: prefix, parent: parent}
+	(*subs)[name] = n
+	return n
+}
+
+// ResetAll 遗忘所有Namespace的所有key，返回被遗忘的key的数量，见Group.ForgetAll。
+func (m *Manager) ResetAll() int {
+	return m.g.ForgetAll()
+}
+
+// Len 返回所有Namespace记录的key数量。
+func (m *Manager) Len() int {
+	return m.g.Len()
+}
+
+// Stats 返回所有Namespace共同的统计数据。
+func (m *Manager) Stats() Stats {
+	return m.g.Stats()
+}
+
+// Close 关闭所有Namespace共享的Group，见Group.Close。
+func (m *Manager) Close() error {
+	return m.g.Close()
+}
+
+// Namespace 是Manager或者Group.Sub中一个命名的缓存，其方法和Group的同名方法相同，但是
+// 只作用于自己的key。Sub创建的下一级Namespace的key同时属于上一级。
+type Namespace struct {
+	g      *Group
+	name   string
+	prefix string
+	parent *Namespace
+	// stats 是此Namespace以及下一级Namespace的Do和DoChan调用的统计，见Stats。
+	stats stats
+
+	mu   sync.Mutex
+	subs map[string]*Namespace
+}
+
+// Name 返回Namespace的名称。
+func (n *Namespace) Name() string {
+	return n.name
+}
+
+// Sub 返回此Namespace中名为name的下一级Namespace，见Group.Sub。下一级的key、统计数据
+// 同时计入此Namespace，Reset同样遗忘下一级的key，Keys返回的下一级的key带有其前缀。
+func (n *Namespace) Sub(name string) *Namespace {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return sub(&n.subs, n.g, n, name)
+}
+
+// Do 见Group.Do。
+func (n *Namespace) Do(key string, validTime time.Duration, fn func() (interface{}, error)) (v interface{}, err error, shared bool) {
+	r := n.g.DoDetailed(n.prefix+key, validTime, fn)
+	n.record(r)
+	return r.Val, r.Err, r.Shared
+}
+
+// DoChan 见Group.DoChan。
+func (n *Namespace) DoChan(key string, validTime time.Duration, fn func() (interface{}, error)) <-chan Result {
+	ch := make(chan Result, 1)
+	rc := n.g.DoChan(n.prefix+key, validTime, fn)
+	go func() {
+		r := <-rc
+		n.record(r)
+		send(ch, r)
+	}()
+	return ch
+}
+
+// record 按照结果的Role将一次调用计入此Namespace以及上一级的统计数据。
+func (n *Namespace) record(r Result) {
+	for ; n != nil; n = n.parent {
+		switch r.Role {
+		case RoleCachedHit:
+			n.stats.hits.Add(1)
+		case RoleWaiter:
+			n.stats.coalesced.Add(1)
+		case RoleLeader:
+			n.stats.misses.Add(1)
+		}
+		if r.Err != nil {
+			n.stats.errors.Add(1)
+		}
+	}
+}
+
+// Stats 返回此Namespace的统计数据：Hits、Coalesced、Misses和Errors是Do和DoChan调用的
+// 计数，Entries是Len，其他字段为0。所有Namespace共同的统计数据见Group.Stats。
+func (n *Namespace) Stats() Stats {
+	return Stats{
+		Hits:      n.stats.hits.Load(),
+		Coalesced: n.stats.coalesced.Load(),
+		Misses:    n.stats.misses.Load(),
+		Errors:    n.stats.errors.Load(),
+		Entries:   n.Len(),
+	}
+}
+
+// Set 见Group.Set。
+func (n *Namespace) Set(key string, val interface{}, validTime time.Duration) {
+	n.g.Set(n.prefix+key, val, validTime)
+}
+
+// Peek 见Group.Peek。
+func (n *Namespace) Peek(key string) (val interface{}, err error, ok bool) {
+	return n.g.Peek(n.prefix + key)
+}
+
+// Has 见Group.Has。
+func (n *Namespace) Has(key string) bool {
+	return n.g.Has(n.prefix + key)
+}
+
+// Forget 见Group.Forget。
+func (n *Namespace) Forget(key string) {
+	n.g.Forget(n.prefix + key)
+}
+
+// Keys 返回此Namespace中记录的key，不带前缀，见Group.Keys。
+func (n *Namespace) Keys() []string {
+	var keys []string
+	for _, key := range n.g.Keys() {
+		if strings.HasPrefix(key, n.prefix) {
+			keys = append(keys, key[len(n.prefix):])
+		}
+	}
+	return keys
+}
+
+// Len 返回此Namespace中记录的key数量。
+func (n *Namespace) Len() int {
+	return len(n.Keys())
+}
+
+// Reset 遗忘此Namespace的所有key，其他Namespace不受影响，返回被遗忘的key的数量。
+func (n *Namespace) Reset() int {
+	return n.g.ForgetPrefix(n.prefix)
+}
+
+// ForgetAll 同Reset，和Group.ForgetAll同名。
+func (n *Namespace) ForgetAll() int {
+	return n.Reset()
+}
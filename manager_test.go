@@ -0,0 +1,114 @@
+package timesf
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestManagerNamespaces(t *testing.T) {
+	m := NewManager()
+	users, sessions := m.Group("users"), m.Group("sessions")
+	if m.Group("users") != users {
+		t.Error("Group returned a different Namespace for the same name")
+	}
+	users.Do("1", time.Hour, func() (interface{}, error) { return "alice", nil })
+	sessions.Do("1", time.Hour, func() (interface{}, error) { return "token", nil })
+	if v, _, _ := users.Peek("1"); v != "alice" {
+		t.Errorf("users.Peek(1) = %v; want alice", v)
+	}
+	if v, _, _ := sessions.Peek("1"); v != "token" {
+		t.Errorf("sessions.Peek(1) = %v; want token", v)
+	}
+
+	// 名称互为前缀的Namespace也不会冲突
+	a, ab := m.Group("a"), m.Group("ab")
+	a.Set("b:x", 1, time.Hour)
+	ab.Set("x", 2, time.Hour)
+	if n := a.Reset(); n != 1 || !ab.Has("x") {
+		t.Errorf("a.Reset = %d, ab.Has(x) = %v; want 1, true", n, ab.Has("x"))
+	}
+
+	users.Forget("1")
+	if users.Has("1") || !sessions.Has("1") {
+		t.Error("users.Forget affected another namespace")
+	}
+	users.Set("2", "bob", time.Hour)
+	if n := sessions.Reset(); n != 1 || users.Len() != 1 {
+		t.Errorf("sessions.Reset = %d, users.Len = %d; want 1, 1", n, users.Len())
+	}
+	if keys := users.Keys(); len(keys) != 1 || keys[0] != "2" {
+		t.Errorf("users.Keys = %v; want [2]", keys)
+	}
+	if n := m.ResetAll(); n != 2 || m.Len() != 0 {
+		t.Errorf("ResetAll = %d, Len = %d; want 2, 0", n, m.Len())
+	}
+}
+
+func TestManagerSharedBudget(t *testing.T) {
+	var rec evictRecorder
+	m := NewManager(WithCapacity(3), WithOnEvict(rec.onEvict))
+	users, products := m.Group("users"), m.Group("products")
+	users.Set("1", "u1", time.Hour)
+	users.Set("2", "u2", time.Hour)
+	products.Set("1", "p1", time.Hour)
+	products.Set("2", "p2", time.Hour)
+
+	if users.Has("1") || !users.Has("2") {
+		t.Error("least recently used user was not evicted for a product")
+	}
+	keys := append(users.Keys(), products.Keys()...)
+	sort.Strings(keys)
+	if got, want := fmt.Sprint(keys), "[1 2 2]"; got != want {
+		t.Errorf("keys = %v; want %v", got, want)
+	}
+	if got, want := fmt.Sprint(rec.take()), "[5:users:1=u1:lru]"; got != want {
+		t.Errorf("evictions = %v; want %v", got, want)
+	}
+	if s := m.Stats(); s.Evictions != 1 || s.Entries != 3 {
+		t.Errorf("Stats = %+v; want 1 eviction, 3 entries", s)
+	}
+}
+
+func TestGroupSub(t *testing.T) {
+	var g Group
+	users := g.Sub("users")
+	if g.Sub("users") != users {
+		t.Error("Sub returned a different Namespace for the same name")
+	}
+	admins := users.Sub("admins")
+	fn := func() (interface{}, error) { return "alice", nil }
+	users.Do("1", time.Hour, fn)
+	users.Do("1", time.Hour, fn)
+	admins.Do("1", time.Hour, func() (interface{}, error) { return "root", nil })
+	g.Do("1", time.Hour, fn)
+
+	if v, _, _ := admins.Peek("1"); v != "root" {
+		t.Errorf("admins.Peek(1) = %v; want root", v)
+	}
+	if v, _, _ := users.Peek("1"); v != "alice" {
+		t.Errorf("users.Peek(1) = %v; want alice", v)
+	}
+	if s := admins.Stats(); s.Misses != 1 || s.Hits != 0 || s.Entries != 1 {
+		t.Errorf("admins.Stats = %+v; want one miss, one entry", s)
+	}
+	// 下一级的调用和key同时计入上一级
+	if s := users.Stats(); s.Misses != 2 || s.Hits != 1 || s.Entries != 2 {
+		t.Errorf("users.Stats = %+v; want 2 misses, 1 hit, 2 entries", s)
+	}
+	if s := g.Stats(); s.Misses != 3 || s.Hits != 1 || s.Entries != 3 {
+		t.Errorf("Group.Stats = %+v; want the aggregate of all namespaces", s)
+	}
+
+	if n := admins.ForgetAll(); n != 1 || !users.Has("1") {
+		t.Errorf("admins.ForgetAll = %d, users.Has(1) = %v; want 1, true", n, users.Has("1"))
+	}
+	admins.Set("2", "x", time.Hour)
+	if n := users.ForgetAll(); n != 2 || !g.Has("1") {
+		t.Errorf("users.ForgetAll = %d, g.Has(1) = %v; want 2, true", n, g.Has("1"))
+	}
+	if r := <-users.Sub("admins").DoChan("3", time.Hour, fn); r.Val != "alice" {
+		t.Errorf("nested DoChan = %+v; want alice", r)
+	}
+}
@@ -0,0 +1,23 @@
+package timesf
+
+import "time"
+
+// WithMaxAge 限制结果最长的寿命：无论被滑动过期、Touch或者其他方式延长了多少次有效期，
+// 结果在完成d时长之后都会过期，之后的调用者重新执行方法。d不大于0时不限制。可以返回
+// 旧值的时长仍然从过期之后开始计算，见DoStale。
+func WithMaxAge(d time.Duration) Option {
+	return func(g *Group) {
+		g.maxAge = d
+	}
+}
+
+// capped 将已完成的调用c的过期时间t限制在WithMaxAge设置的寿命之内。调用者需要持有锁。
+func (g *Group) capped(c *call, t int64) int64 {
+	if g.maxAge <= 0 {
+		return t
+	}
+	if max := addTime(c.doneAt, g.maxAge); t > max {
+		return max
+	}
+	return t
+}
@@ -0,0 +1,48 @@
+package timesf
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMaxAge(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithClock(clock), WithMaxAge(time.Second), WithSlidingExpiration(true))
+	calls := 0
+	fn := func() (interface{}, error) {
+		calls++
+		return calls, nil
+	}
+
+	start := clock.Now()
+	g.Do("key", 300*time.Millisecond, fn)
+	for i := 0; i < 4; i++ {
+		clock.Advance(200 * time.Millisecond)
+		g.Do("key", 300*time.Millisecond, fn)
+	}
+	if calls != 1 {
+		t.Errorf("fn called %d times within max age; want 1", calls)
+	}
+	if ttl, _ := g.TTL("key"); ttl != 200*time.Millisecond {
+		t.Errorf("TTL near max age = %v; want 200ms", ttl)
+	}
+	g.Touch("key", time.Hour)
+	if ttl, _ := g.TTL("key"); ttl != 200*time.Millisecond {
+		t.Errorf("TTL after Touch = %v; want 200ms", ttl)
+	}
+	entries := g.Entries()
+	if len(entries) != 1 || !entries[0].ComputedAt.Equal(start) {
+		t.Errorf("Entries = %+v; want ComputedAt %v", entries, start)
+	}
+
+	clock.Advance(200 * time.Millisecond)
+	if v, _, _ := g.Do("key", 300*time.Millisecond, fn); v != 2 {
+		t.Errorf("Do after max age = %v; want 2", v)
+	}
+
+	g.Set("set", "v", NoExpiration)
+	clock.Advance(time.Second)
+	if g.Has("set") {
+		t.Error("never-expiring entry outlived max age")
+	}
+}
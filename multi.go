@@ -0,0 +1,166 @@
+package timesf
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// DoMulti 像Do方法，但是一次处理多个key：还未过期或者正在调用中的key共享已有的结果，
+// 其余缺失的key只调用一次fn进行批量获取，missing是这些key。fn返回的map中没有的key
+// 得到nil值，fn返回错误时所有缺失的key都得到此错误。返回的map包含keys中的每一个key。
+func (g *Group) DoMulti(keys []string, validTime time.Duration, fn func(missing []string) (map[string]interface{}, error)) map[string]Result {
+	results, _ := g.multi(keys, validTime, fn, nil)
+	return results
+}
+
+// ErrNotLoaded 是DoMany中fn返回的map里没有的key得到的错误。
+var ErrNotLoaded = errors.New("timesf: key not returned by batch loader")
+
+// DoMany 像DoMulti方法，但是fn返回的map中没有的key得到ErrNotLoaded，而不是nil值，这些
+// key的结果和其他错误一样按照Group的错误缓存配置处理。fn返回错误时同时返回此错误，
+// 所有缺失的key也得到此错误；其他情况返回nil。
+func (g *Group) DoMany(keys []string, validTime time.Duration, fn func(missing []string) (map[string]interface{}, error)) (map[string]Result, error) {
+	return g.multi(keys, validTime, fn, ErrNotLoaded)
+}
+
+// doMulti 是DoMulti和DoMany的底层实现，notLoaded不为nil时作为fn没有返回的key的错误。
+// 返回fn的错误。keys已经按照WithKeyFunc进行了转换，见multi。
+func (g *Group) doMulti(keys []string, validTime time.Duration, fn func(missing []string) (map[string]interface{}, error), notLoaded error) (map[string]Result, error) {
+	var evs []eviction
+	joined := make(map[string]*call)
+	ready := make(map[string]<-chan struct{})
+	hits := make(map[string]Result)
+	rejected := make(map[string]error)
+	owned := make(map[string]*call)
+	var hot []crowdedKey
+	var missing []string
+	g.mu.Lock()
+	if g.m == nil {
+		g.m = make(map[string]*call)
+		g.t = make(map[string]int64)
+	}
+	now := g.now()
+	for _, key := range keys {
+		if joined[key] != nil || owned[key] != nil || rejected[key] != nil {
+			continue
+		}
+		old, ok := g.m[key]
+		if ok {
+			if g.t[key] > now || !old.done { // 还未过期或者正在调用中，共享其结果
+				if g.tooManyWaiters(old) {
+					rejected[key] = ErrTooManyWaiters
+					continue
+				}
+				old.dups++
+				g.stats.hitOrCoalesced(old)
+				g.looked(true)
+				g.touch(old)
+				joined[key] = old
+				if old.done {
+					hits[key] = old.hitResult(g.slide(key, old, g.t[key], now), now)
+				} else {
+					old.join()
+					ready[key] = old.readyChan()
+				}
+				continue
+			}
+			if rc := old.refreshing; rc != nil { // 等待正在进行的刷新
+				if g.tooManyWaiters(rc) {
+					rejected[key] = ErrTooManyWaiters
+					continue
+				}
+				rc.dups++
+				g.stats.coalesced.Add(1)
+				g.looked(true)
+				joined[key] = rc
+				ready[key] = rc.readyChan()
+				continue
+			}
+			g.stats.evictions.Add(1)
+			evs = g.evict(evs, key, old, EvictExpired)
+		}
+		if g.refused != nil {
+			rejected[key] = g.refused
+			continue
+		}
+		if g.circuitOpen(key, now) {
+			rejected[key] = ErrCircuitOpen
+			continue
+		}
+		c := &call{params: params{validTime: validTime}, startedAt: now}
+		g.stats.misses.Add(1)
+		g.looked(false)
+		g.begin()
+		g.m[key] = c
+		g.pending++
+		g.t[key] = g.pendingValidTime(validTime)
+		g.unpublish(key)
+		g.track(key, old, c)
+		owned[key] = c
+		missing = append(missing, key)
+	}
+	evs = g.trim(evs, nil)
+	g.mu.Unlock()
+	g.notifyEvicted(evs)
+	for _, h := range hot {
+		g.hotKey(h.key, h.dups)
+	}
+
+	var fnErr error
+	results := make(map[string]Result, len(joined)+len(owned)+len(rejected))
+	for key, err := range rejected {
+		results[key] = Result{Err: err}
+	}
+	if len(missing) > 0 {
+		var took time.Duration
+		v, err, ok := g.limited(g.priorityOf(missing...), func() (interface{}, error) {
+			start := g.now()
+			defer func() {
+				took = time.Duration(g.now() - start)
+				g.stats.observe(took)
+			}()
+			return g.execute(strings.Join(missing, ","), func() (interface{}, error) {
+				return fn(missing)
+			})
+		})
+		vals, _ := v.(map[string]interface{})
+		fnErr = err
+		for key, c := range owned {
+			c.val, c.err, c.rejected, c.took = vals[key], err, !ok, took
+			if _, loaded := vals[key]; !loaded && err == nil && notLoaded != nil {
+				c.err = notLoaded
+			}
+			if ok && err != nil {
+				g.classify(c)
+				c.err = g.wrapped(key, err)
+			}
+		}
+
+		ds := make([]delivery, 0, len(owned))
+		g.mu.Lock()
+		for key, c := range owned {
+			d := g.complete(c, key, validTime)
+			results[key] = d.r
+			ds = append(ds, d)
+		}
+		g.mu.Unlock()
+		for _, d := range ds {
+			g.deliver(d)
+		}
+	}
+
+	for key, c := range joined {
+		if r, ok := hits[key]; ok { // 已经完成的结果
+			results[key] = g.copied(r)
+			continue
+		}
+		c, err := g.wait(nil, c, key, now, ready[key], 0)
+		if err != nil {
+			results[key] = Result{Err: err, Shared: true}
+			continue
+		}
+		results[key] = g.copied(c.waited())
+	}
+	return results, fnErr
+}
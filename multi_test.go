@@ -0,0 +1,125 @@
+package timesf
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDoMulti(t *testing.T) {
+	var g Group
+	var mu sync.Mutex
+	var batches []string
+	release := make(chan struct{})
+	fn := func(missing []string) (map[string]interface{}, error) {
+		mu.Lock()
+		batches = append(batches, strings.Join(missing, ","))
+		mu.Unlock()
+		if missing[0] == "a" {
+			<-release
+		}
+		vals := make(map[string]interface{})
+		for _, key := range missing {
+			vals[key] = key + "!"
+		}
+		return vals, nil
+	}
+
+	first := make(chan map[string]Result)
+	go func() {
+		first <- g.DoMulti([]string{"a", "b", "a"}, time.Hour, fn)
+	}()
+	waitFor(t, func() bool { return g.Stats().Misses == 2 })
+
+	// b正在调用中，只有c需要获取
+	second := make(chan map[string]Result)
+	go func() {
+		second <- g.DoMulti([]string{"b", "c"}, time.Hour, fn)
+	}()
+	waitFor(t, func() bool { return g.Stats().Coalesced == 1 })
+	close(release)
+
+	r1, r2 := <-first, <-second
+	if got, want := fmtResults(r1), "map[a:{a! <nil> false} b:{b! <nil> true}]"; got != want {
+		t.Errorf("first DoMulti = %v; want %v", got, want)
+	}
+	if got, want := fmtResults(r2), "map[b:{b! <nil> true} c:{c! <nil> false}]"; got != want {
+		t.Errorf("second DoMulti = %v; want %v", got, want)
+	}
+	sort.Strings(batches)
+	if got, want := fmt.Sprint(batches), "[a,b c]"; got != want {
+		t.Errorf("fn batches = %v; want %v", got, want)
+	}
+	if v, _, ok := g.Peek("c"); !ok || v != "c!" {
+		t.Errorf("Peek(c) = %v, %v; want c!, true", v, ok)
+	}
+}
+
+func TestDoMultiError(t *testing.T) {
+	var g Group
+	someErr := errors.New("some error")
+	g.Set("cached", "v", time.Hour)
+	r := g.DoMulti([]string{"cached", "x", "y"}, time.Hour, func(missing []string) (map[string]interface{}, error) {
+		return nil, someErr
+	})
+	if got, want := fmtResults(r), "map[cached:{v <nil> true} x:{<nil> some error false} y:{<nil> some error false}]"; got != want {
+		t.Errorf("DoMulti = %v; want %v", got, want)
+	}
+	// 默认不缓存错误
+	if n := g.Len(); n != 1 {
+		t.Errorf("Len = %d; want 1", n)
+	}
+}
+
+func TestDoMany(t *testing.T) {
+	var g Group
+	g.Set("cached", "v", time.Hour)
+	var batches []string
+	fn := func(missing []string) (map[string]interface{}, error) {
+		sort.Strings(missing)
+		batches = append(batches, strings.Join(missing, ","))
+		return map[string]interface{}{"x": "x!"}, nil
+	}
+	r, err := g.DoMany([]string{"cached", "x", "y"}, time.Hour, fn)
+	if err != nil {
+		t.Errorf("DoMany err = %v", err)
+	}
+	if got, want := fmtResults(r), "map[cached:{v <nil> true} x:{x! <nil> false} y:{<nil> "+ErrNotLoaded.Error()+" false}]"; got != want {
+		t.Errorf("DoMany = %v; want %v", got, want)
+	}
+	// 没有返回的key不会被缓存，下一次重新获取
+	g.DoMany([]string{"x", "y"}, time.Hour, fn)
+	if got, want := fmt.Sprint(batches), "[x,y y]"; got != want {
+		t.Errorf("fn batches = %v; want %v", got, want)
+	}
+
+	someErr := errors.New("some error")
+	r, err = g.DoMany([]string{"x", "z"}, time.Hour, func(missing []string) (map[string]interface{}, error) {
+		return nil, someErr
+	})
+	if err != someErr {
+		t.Errorf("DoMany err = %v; want %v", err, someErr)
+	}
+	if got, want := fmtResults(r), "map[x:{x! <nil> true} z:{<nil> some error false}]"; got != want {
+		t.Errorf("DoMany with error = %v; want %v", got, want)
+	}
+}
+
+// fmtResults 按key的顺序格式化DoMulti的结果，不包括ComputedAt。
+func fmtResults(rs map[string]Result) string {
+	keys := make([]string, 0, len(rs))
+	for key := range rs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		r := rs[key]
+		parts[i] = fmt.Sprintf("%s:{%v %v %v}", key, r.Val, r.Err, r.Shared)
+	}
+	return "map[" + strings.Join(parts, " ") + "]"
+}
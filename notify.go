@@ -0,0 +1,69 @@
+package timesf
+
+// ExpiryEvent 是Notify的通道收到的一个结果离开Group的事件，字段的含义和WithOnEvict的
+// 回调参数相同。
+type ExpiryEvent struct {
+	Key    string
+	Val    interface{}
+	Reason EvictReason
+}
+
+// Notify 订阅结果离开Group的事件，包括过期、遗忘、替换和超过容量被移除，见EvictReason。
+// 返回的通道最多缓存buffer个事件，发送不会阻塞：订阅者跟不上时之后的事件被丢弃，计入
+// Stats的Dropped。可以有多个订阅者，每一个都收到所有事件。StopNotify取消订阅，Close
+// 取消所有的订阅，之后通道被关闭。
+func (g *Group) Notify(buffer int) <-chan ExpiryEvent {
+	if buffer < 0 {
+		buffer = 0
+	}
+	ch := make(chan ExpiryEvent, buffer)
+	g.mu.Lock()
+	g.watchMu.Lock()
+	g.watchers = append(g.watchers, ch)
+	g.watchMu.Unlock()
+	g.mu.Unlock()
+	return ch
+}
+
+// StopNotify 取消Notify返回的通道ch的订阅并关闭通道，ch没有在订阅时返回false。
+func (g *Group) StopNotify(ch <-chan ExpiryEvent) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.watchMu.Lock()
+	defer g.watchMu.Unlock()
+	for i, w := range g.watchers {
+		if w == ch {
+			// 复制而不是原地删除，notifyEvicted可能正在遍历旧的切片。
+			watchers := make([]chan ExpiryEvent, 0, len(g.watchers)-1)
+			g.watchers = append(append(watchers, g.watchers[:i]...), g.watchers[i+1:]...)
+			close(w)
+			return true
+		}
+	}
+	return false
+}
+
+// stopNotifyAll 取消所有的订阅并关闭其通道，见Close。
+func (g *Group) stopNotifyAll() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.watchMu.Lock()
+	defer g.watchMu.Unlock()
+	for _, w := range g.watchers {
+		close(w)
+	}
+	g.watchers = nil
+}
+
+// broadcast 将ev发送给所有的订阅者，通道已满时丢弃并计数，调用者不能持有锁。
+func (g *Group) broadcast(ev eviction) {
+	g.watchMu.RLock()
+	defer g.watchMu.RUnlock()
+	for _, w := range g.watchers {
+		select {
+		case w <- ExpiryEvent{ev.key, ev.val, ev.reason}:
+		default:
+			g.stats.dropped.Add(1)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package timesf
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNotify(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithJanitor(time.Millisecond), WithClock(clock))
+	a, b := g.Notify(4), g.Notify(1)
+	fn := func() (interface{}, error) {
+		return "v", nil
+	}
+
+	g.Do("short", time.Second, fn)
+	clock.Advance(2 * time.Second)
+	select {
+	case ev := <-a:
+		if ev.Key != "short" || ev.Val != "v" || ev.Reason != EvictExpired {
+			t.Errorf("event = %+v; want short=v expired", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no event for the expired key")
+	}
+	if ev := <-b; ev.Key != "short" {
+		t.Errorf("second subscriber event = %+v; want short", ev)
+	}
+
+	// 订阅者跟不上时丢弃事件，不会阻塞
+	g.Set("x", 1, time.Hour)
+	g.Forget("x")
+	g.Set("y", 2, time.Hour)
+	g.Forget("y")
+	if ev := <-a; ev.Key != "x" || ev.Reason != EvictForgotten {
+		t.Errorf("event = %+v; want x forgotten", ev)
+	}
+	if ev := <-a; ev.Key != "y" {
+		t.Errorf("event = %+v; want y", ev)
+	}
+	<-b
+	if s := g.Stats(); s.Dropped != 1 {
+		t.Errorf("Dropped = %d; want 1", s.Dropped)
+	}
+
+	if !g.StopNotify(b) || g.StopNotify(b) {
+		t.Error("StopNotify = false, or true twice; want true once")
+	}
+	if _, ok := <-b; ok {
+		t.Error("channel still open after StopNotify")
+	}
+	g.Close()
+	if _, ok := <-a; ok {
+		t.Error("channel still open after Close")
+	}
+	g.Forget("short")
+}
@@ -0,0 +1,18 @@
+package timesf
+
+import "time"
+
+// Observer 观测每一次由执行方法的调用者完成的调用，可以用来导出方法耗时的直方图和错误率
+// 等指标，而不需要依赖任何指标库。
+type Observer interface {
+	// ObserveCompute 在方法执行完成并且结果交给等待者之后被调用。duration是方法执行的
+	// 系统时间，err是方法返回的错误，shared标识结果是否被其他调用者共享。
+	ObserveCompute(key string, duration time.Duration, err error, shared bool)
+}
+
+// WithObserver 设置Group的观测者，见Observer。后台刷新和DoMulti的批量调用不会被观测。
+func WithObserver(o Observer) Option {
+	return func(g *Group) {
+		g.observer = o
+	}
+}
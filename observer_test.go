@@ -0,0 +1,56 @@
+package timesf
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// observerRecorder 记录观测到的调用，供测试检查。
+type observerRecorder struct {
+	mu  sync.Mutex
+	obs []string
+	dur []time.Duration
+}
+
+func (r *observerRecorder) ObserveCompute(key string, duration time.Duration, err error, shared bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.obs = append(r.obs, fmt.Sprintf("%s:%v:%v", key, err, shared))
+	r.dur = append(r.dur, duration)
+}
+
+func TestWithObserver(t *testing.T) {
+	var rec observerRecorder
+	g := New(WithObserver(&rec))
+	someErr := errors.New("some error")
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	ch := g.DoChan("slow", time.Hour, func() (interface{}, error) {
+		close(started)
+		<-release
+		time.Sleep(10 * time.Millisecond)
+		return nil, nil
+	})
+	<-started
+	dup := g.DoChan("slow", time.Hour, nil)
+	close(release)
+	<-ch
+	<-dup
+	g.Do("slow", time.Hour, nil)
+	g.Do("err", time.Hour, func() (interface{}, error) {
+		return nil, someErr
+	})
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if got, want := fmt.Sprint(rec.obs), "[slow:<nil>:true err:some error:false]"; got != want {
+		t.Errorf("observations = %v; want %v", got, want)
+	}
+	if d := rec.dur[0]; d < 10*time.Millisecond || d > time.Second {
+		t.Errorf("slow duration = %v; want between 10ms and 1s", d)
+	}
+}
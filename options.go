@@ -0,0 +1,121 @@
+package timesf
+
+import "time"
+
+// Option 用来配置New创建的Group。
+type Option func(*Group)
+
+// New 根据选项创建一个Group。零值的Group同样可以直接使用，其行为和不传任何选项
+// 的New相同。选项按顺序应用，后面的选项覆盖前面的同一项配置；选项的取值无效或者
+// 彼此矛盾时New会panic，并说明是哪一个选项，这样的错误只能在编写代码时修正。
+func New(opts ...Option) *Group {
+	g := &Group{}
+	for _, opt := range opts {
+		opt(g)
+	}
+	if err := g.validate(); err != "" {
+		panic("timesf: " + err)
+	}
+	if g.janitor != nil {
+		go g.runJanitor(g.janitor)
+	}
+	return g
+}
+
+// validate 检查选项的配置，返回第一个问题的描述，没有问题时返回空字符串。
+func (g *Group) validate() string {
+	switch {
+	case g.capacity < 0:
+		return "WithCapacity requires n >= 0"
+	case g.maxWaiters < 0:
+		return "WithMaxWaiters requires n >= 0"
+	case g.janitor != nil && g.janitor.interval <= 0:
+		return "WithJanitor requires a positive interval"
+	case g.maxCost > 0 && g.costFn == nil:
+		return "WithMaxCost requires a cost function"
+	case g.errorTTL > 0 && !g.cacheErrors:
+		return "WithErrorTTL conflicts with WithErrorCaching(false)"
+	}
+	return ""
+}
+
+// WithDefaultTTL 设置DoDefault方法使用的默认有效时长，含义和Do方法的validTime相同。
+// 显式传入有效时长的方法不受影响。
+func WithDefaultTTL(d time.Duration) Option {
+	return func(g *Group) {
+		g.defaultTTL = d
+	}
+}
+
+// WithRefreshAhead 开启提前刷新：当命中一个已完成的结果，并且其剩余的有效期小于
+// factor*validTime时，在后台执行一次保存的方法，成功后替换结果和有效期。比如0.2
+// 表示在有效期过去80%之后进行刷新。同一个key同时只会有一个刷新，刷新失败时保留
+// 仍然有效的结果。只有被访问的key才会被刷新。
+func WithRefreshAhead(factor float64) Option {
+	return func(g *Group) {
+		g.refreshAhead = factor
+	}
+}
+
+// WithRefreshBefore 像WithRefreshAhead，但是使用固定的时长：当命中的结果剩余的有效期
+// 小于d时，在后台进行一次刷新。两者同时设置时满足任意一个即进行刷新。
+func WithRefreshBefore(d time.Duration) Option {
+	return func(g *Group) {
+		g.refreshBefore = d
+	}
+}
+
+// WithErrorCaching 设置是否缓存返回错误的结果，默认不缓存：返回错误的调用在完成时
+// 立即被删除，下一个调用者会重新执行方法。为true时错误像结果一样被缓存。
+func WithErrorCaching(enabled bool) Option {
+	return func(g *Group) {
+		g.cacheErrors = enabled
+	}
+}
+
+// WithNilCaching 设置是否缓存值为nil并且没有错误的结果，默认缓存。为false时这样的结果
+// 像没有开启错误缓存时的错误一样在完成时被删除，下一个调用者重新执行方法，适用于用nil
+// 表示不存在、之后可能出现的加载方法；后台刷新得到nil时保留原来的结果。只判断值本身
+// 是否为nil，包含nil指针的接口值不算nil。
+func WithNilCaching(enabled bool) Option {
+	return func(g *Group) {
+		g.skipNil = !enabled
+	}
+}
+
+// WithErrorTTL 开启错误缓存，并设置返回错误的结果的有效时长，与成功结果的有效时长
+// 无关，用来进行短暂的负缓存。
+func WithErrorTTL(d time.Duration) Option {
+	return func(g *Group) {
+		g.cacheErrors = d > 0
+		g.errorTTL = d
+	}
+}
+
+// WithJitter 为结果的有效时长加入随机抖动：每个结果实际的有效时长在validTime的
+// ±fraction范围内均匀分布，比如0.1表示±10%，避免同时写入的大量key同时过期。永不
+// 过期的结果不受影响。默认为0，不进行抖动。
+func WithJitter(fraction float64) Option {
+	return func(g *Group) {
+		g.jitter = fraction
+	}
+}
+
+// WithJitterSource 设置WithJitter使用的随机数来源，random返回[0, 1)之间的随机数，
+// 比如rand.New(rand.NewSource(seed)).Float64，可以让抖动的结果固定下来。random总是在
+// 持有锁时被调用，不需要是并发安全的。默认使用math/rand。
+func WithJitterSource(random func() float64) Option {
+	return func(g *Group) {
+		g.rand = random
+	}
+}
+
+// WithStrictTTL 设置有效时长的严格模式。默认模式下validTime为0表示永不过期，负数
+// 表示结果立即过期。严格模式下0和除NoExpiration之外的负数都表示不缓存结果：调用
+// 执行期间重复的调用者仍然共享其结果，调用完成后立即删除。两种模式下NoExpiration
+// 都表示永不过期。
+func WithStrictTTL(enabled bool) Option {
+	return func(g *Group) {
+		g.strictTTL = enabled
+	}
+}
@@ -0,0 +1,447 @@
+package timesf
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNewZeroOptions(t *testing.T) {
+	g := New()
+	v, err, shared := g.Do("key", time.Second, func() (interface{}, error) {
+		return "bar", nil
+	})
+	if v != "bar" || err != nil || shared {
+		t.Errorf("Do = %v, %v, %v; want bar, nil, false", v, err, shared)
+	}
+}
+
+func TestNewComposedOptions(t *testing.T) {
+	clock := newFakeClock()
+	var rec observerRecorder
+	g := New(
+		WithClock(clock),
+		WithObserver(&rec),
+		WithCapacity(2),
+		WithErrorCaching(true),
+		WithJitter(0.5),
+		WithJitterSource(func() float64 { return 1 }),
+		WithJanitor(time.Hour),
+	)
+	defer g.Close()
+	boom := errors.New("boom")
+	g.Do("a", time.Minute, func() (interface{}, error) { return nil, boom })
+	g.Do("b", time.Minute, func() (interface{}, error) { return 2, nil })
+	g.Do("c", time.Minute, func() (interface{}, error) { return 3, nil })
+	if g.Has("a") || !g.Has("b") || !g.Has("c") {
+		t.Errorf("keys = %v; want the least recently used a evicted", g.Keys())
+	}
+	if ttl, _ := g.TTL("c"); ttl != 90*time.Second {
+		t.Errorf("TTL with jitter = %v; want 1m30s", ttl)
+	}
+	if _, err, _ := g.Do("b", time.Minute, nil); err != nil {
+		t.Errorf("cached b err = %v", err)
+	}
+	if want := "[a:boom:false b:<nil>:false c:<nil>:false]"; fmt.Sprint(rec.obs) != want {
+		t.Errorf("observed = %v; want %v", rec.obs, want)
+	}
+}
+
+func TestNewInvalidOptions(t *testing.T) {
+	tests := []struct {
+		opts []Option
+		want string
+	}{
+		{[]Option{WithCapacity(-1)}, "timesf: WithCapacity requires n >= 0"},
+		{[]Option{WithMaxWaiters(-1)}, "timesf: WithMaxWaiters requires n >= 0"},
+		{[]Option{WithJanitor(0)}, "timesf: WithJanitor requires a positive interval"},
+		{[]Option{WithMaxCost(10, nil)}, "timesf: WithMaxCost requires a cost function"},
+		{[]Option{WithErrorTTL(time.Second), WithErrorCaching(false)}, "timesf: WithErrorTTL conflicts with WithErrorCaching(false)"},
+	}
+	for _, tt := range tests {
+		func() {
+			defer func() {
+				if r := recover(); r != tt.want {
+					t.Errorf("New panicked with %v; want %q", r, tt.want)
+				}
+			}()
+			New(tt.opts...)
+		}()
+	}
+
+	// 后面的选项覆盖前面的同一项配置，不算矛盾
+	New(WithErrorCaching(false), WithErrorTTL(time.Second))
+}
+
+func TestRefreshAhead(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithRefreshAhead(0.5), WithClock(clock))
+	var calls int32
+	fn := func() (interface{}, error) {
+		return atomic.AddInt32(&calls, 1), nil
+	}
+
+	g.Do("key", 100*time.Millisecond, fn)
+	// 有效期还剩一半以上时不刷新。
+	if v, _, _ := g.Do("key", 100*time.Millisecond, fn); v != int32(1) {
+		t.Errorf("Do = %v; want 1", v)
+	}
+	clock.Advance(70 * time.Millisecond)
+
+	// 进入刷新窗口后仍然返回有效的旧值，同时后台进行一次刷新。
+	if v, _, _ := g.Do("key", 100*time.Millisecond, fn); v != int32(1) {
+		t.Errorf("Do inside refresh window = %v; want 1", v)
+	}
+	waitFor(t, func() bool {
+		v, _, _ := g.Peek("key")
+		return v == int32(2)
+	})
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Errorf("number of calls = %d; want 2", got)
+	}
+
+	// 刷新后的有效期重新计算，旧的过期时间已经过去也仍然命中。
+	clock.Advance(40 * time.Millisecond)
+	if v, _, _ := g.Do("key", 100*time.Millisecond, fn); v != int32(2) {
+		t.Errorf("Do after old expiry = %v; want 2", v)
+	}
+}
+
+func TestRefreshBefore(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithRefreshBefore(10*time.Second), WithClock(clock))
+	var calls int32
+	fn := func() (interface{}, error) {
+		return atomic.AddInt32(&calls, 1), nil
+	}
+
+	g.Do("key", time.Minute, fn)
+	clock.Advance(45 * time.Second)
+	if v, _, _ := g.Do("key", time.Minute, fn); v != int32(1) {
+		t.Errorf("Do outside refresh window = %v; want 1", v)
+	}
+	clock.Advance(10 * time.Second)
+
+	// 窗口内的多次命中只触发一次刷新
+	for i := 0; i < 3; i++ {
+		if v, _, _ := g.Do("key", time.Minute, fn); v != int32(1) {
+			t.Errorf("Do inside refresh window = %v; want 1", v)
+		}
+	}
+	waitFor(t, func() bool {
+		v, _, _ := g.Peek("key")
+		return v == int32(2)
+	})
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Errorf("number of calls = %d; want 2", got)
+	}
+	if ttl, _ := g.TTL("key"); ttl != time.Minute {
+		t.Errorf("TTL after refresh = %v; want 1m", ttl)
+	}
+}
+
+func TestRefreshAheadFailureKeepsEntry(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithRefreshAhead(0.5), WithClock(clock))
+	someErr := errors.New("some error")
+	var calls int32
+	fn := func() (interface{}, error) {
+		if atomic.AddInt32(&calls, 1) > 1 {
+			return nil, someErr
+		}
+		return "good", nil
+	}
+
+	g.Do("key", 100*time.Millisecond, fn)
+	clock.Advance(60 * time.Millisecond)
+	g.Do("key", 100*time.Millisecond, fn)
+	waitFor(t, func() bool { return g.Stats().Errors == 1 })
+	if v, err, _ := g.Do("key", 100*time.Millisecond, fn); v != "good" || err != nil {
+		t.Errorf("Do after failed refresh = %v, %v; want good, nil", v, err)
+	}
+}
+
+func TestErrorsNotCachedByDefault(t *testing.T) {
+	var g Group
+	someErr := errors.New("some error")
+	var calls int32
+	fn := func() (interface{}, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			return nil, someErr
+		}
+		return "bar", nil
+	}
+
+	if _, err, _ := g.Do("key", time.Second, fn); err != someErr {
+		t.Errorf("Do error = %v; want someErr", err)
+	}
+	if v, err, _ := g.Do("key", time.Second, fn); v != "bar" || err != nil {
+		t.Errorf("Do after error = %v, %v; want bar, nil", v, err)
+	}
+	if v, _, _ := g.Do("key", time.Second, fn); v != "bar" {
+		t.Errorf("Do on cached success = %v; want bar", v)
+	}
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Errorf("number of calls = %d; want 2", got)
+	}
+}
+
+func TestWithErrorTTL(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithErrorTTL(5*time.Millisecond), WithClock(clock))
+	someErr := errors.New("some error")
+	var calls int32
+	fn := func() (interface{}, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			return nil, someErr
+		}
+		return "bar", nil
+	}
+
+	if _, err, _ := g.Do("key", time.Second, fn); err != someErr {
+		t.Errorf("Do error = %v; want someErr", err)
+	}
+	if _, err, _ := g.Do("key", time.Second, fn); err != someErr {
+		t.Errorf("Do inside error ttl = %v; want someErr", err)
+	}
+	clock.Advance(10 * time.Millisecond)
+	if v, err, _ := g.Do("key", time.Second, fn); v != "bar" || err != nil {
+		t.Errorf("Do after error ttl = %v, %v; want bar, nil", v, err)
+	}
+	clock.Advance(10 * time.Millisecond)
+	if v, _, _ := g.Do("key", time.Second, fn); v != "bar" {
+		t.Errorf("Do on cached success = %v; want bar", v)
+	}
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Errorf("number of calls = %d; want 2", got)
+	}
+}
+
+func TestWithErrorCaching(t *testing.T) {
+	g := New(WithErrorCaching(true))
+	someErr := errors.New("some error")
+	var calls int32
+	fn := func() (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, someErr
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err, _ := g.Do("key", time.Second, fn); err != someErr {
+			t.Errorf("Do error = %v; want someErr", err)
+		}
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("number of calls = %d; want 1", got)
+	}
+}
+
+func TestWithNilCaching(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		g := New(WithNilCaching(enabled))
+		var calls int32
+		fn := func() (interface{}, error) {
+			atomic.AddInt32(&calls, 1)
+			return nil, nil
+		}
+		for i := 0; i < 3; i++ {
+			if v, err, _ := g.Do("key", time.Hour, fn); v != nil || err != nil {
+				t.Errorf("Do = %v, %v; want nil, nil", v, err)
+			}
+		}
+		want := int32(1)
+		if !enabled {
+			want = 3
+		}
+		if got := atomic.LoadInt32(&calls); got != want || g.Has("key") != enabled {
+			t.Errorf("WithNilCaching(%v): calls = %d, cached %v; want %d, %v", enabled, got, g.Has("key"), want, enabled)
+		}
+		g.Do("found", time.Hour, func() (interface{}, error) {
+			return "bar", nil
+		})
+		if !g.Has("found") {
+			t.Errorf("WithNilCaching(%v) did not cache a non-nil value", enabled)
+		}
+	}
+}
+
+func TestWithDefaultTTL(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithDefaultTTL(time.Minute), WithClock(clock))
+	fn := func() (interface{}, error) {
+		return nil, nil
+	}
+
+	g.DoDefault("default", fn)
+	if ttl, ok := g.TTL("default"); !ok || ttl != time.Minute {
+		t.Errorf("TTL after DoDefault = %v, %v; want 1m, true", ttl, ok)
+	}
+	g.Do("explicit", time.Second, fn)
+	if ttl, ok := g.TTL("explicit"); !ok || ttl != time.Second {
+		t.Errorf("TTL after explicit Do = %v, %v; want 1s, true", ttl, ok)
+	}
+
+	clock.Advance(time.Second)
+	var calls int
+	g.DoDefault("default", func() (interface{}, error) {
+		calls++
+		return nil, nil
+	})
+	if calls != 0 {
+		t.Errorf("DoDefault recomputed before default TTL")
+	}
+
+	<-g.DoChanDefault("chan", fn)
+	if ttl, ok := g.TTL("chan"); !ok || ttl != time.Minute {
+		t.Errorf("TTL after DoChanDefault = %v, %v; want 1m, true", ttl, ok)
+	}
+}
+
+func TestWithJitter(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithClock(clock), WithJitter(0.1))
+	fn := func() (interface{}, error) {
+		return nil, nil
+	}
+
+	ttls := make(map[time.Duration]bool)
+	for i := 0; i < 100; i++ {
+		key := strconv.Itoa(i)
+		g.Do(key, time.Minute, fn)
+		ttl, _ := g.TTL(key)
+		if ttl < 54*time.Second || ttl > 66*time.Second {
+			t.Errorf("TTL(%q) = %v; want within 10%% of 1m", key, ttl)
+		}
+		ttls[ttl] = true
+	}
+	if len(ttls) < 50 {
+		t.Errorf("got %d distinct TTLs for 100 keys; want them spread", len(ttls))
+	}
+
+	// 注入随机数，检查抖动的边界
+	for _, tt := range []struct {
+		r    float64
+		want time.Duration
+	}{
+		{0, 54 * time.Second},
+		{0.5, time.Minute},
+		{0.75, 63 * time.Second},
+	} {
+		g.rand = func() float64 { return tt.r }
+		g.Do("pinned", time.Minute, fn)
+		if ttl, _ := g.TTL("pinned"); ttl != tt.want {
+			t.Errorf("TTL with rand %v = %v; want %v", tt.r, ttl, tt.want)
+		}
+		g.Forget("pinned")
+	}
+	g.Do("forever", 0, fn)
+	if ttl, _ := g.TTL("forever"); ttl != math.MaxInt64 {
+		t.Errorf("TTL of a never-expiring result = %v; want max duration", ttl)
+	}
+}
+
+func TestWithJitterSource(t *testing.T) {
+	ttls := func() []time.Duration {
+		clock := newFakeClock()
+		g := New(WithClock(clock), WithJitter(0.5), WithJitterSource(rand.New(rand.NewSource(1)).Float64))
+		var ttls []time.Duration
+		for i := 0; i < 10; i++ {
+			key := strconv.Itoa(i)
+			g.Do(key, time.Minute, func() (interface{}, error) {
+				return nil, nil
+			})
+			ttl, _ := g.TTL(key)
+			ttls = append(ttls, ttl)
+		}
+		return ttls
+	}
+	if a, b := fmt.Sprint(ttls()), fmt.Sprint(ttls()); a != b {
+		t.Errorf("TTLs with the same seed differ: %v and %v", a, b)
+	}
+
+	// 抖动之后的有效时长至少为1纳秒
+	clock := newFakeClock()
+	g := New(WithClock(clock), WithJitter(2), WithJitterSource(func() float64 { return 0 }))
+	g.Do("key", time.Minute, func() (interface{}, error) {
+		return nil, nil
+	})
+	if ttl, ok := g.TTL("key"); !ok || ttl != 1 {
+		t.Errorf("TTL with full negative jitter = %v, %v; want 1ns, true", ttl, ok)
+	}
+}
+
+func TestNoJitter(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithClock(clock))
+	g.rand = func() float64 { return 0 }
+	g.Do("key", time.Minute, func() (interface{}, error) {
+		return nil, nil
+	})
+	if ttl, _ := g.TTL("key"); ttl != time.Minute {
+		t.Errorf("TTL without jitter = %v; want 1m", ttl)
+	}
+}
+
+func TestGetValidTime(t *testing.T) {
+	clock := newFakeClock()
+	now := clock.Now().UnixNano()
+	tests := []struct {
+		strict    bool
+		validTime time.Duration
+		want      int64
+	}{
+		{false, time.Second, now + int64(time.Second)},
+		{false, 0, math.MaxInt64},
+		{false, NoExpiration, math.MaxInt64},
+		{false, -5 * time.Second, now - int64(5*time.Second)},
+		{true, time.Second, now + int64(time.Second)},
+		{true, 0, now},
+		{true, NoExpiration, math.MaxInt64},
+		{true, -5 * time.Second, now},
+	}
+	for _, tt := range tests {
+		g := New(WithClock(clock), WithStrictTTL(tt.strict))
+		if got := g.getValidTime(tt.validTime); got != tt.want {
+			t.Errorf("strict=%v getValidTime(%v) = %d; want %d", tt.strict, tt.validTime, got, tt.want)
+		}
+	}
+}
+
+func TestWithStrictTTL(t *testing.T) {
+	g := New(WithStrictTTL(true))
+	var calls int32
+	release := make(chan struct{})
+	fn := func() (interface{}, error) {
+		<-release
+		return atomic.AddInt32(&calls, 1), nil
+	}
+
+	// 执行期间重复的调用者仍然共享结果
+	ch1 := g.DoChan("key", 0, fn)
+	ch2 := g.DoChan("key", 0, fn)
+	close(release)
+	if r1, r2 := <-ch1, <-ch2; r1.Val != int32(1) || r2.Val != int32(1) || !r2.Shared {
+		t.Errorf("DoChan results = %+v, %+v; want a shared value 1", r1, r2)
+	}
+	// 完成之后不缓存
+	waitFor(t, func() bool { return g.Len() == 0 })
+	for _, validTime := range []time.Duration{0, -5 * time.Second} {
+		g.Do("key", validTime, fn)
+		if g.Has("key") {
+			t.Errorf("result with validTime %v was cached", validTime)
+		}
+	}
+	if n := atomic.LoadInt32(&calls); n != 3 {
+		t.Errorf("calls = %d; want 3", n)
+	}
+
+	g.Do("forever", NoExpiration, fn)
+	if ttl, ok := g.TTL("forever"); !ok || ttl != math.MaxInt64 {
+		t.Errorf("TTL with NoExpiration = %v, %v; want max duration, true", ttl, ok)
+	}
+}
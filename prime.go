@@ -0,0 +1,85 @@
+package timesf
+
+import "time"
+
+// primeChunk 是PrimeMany每一次持有锁时最多写入的结果数量，写入大量结果时其他调用者
+// 不会被阻塞太久。
+const primeChunk = 1024
+
+// Primed 是PrimeManyWithTTLs写入的一个结果和其有效时长。
+type Primed struct {
+	Val       interface{}
+	ValidTime time.Duration
+}
+
+// PrimeMany 像对entries中的每一个key调用SetValue一样写入已完成的结果，都有效validTime
+// 时长，比如在启动时预热缓存。已经有正在进行的调用的key被跳过，已完成的结果被替换，
+// 并以EvictReplaced通知移除回调。每持有一次锁写入一批结果，而不是每一个key都加锁一次。
+// 返回实际写入的结果数量。
+func (g *Group) PrimeMany(entries map[string]interface{}, validTime time.Duration) int {
+	primed := make(map[string]Primed, len(entries))
+	for key, val := range entries {
+		primed[key] = Primed{Val: val, ValidTime: validTime}
+	}
+	return g.PrimeManyWithTTLs(primed)
+}
+
+// PrimeManyWithTTLs 像PrimeMany方法，但是每一个结果使用自己的有效时长。
+func (g *Group) PrimeManyWithTTLs(entries map[string]Primed) int {
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	n := 0
+	for len(keys) > 0 {
+		chunk := keys
+		if len(chunk) > primeChunk {
+			chunk = chunk[:primeChunk]
+		}
+		keys = keys[len(chunk):]
+		n += g.prime(chunk, entries)
+	}
+	return n
+}
+
+// prime 在一次持有锁时写入entries中keys的结果，返回写入的数量。
+func (g *Group) prime(keys []string, entries map[string]Primed) int {
+	var evs []eviction
+	n := 0
+	g.mu.Lock()
+	if g.m == nil {
+		g.m = make(map[string]*call)
+		g.t = make(map[string]int64)
+	}
+	now := g.now()
+	for _, k := range keys {
+		e := entries[k]
+		var ok bool
+		if evs, ok = g.install(evs, g.normalize(k), e.Val, e.ValidTime, now); ok {
+			n++
+		}
+	}
+	g.mu.Unlock()
+	g.notifyEvicted(evs)
+	return n
+}
+
+// install 像SetValue一样将val写入为key已完成的结果，key已经有正在进行的调用时跳过。
+// 返回是否写入。key已经按照WithKeyFunc进行了转换，调用者需要持有锁，并在释放锁之后
+// 调用notifyEvicted。
+func (g *Group) install(evs []eviction, key string, val interface{}, validTime time.Duration, now int64) ([]eviction, bool) {
+	old, ok := g.m[key]
+	if ok && !old.done {
+		return evs, false
+	}
+	if ok {
+		evs = g.evict(evs, key, old, EvictReplaced)
+	}
+	c := &call{val: val, done: true, startedAt: now, doneAt: now, params: params{validTime: validTime}}
+	g.m[key] = c
+	g.t[key] = g.capped(c, g.getValidTime(validTime))
+	g.unpublish(key)
+	g.track(key, old, c)
+	g.charge(c)
+	return g.trim(evs, c), true
+}
@@ -0,0 +1,48 @@
+package timesf
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestPrimeMany(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithClock(clock))
+	g.Set("done", "old", time.Hour)
+	release := make(chan struct{})
+	defer close(release)
+	g.DoChan("pending", time.Hour, func() (interface{}, error) {
+		<-release
+		return "computed", nil
+	})
+
+	entries := map[string]interface{}{"done": "new", "pending": "primed"}
+	for i := 0; i < 3*primeChunk; i++ {
+		entries[strconv.Itoa(i)] = i
+	}
+	if n := g.PrimeMany(entries, time.Second); n != len(entries)-1 {
+		t.Errorf("PrimeMany = %d; want %d", n, len(entries)-1)
+	}
+	if v, _, _ := g.Peek("done"); v != "new" {
+		t.Errorf("Peek(done) = %v; want new", v)
+	}
+	if _, _, ok := g.Peek("pending"); ok {
+		t.Error("PrimeMany replaced an in-flight call")
+	}
+	if v, _, _ := g.Do("7", time.Hour, nil); v != 7 {
+		t.Errorf("Do(7) = %v; want the primed 7", v)
+	}
+
+	n := g.PrimeManyWithTTLs(map[string]Primed{
+		"short": {Val: 1, ValidTime: time.Second},
+		"long":  {Val: 2, ValidTime: time.Hour},
+	})
+	if n != 2 {
+		t.Errorf("PrimeManyWithTTLs = %d; want 2", n)
+	}
+	clock.Advance(time.Minute)
+	if g.Has("short") || !g.Has("long") {
+		t.Errorf("after a minute Has(short) = %v, Has(long) = %v; want false, true", g.Has("short"), g.Has("long"))
+	}
+}
@@ -0,0 +1,103 @@
+package timesf
+
+import (
+	"container/heap"
+	"sync"
+)
+
+// WithComputePriority 为WithMaxConcurrency的排队设置优先级，priority返回key的方法的
+// 优先级。位置被占满时，空闲出来的位置先交给优先级最高的排队的方法，相同优先级的按照
+// 排队的顺序，这样交互请求使用的廉价key在大量昂贵的key排队时仍然能及时执行。DoMulti
+// 和DoMany的批量调用使用缺失的key中最高的优先级。已经在执行的方法不会被打断，快速失败
+// 时不排队，优先级不起作用。没有设置WithMaxConcurrency时不起作用。
+func WithComputePriority(priority func(key string) int) Option {
+	return func(g *Group) {
+		g.priority = priority
+	}
+}
+
+// admission 是设置了优先级时等待WithMaxConcurrency位置的队列。
+type admission struct {
+	mu      sync.Mutex
+	waiting admitters
+	seq     uint64
+}
+
+// admitter 是一个排队的方法，拿到位置时ready被关闭。
+type admitter struct {
+	priority int
+	seq      uint64
+	ready    chan struct{}
+}
+
+// admitters 按照优先级从高到低、排队顺序从早到晚实现heap.Interface。
+type admitters []*admitter
+
+func (a admitters) Len() int { return len(a) }
+
+func (a admitters) Less(i, j int) bool {
+	if a[i].priority != a[j].priority {
+		return a[i].priority > a[j].priority
+	}
+	return a[i].seq < a[j].seq
+}
+
+func (a admitters) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+
+func (a *admitters) Push(x interface{}) { *a = append(*a, x.(*admitter)) }
+
+func (a *admitters) Pop() interface{} {
+	old := *a
+	w := old[len(old)-1]
+	old[len(old)-1] = nil
+	*a = old[:len(old)-1]
+	return w
+}
+
+// priorityOf 返回keys的方法中最高的优先级，没有设置WithComputePriority时返回0。
+func (g *Group) priorityOf(keys ...string) int {
+	if g.priority == nil {
+		return 0
+	}
+	p := g.priority(keys[0])
+	for _, key := range keys[1:] {
+		if q := g.priority(key); q > p {
+			p = q
+		}
+	}
+	return p
+}
+
+// admit 按照优先级等待WithMaxConcurrency的位置。没有排队的方法时直接尝试拿到位置，
+// 否则和其他方法一起排队，由yield交接位置。
+func (g *Group) admit(priority int) {
+	q := &g.admission
+	q.mu.Lock()
+	if q.waiting.Len() == 0 {
+		select {
+		case g.sem <- struct{}{}:
+			q.mu.Unlock()
+			return
+		default:
+		}
+	}
+	q.seq++
+	w := &admitter{priority: priority, seq: q.seq, ready: make(chan struct{})}
+	heap.Push(&q.waiting, w)
+	q.mu.Unlock()
+	g.stats.queued.Add(1)
+	<-w.ready
+	g.stats.queued.Add(-1)
+}
+
+// yield 释放admit拿到的位置，有排队的方法时直接交给优先级最高的一个。
+func (g *Group) yield() {
+	q := &g.admission
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.waiting.Len() == 0 {
+		<-g.sem
+		return
+	}
+	close(heap.Pop(&q.waiting).(*admitter).ready)
+}
@@ -0,0 +1,73 @@
+package timesf
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestComputePriority(t *testing.T) {
+	g := New(WithMaxConcurrency(1, false), WithComputePriority(func(key string) int {
+		if strings.HasPrefix(key, "fast") {
+			return 1
+		}
+		return 0
+	}))
+	var mu sync.Mutex
+	var order []string
+	record := func(key string) func() (interface{}, error) {
+		return func() (interface{}, error) {
+			mu.Lock()
+			order = append(order, key)
+			mu.Unlock()
+			return key, nil
+		}
+	}
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	slow := g.DoChan("slow", time.Hour, func() (interface{}, error) {
+		close(started)
+		<-release
+		return "slow", nil
+	})
+	<-started
+
+	var chans []<-chan Result
+	for i := 0; i < 3; i++ {
+		key := "backlog" + strconv.Itoa(i)
+		chans = append(chans, g.DoChan(key, time.Hour, record(key)))
+	}
+	waitFor(t, func() bool { return g.Stats().Queued == 3 })
+	for i := 0; i < 3; i++ {
+		key := "fast" + strconv.Itoa(i)
+		chans = append(chans, g.DoChan(key, time.Hour, record(key)))
+	}
+	waitFor(t, func() bool { return g.Stats().Queued == 6 })
+
+	close(release)
+	<-slow
+	for _, ch := range chans {
+		<-ch
+	}
+	// 同一优先级按照排队的顺序执行，DoChan排队的顺序取决于调度，只检查优先级
+	if len(order) != 6 {
+		t.Fatalf("executed %v; want 6 computes", order)
+	}
+	for i, key := range order {
+		if fast := strings.HasPrefix(key, "fast"); fast != (i < 3) {
+			t.Errorf("execution order = %v; want fast keys ahead of the backlog", order)
+			break
+		}
+	}
+	if s := g.Stats(); s.Queued != 0 {
+		t.Errorf("Queued = %d; want 0", s.Queued)
+	}
+
+	// 没有排队时直接执行，位置被正确归还
+	if v, err, _ := g.Do("fast-again", time.Hour, record("fast-again")); v != "fast-again" || err != nil {
+		t.Errorf("Do = %v, %v; want fast-again, nil", v, err)
+	}
+}
@@ -0,0 +1,68 @@
+package timesf
+
+import "time"
+
+// progressBuffer 是DoChanProgress的通道能够缓存的进度数量，接收者跟不上时之后的进度被丢弃。
+const progressBuffer = 16
+
+// Progress 是DoChanProgress的方法报告的进度。
+type Progress struct {
+	Done    int64
+	Total   int64
+	Message string
+}
+
+// Update 是DoChanProgress的通道收到的消息：Final为false时是一次进度，Final为true时是
+// 最终的结果，之后通道被关闭。
+type Update struct {
+	Progress Progress
+	Final    bool
+	Result   Result
+}
+
+// DoChanProgress 像DoChan方法，但是fn可以调用report报告进度，每一次进度按照报告的顺序
+// 发送给所有共享此调用的DoChanProgress通道，之后是最终的结果。通道最多缓存progressBuffer
+// 个进度，接收者跟不上时丢弃之后的进度，最终的结果总是会被发送。加入调用之前报告的进度
+// 不会被补发；加入的调用不是DoChanProgress开始的，或者命中已完成的结果时只收到结果。
+// 后台刷新和重新执行时报告的进度被丢弃。
+func (g *Group) DoChanProgress(key string, validTime time.Duration, fn func(report func(p Progress)) (interface{}, error)) <-chan Update {
+	out := make(chan Update, progressBuffer+1)
+	var self *call
+	assigned := make(chan struct{})
+	ch, c := g.doChan(key, params{validTime: validTime}, func() (interface{}, error) {
+		<-assigned
+		return fn(func(p Progress) {
+			g.report(self, p)
+		})
+	})
+	g.mu.Lock()
+	if c != nil && !c.done {
+		c.progress = append(c.progress, out)
+	}
+	self = c
+	g.mu.Unlock()
+	close(assigned)
+	go func() {
+		out <- Update{Final: true, Result: <-ch}
+		close(out)
+	}()
+	return out
+}
+
+// report 将进度p发送给调用c的所有DoChanProgress通道，通道已满时丢弃，c完成之后什么都不做。
+func (g *Group) report(c *call, p Progress) {
+	if c == nil {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if c.done {
+		return
+	}
+	for _, ch := range c.progress {
+		select {
+		case ch <- Update{Progress: p}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package timesf
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDoChanProgress(t *testing.T) {
+	var g Group
+	joined := make(chan struct{})
+	leader := g.DoChanProgress("key", time.Hour, func(report func(Progress)) (interface{}, error) {
+		<-joined
+		report(Progress{Done: 1, Total: 2})
+		report(Progress{Done: 2, Total: 2, Message: "done"})
+		return "report", nil
+	})
+	chans := []<-chan Update{leader}
+	for i := 0; i < 2; i++ {
+		chans = append(chans, g.DoChanProgress("key", time.Hour, nil))
+	}
+	close(joined)
+
+	for i, ch := range chans {
+		var got []Update
+		for u := range ch {
+			got = append(got, u)
+		}
+		if len(got) != 3 {
+			t.Fatalf("channel %d received %d updates; want 2 progress and the result", i, len(got))
+		}
+		if got[0].Final || got[0].Progress.Done != 1 || got[1].Final || got[1].Progress.Message != "done" {
+			t.Errorf("channel %d progress = %+v, %+v; want 1/2 then 2/2 done", i, got[0], got[1])
+		}
+		if r := got[2].Result; !got[2].Final || r.Val != "report" || r.Err != nil || !r.Shared {
+			t.Errorf("channel %d final = %+v; want shared report result", i, got[2])
+		}
+	}
+
+	// 命中已完成的结果时只收到结果
+	var n int
+	for u := range g.DoChanProgress("key", time.Hour, nil) {
+		n++
+		if !u.Final || u.Result.Val != "report" {
+			t.Errorf("hit update = %+v; want the final cached result", u)
+		}
+	}
+	if n != 1 {
+		t.Errorf("hit received %d updates; want 1", n)
+	}
+}
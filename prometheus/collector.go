@@ -0,0 +1,60 @@
+// Package prometheus 将timesf.Group的统计数据导出为Prometheus指标。
+package prometheus
+
+import (
+	"github.com/ChangsongLiQD/timesf"
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	hitsDesc = prom.NewDesc("singlecache_hits_total",
+		"Number of calls served from a completed cached result.", []string{"group"}, nil)
+	missesDesc = prom.NewDesc("singlecache_misses_total",
+		"Number of calls that started a new execution.", []string{"group"}, nil)
+	sharedWaitsDesc = prom.NewDesc("singlecache_shared_waits_total",
+		"Number of calls that joined an in-flight execution.", []string{"group"}, nil)
+	executionsDesc = prom.NewDesc("singlecache_executions_total",
+		"Number of times fn was executed, including background refreshes.", []string{"group"}, nil)
+	entriesDesc = prom.NewDesc("singlecache_entries",
+		"Number of keys currently tracked by the group.", []string{"group"}, nil)
+	inFlightDesc = prom.NewDesc("singlecache_inflight",
+		"Number of executions currently running.", []string{"group"}, nil)
+)
+
+// Collector 实现了prometheus.Collector，导出一个或多个命名的Group的统计数据，每个
+// 指标都带有group标签。
+type Collector struct {
+	groups map[string]*timesf.Group
+}
+
+// NewCollector 创建导出groups统计数据的Collector，map的key是group标签的值。
+func NewCollector(groups map[string]*timesf.Group) *Collector {
+	c := &Collector{groups: make(map[string]*timesf.Group, len(groups))}
+	for name, g := range groups {
+		c.groups[name] = g
+	}
+	return c
+}
+
+// Describe 实现prometheus.Collector。
+func (c *Collector) Describe(ch chan<- *prom.Desc) {
+	ch <- hitsDesc
+	ch <- missesDesc
+	ch <- sharedWaitsDesc
+	ch <- executionsDesc
+	ch <- entriesDesc
+	ch <- inFlightDesc
+}
+
+// Collect 实现prometheus.Collector。
+func (c *Collector) Collect(ch chan<- prom.Metric) {
+	for name, g := range c.groups {
+		s := g.Stats()
+		ch <- prom.MustNewConstMetric(hitsDesc, prom.CounterValue, float64(s.Hits), name)
+		ch <- prom.MustNewConstMetric(missesDesc, prom.CounterValue, float64(s.Misses), name)
+		ch <- prom.MustNewConstMetric(sharedWaitsDesc, prom.CounterValue, float64(s.Coalesced), name)
+		ch <- prom.MustNewConstMetric(executionsDesc, prom.CounterValue, float64(s.Executions), name)
+		ch <- prom.MustNewConstMetric(entriesDesc, prom.GaugeValue, float64(s.Entries), name)
+		ch <- prom.MustNewConstMetric(inFlightDesc, prom.GaugeValue, float64(s.InFlight), name)
+	}
+}
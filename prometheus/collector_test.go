@@ -0,0 +1,41 @@
+package prometheus
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ChangsongLiQD/timesf"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCollector(t *testing.T) {
+	var users, orders timesf.Group
+	fn := func() (interface{}, error) {
+		return nil, nil
+	}
+	users.Do("a", time.Minute, fn)
+	users.Do("a", time.Minute, fn)
+	orders.Do("b", time.Minute, fn)
+
+	reg := prom.NewPedanticRegistry()
+	reg.MustRegister(NewCollector(map[string]*timesf.Group{"users": &users, "orders": &orders}))
+
+	want := `
+# HELP singlecache_hits_total Number of calls served from a completed cached result.
+# TYPE singlecache_hits_total counter
+singlecache_hits_total{group="orders"} 0
+singlecache_hits_total{group="users"} 1
+# HELP singlecache_entries Number of keys currently tracked by the group.
+# TYPE singlecache_entries gauge
+singlecache_entries{group="orders"} 1
+singlecache_entries{group="users"} 1
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want), "singlecache_hits_total", "singlecache_entries"); err != nil {
+		t.Error(err)
+	}
+	if n, err := testutil.GatherAndCount(reg); err != nil || n != 12 {
+		t.Errorf("GatherAndCount = %d, %v; want 12, nil", n, err)
+	}
+}
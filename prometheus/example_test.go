@@ -0,0 +1,17 @@
+package prometheus_test
+
+import (
+	"net/http"
+
+	"github.com/ChangsongLiQD/timesf"
+	timesfprom "github.com/ChangsongLiQD/timesf/prometheus"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+func ExampleCollector() {
+	users := timesf.New()
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(timesfprom.NewCollector(map[string]*timesf.Group{"users": users}))
+	http.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+}
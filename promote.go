@@ -0,0 +1,62 @@
+package timesf
+
+// maxPromotions 是WithoutSharedErrors为同一批等待者重新执行方法的最多次数。
+const maxPromotions = 3
+
+// WithoutSharedErrors 让方法返回的错误只交给执行方法的调用者，而不是共享给所有等待的
+// 调用者：调用失败并且有调用者在等待时，用同样的方法为key开始新的调用，等待者转而等待
+// 新调用的结果，比如执行方法的调用者自己的ctx超时导致的失败不会影响其他调用者。每一次
+// 接替相当于其中一个等待者成为执行方法的调用者，没有其他等待者时其错误不再被接替；同一批
+// 等待者最多接替maxPromotions次，之后的错误像平常一样共享。只作用于Do和DoChan系列
+// 方法，DoMulti的批量调用、后台刷新和被WithMaxConcurrency拒绝的调用不受影响。
+func WithoutSharedErrors() Option {
+	return func(g *Group) {
+		g.noSharedErrors = true
+	}
+}
+
+// promote 在设置了WithoutSharedErrors时，为失败的调用c的等待者开始接替的调用，返回是否
+// 开始。c刚刚完成并且仍然是key当前的调用，调用者需要持有锁。
+func (g *Group) promote(c *call, key string) bool {
+	p := c.rerun()
+	if !g.noSharedErrors || c.err == nil || c.rejected || c.dups == 0 || c.promotions >= maxPromotions ||
+		g.refused != nil || c.fn == nil && p.ttlFn == nil && p.ctxFn == nil && p.seedFn == nil && p.versionFn == nil {
+		return false
+	}
+	next := &call{fn: c.fn, params: p, startedAt: g.now(), dups: c.dups - 1, promotions: c.promotions + 1}
+	if c.chanOwner {
+		next.chans = append([]chan<- Result(nil), c.chans[1:]...)
+		c.chans = c.chans[:1]
+	} else {
+		next.chans, c.chans = c.chans, nil
+	}
+	c.dups = 0
+	c.next = next
+	next.cancelable()
+	g.begin()
+	g.m[key] = next
+	g.pending++
+	g.t[key] = g.pendingValidTime(p.validTime)
+	g.unpublish(key)
+	g.track(key, c, next)
+	go g.doCall(next, key, next.fn)
+	return true
+}
+
+// successor 返回接替c的调用，以及其完成时被关闭的通道。调用者不能持有锁。
+func (g *Group) successor(c *call) (*call, <-chan struct{}) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	next := c.next
+	if next.done {
+		return next, closedChan
+	}
+	return next, next.readyChan()
+}
+
+// closedChan 是一个已经关闭的通道。
+var closedChan = func() chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
+}()
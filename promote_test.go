@@ -0,0 +1,90 @@
+package timesf
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithoutSharedErrors(t *testing.T) {
+	g := New(WithoutSharedErrors())
+	var calls int32
+	release := make(chan struct{})
+	errFlaky := errors.New("flaky")
+	fn := func() (interface{}, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			<-release
+			return nil, errFlaky
+		}
+		return "good", nil
+	}
+
+	owner := make(chan error)
+	go func() {
+		_, err, _ := g.Do("key", time.Hour, fn)
+		owner <- err
+	}()
+	waitFor(t, func() bool { return g.Stats().InFlight == 1 })
+
+	const n = 10
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var r Result
+			if i%2 == 0 {
+				r.Val, r.Err, _ = g.Do("key", time.Hour, fn)
+			} else {
+				r = <-g.DoChan("key", time.Hour, fn)
+			}
+			if r.Val != "good" || r.Err != nil {
+				t.Errorf("waiter %d = %v, %v; want good, nil", i, r.Val, r.Err)
+			}
+		}(i)
+	}
+	waitFor(t, func() bool { return g.InFlight()["key"] == n })
+	close(release)
+	if err := <-owner; err != errFlaky {
+		t.Errorf("owner error = %v; want %v", err, errFlaky)
+	}
+	wg.Wait()
+	if calls != 2 {
+		t.Errorf("calls = %d; want 2", calls)
+	}
+	if v, _, _ := g.Peek("key"); v != "good" {
+		t.Errorf("cached value = %v; want good", v)
+	}
+}
+
+func TestWithoutSharedErrorsBounded(t *testing.T) {
+	g := New(WithoutSharedErrors())
+	var calls int32
+	release := make(chan struct{})
+	errAlways := errors.New("always")
+	fn := func() (interface{}, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			<-release
+		}
+		return nil, errAlways
+	}
+	ch := g.DoChan("key", time.Hour, fn)
+	waitFor(t, func() bool { return g.Stats().InFlight == 1 })
+	// 每一次接替都有一个等待者成为执行方法的调用者，接替的次数同样受maxPromotions限制
+	var waiters []<-chan Result
+	for i := 0; i < 5; i++ {
+		waiters = append(waiters, g.DoChan("key", time.Hour, fn))
+	}
+	close(release)
+	<-ch
+	for _, w := range waiters {
+		if r := <-w; r.Err != errAlways {
+			t.Errorf("waiter error = %v; want %v", r.Err, errAlways)
+		}
+	}
+	if calls != 1+maxPromotions {
+		t.Errorf("calls = %d; want %d", calls, 1+maxPromotions)
+	}
+}
@@ -0,0 +1,89 @@
+package timesf
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// WithHitRatioWindow 让HitRatio统计最近window时长内的命中率，window被分为buckets个桶，
+// 最早的桶随着时间滚动被丢弃，桶越多滚动越平滑。每一次查找增加几次原子操作，不需要持有锁。
+// window或者buckets不大于0时使用累计的统计数据，这是默认值。
+func WithHitRatioWindow(window time.Duration, buckets int) Option {
+	return func(g *Group) {
+		g.ratio = nil
+		if window <= 0 || buckets <= 0 {
+			return
+		}
+		width := int64(window) / int64(buckets)
+		if width < 1 {
+			width = 1
+		}
+		g.ratio = &hitWindow{width: width, buckets: make([]ratioBucket, buckets)}
+	}
+}
+
+// HitRatio 返回不需要执行方法的查找所占的比例：命中已完成的结果和等待正在进行的调用都
+// 算作命中，开启了新的调用算作未命中。设置了WithHitRatioWindow时只统计窗口内的查找，
+// 否则使用Stats的累计数量。没有查找时返回0。
+func (g *Group) HitRatio() float64 {
+	var hits, total uint64
+	if w := g.ratio; w != nil {
+		hits, total = w.load(g.now())
+	} else {
+		hits = g.stats.hits.Load() + g.stats.coalesced.Load()
+		total = hits + g.stats.misses.Load()
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(hits) / float64(total)
+}
+
+// looked 在设置了WithHitRatioWindow时记录一次查找，hit标识是否不需要执行方法。
+func (g *Group) looked(hit bool) {
+	if w := g.ratio; w != nil {
+		w.record(g.now(), hit)
+	}
+}
+
+// hitWindow 是按时间滚动的命中率统计。
+type hitWindow struct {
+	// width 是每个桶的纳秒数。
+	width   int64
+	buckets []ratioBucket
+}
+
+// ratioBucket 记录一个时间段内的查找，epoch是时间段的序号。
+type ratioBucket struct {
+	epoch atomic.Int64
+	hits  atomic.Uint64
+	total atomic.Uint64
+}
+
+// record 将now时的一次查找计入对应的桶，桶属于更早的时间段时先将其清空。清空和同时
+// 进行的记录之间不加锁，滚动的瞬间可能少记几次查找。
+func (w *hitWindow) record(now int64, hit bool) {
+	epoch := now / w.width
+	b := &w.buckets[epoch%int64(len(w.buckets))]
+	if e := b.epoch.Load(); e != epoch && b.epoch.CompareAndSwap(e, epoch) {
+		b.hits.Store(0)
+		b.total.Store(0)
+	}
+	b.total.Add(1)
+	if hit {
+		b.hits.Add(1)
+	}
+}
+
+// load 返回now时窗口内的命中次数和查找次数。
+func (w *hitWindow) load(now int64) (hits, total uint64) {
+	epoch := now / w.width
+	for i := range w.buckets {
+		b := &w.buckets[i]
+		if e := b.epoch.Load(); e > epoch-int64(len(w.buckets)) && e <= epoch {
+			hits += b.hits.Load()
+			total += b.total.Load()
+		}
+	}
+	return hits, total
+}
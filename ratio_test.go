@@ -0,0 +1,52 @@
+package timesf
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHitRatio(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithClock(clock), WithHitRatioWindow(10*time.Second, 10))
+	if r := g.HitRatio(); r != 0 {
+		t.Errorf("HitRatio without lookups = %v; want 0", r)
+	}
+	fn := func() (interface{}, error) { return 1, nil }
+
+	// 第一个窗口：每个key一次未命中、三次命中
+	for _, key := range []string{"a", "b"} {
+		for i := 0; i < 4; i++ {
+			g.Do(key, time.Hour, fn)
+		}
+	}
+	if r := g.HitRatio(); r != 0.75 {
+		t.Errorf("HitRatio = %v; want 0.75", r)
+	}
+
+	// 5秒之后只有命中，窗口同时包含两段
+	clock.Advance(5 * time.Second)
+	for i := 0; i < 8; i++ {
+		g.Do("a", time.Hour, fn)
+	}
+	if r := g.HitRatio(); r != 14.0/16 {
+		t.Errorf("HitRatio = %v; want %v", r, 14.0/16)
+	}
+
+	// 最初的查找滚出窗口
+	clock.Advance(6 * time.Second)
+	if r := g.HitRatio(); r != 1 {
+		t.Errorf("HitRatio after rotation = %v; want 1", r)
+	}
+	clock.Advance(time.Minute)
+	if r := g.HitRatio(); r != 0 {
+		t.Errorf("HitRatio after idle window = %v; want 0", r)
+	}
+
+	// 没有设置窗口时使用累计的统计数据
+	var cum Group
+	cum.Do("a", time.Hour, fn)
+	cum.Do("a", time.Hour, fn)
+	if r := cum.HitRatio(); r != 0.5 {
+		t.Errorf("cumulative HitRatio = %v; want 0.5", r)
+	}
+}
@@ -0,0 +1,42 @@
+package timesf
+
+import (
+	"context"
+	"time"
+)
+
+// ReadThrough 把Group封装成常规的读穿透缓存：加载方法和有效时长在创建时确定，调用者只
+// 需要提供key，见NewReadThrough。
+type ReadThrough struct {
+	g         *Group
+	validTime time.Duration
+	load      func(ctx context.Context, key string) (interface{}, error)
+}
+
+// NewReadThrough 创建一个使用g缓存结果的ReadThrough，缺失的key调用load加载，结果有效
+// validTime时长。g为nil时使用一个新的Group。
+func NewReadThrough(g *Group, validTime time.Duration, load func(ctx context.Context, key string) (interface{}, error)) *ReadThrough {
+	if g == nil {
+		g = New()
+	}
+	return &ReadThrough{g: g, validTime: validTime, load: load}
+}
+
+// GetOrLoad 返回key的值，缺失或者过期时调用加载方法。同一个key同时进行的加载只执行
+// 一次，ctx的取消按照DoCtx的方式处理。
+func (r *ReadThrough) GetOrLoad(ctx context.Context, key string) (interface{}, error) {
+	v, err, _ := r.g.DoCtx(ctx, key, r.validTime, func(ctx context.Context) (interface{}, error) {
+		return r.load(ctx, key)
+	})
+	return v, err
+}
+
+// Forget 遗忘key，之后的GetOrLoad会重新加载，见Group.Forget。
+func (r *ReadThrough) Forget(key string) {
+	r.g.Forget(key)
+}
+
+// Group 返回底层的Group，用于统计数据等ReadThrough没有暴露的功能。
+func (r *ReadThrough) Group() *Group {
+	return r.g
+}
@@ -0,0 +1,44 @@
+package timesf
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestReadThrough(t *testing.T) {
+	var loads int32
+	release := make(chan struct{})
+	r := NewReadThrough(nil, time.Hour, func(ctx context.Context, key string) (interface{}, error) {
+		atomic.AddInt32(&loads, 1)
+		<-release
+		return "value of " + key, nil
+	})
+
+	const n = 10
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := r.GetOrLoad(context.Background(), "key")
+			if v != "value of key" || err != nil {
+				t.Errorf("GetOrLoad = %v, %v; want value of key, nil", v, err)
+			}
+		}()
+	}
+	waitFor(t, func() bool { return r.Group().InFlight()["key"] == n-1 })
+	close(release)
+	wg.Wait()
+	if loads != 1 {
+		t.Errorf("loads = %d; want 1", loads)
+	}
+
+	r.Forget("key")
+	r.GetOrLoad(context.Background(), "key")
+	if loads != 2 {
+		t.Errorf("loads after Forget = %d; want 2", loads)
+	}
+}
@@ -0,0 +1,20 @@
+package timesf
+
+// WithReleaseFuncs 让已完成的结果不再持有其方法：调用完成之后丢弃fn以及DoCtx和
+// DoWithSeeds的方法，方法捕获的对象可以被回收，适用于缓存大量长期有效的key、方法又
+// 捕获了较大对象的Group。只释放方法，结果仍然以完整的调用记录保存，其他字段占用的内存
+// 不变。DoWithTTL的方法决定了结果是否使用滑动过期，仍然被保留。代价是这些结果不再能用
+// 自己的方法刷新，WithRefreshAhead和WithRefreshBefore对其不再生效，DoStale的刷新仍然
+// 使用调用者传入的方法。默认不开启。
+func WithReleaseFuncs(enabled bool) Option {
+	return func(g *Group) {
+		g.releaseFuncs = enabled
+	}
+}
+
+// dropFuncs 在开启WithReleaseFuncs时丢弃刚刚完成的调用c的方法，调用者需要持有锁。
+func (g *Group) dropFuncs(c *call) {
+	if g.releaseFuncs {
+		c.fn, c.ctxFn, c.seedFn = nil, nil, nil
+	}
+}
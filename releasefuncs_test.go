@@ -0,0 +1,151 @@
+package timesf
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestReleaseFuncs(t *testing.T) {
+	g := New(WithReleaseFuncs(true))
+	var collected int32
+	func() {
+		big := make([]byte, 1<<20)
+		runtime.SetFinalizer(&big[0], func(*byte) { atomic.StoreInt32(&collected, 1) })
+		g.Do("key", time.Hour, func() (interface{}, error) {
+			_ = big[0]
+			return "bar", nil
+		})
+	}()
+	for i := 0; i < 50 && atomic.LoadInt32(&collected) == 0; i++ {
+		runtime.GC()
+		time.Sleep(time.Millisecond)
+	}
+	if atomic.LoadInt32(&collected) == 0 {
+		t.Errorf("object captured by fn was not collected after the call completed")
+	}
+
+	// 命中已释放方法的结果
+	v, err, shared := g.Do("key", time.Hour, func() (interface{}, error) {
+		t.Errorf("fn called for a hit after its funcs were released")
+		return nil, nil
+	})
+	if v != "bar" || err != nil || !shared {
+		t.Errorf("Do = %v, %v, %v; want bar, nil, true", v, err, shared)
+	}
+	if r := <-g.DoChan("key", time.Hour, func() (interface{}, error) {
+		t.Errorf("fn called for a hit after its funcs were released")
+		return nil, nil
+	}); r.Val != "bar" || r.Err != nil {
+		t.Errorf("DoChan = %+v; want bar", r)
+	}
+	if v, err, ok := g.Peek("key"); v != "bar" || err != nil || !ok {
+		t.Errorf("Peek = %v, %v, %v; want bar, nil, true", v, err, ok)
+	}
+
+	g.mu.RLock()
+	c := g.m["key"]
+	g.mu.RUnlock()
+	if c.fn != nil || c.chans != nil {
+		t.Errorf("completed call still holds fn or chans")
+	}
+}
+
+func TestReleaseFuncsEntryOps(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithClock(clock), WithReleaseFuncs(true))
+	var calls int32
+	fn := func() (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		return "bar", nil
+	}
+	g.Do("key", time.Minute, fn)
+
+	if !g.Touch("key", time.Hour) {
+		t.Fatal("Touch = false; want true")
+	}
+	if ttl, _ := g.TTL("key"); ttl != time.Hour {
+		t.Errorf("TTL after Touch = %v; want 1h", ttl)
+	}
+	clock.Advance(30 * time.Minute)
+	if v, _, shared := g.Do("key", time.Minute, fn); v != "bar" || !shared {
+		t.Errorf("Do after Touch = %v, %v; want bar, true", v, shared)
+	}
+
+	if !g.Update("key", "baz") {
+		t.Fatal("Update = false; want true")
+	}
+	if v, _, _ := g.Do("key", time.Minute, fn); v != "baz" {
+		t.Errorf("Do after Update = %v; want baz", v)
+	}
+	if r := <-g.DoChan("key", time.Minute, fn); r.Val != "baz" || r.Role != RoleCachedHit {
+		t.Errorf("DoChan after Update = %+v; want a cached hit of baz", r)
+	}
+	if ks, ok := g.KeyStats("key"); !ok || ks.Hits != 2 {
+		t.Errorf("KeyStats = %+v, %v; want 2 hits on the updated result", ks, ok)
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("fn called %d times; want 1", n)
+	}
+
+	// 过期之后用调用者传入的方法重新执行
+	clock.Advance(time.Hour)
+	if v, _, _ := g.Do("key", time.Minute, fn); v != "bar" || atomic.LoadInt32(&calls) != 2 {
+		t.Errorf("Do after expiry = %v (calls %d); want bar, 2 calls", v, calls)
+	}
+}
+
+func TestReleaseFuncsNoRefreshAhead(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithClock(clock), WithReleaseFuncs(true), WithRefreshAhead(0.5))
+	var calls int32
+	fn := func() (interface{}, error) {
+		return atomic.AddInt32(&calls, 1), nil
+	}
+	g.Do("key", time.Second, fn)
+	clock.Advance(800 * time.Millisecond)
+	if v, _, _ := g.Do("key", time.Second, fn); v != int32(1) {
+		t.Errorf("Do = %v; want cached 1", v)
+	}
+	g.Wait(context.Background())
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("fn called %d times; want 1, results without funcs are not refreshed ahead", n)
+	}
+
+	clock.Advance(time.Second)
+	if v, _, _ := g.Do("key", time.Second, fn); v != int32(2) {
+		t.Errorf("Do after expiry = %v; want 2", v)
+	}
+}
+
+func BenchmarkReleaseFuncs(b *testing.B) {
+	for _, enabled := range []bool{false, true} {
+		b.Run(fmt.Sprintf("enabled=%v", enabled), func(b *testing.B) {
+			const keys = 1000
+			var ms runtime.MemStats
+			var retained uint64
+			for i := 0; i < b.N; i++ {
+				g := New(WithReleaseFuncs(enabled))
+				runtime.GC()
+				runtime.ReadMemStats(&ms)
+				before := ms.HeapAlloc
+				for k := 0; k < keys; k++ {
+					buf := make([]byte, 256)
+					g.Do(fmt.Sprint(k), time.Hour, func() (interface{}, error) {
+						return len(buf), nil
+					})
+				}
+				runtime.GC()
+				runtime.ReadMemStats(&ms)
+				if ms.HeapAlloc > before {
+					retained += ms.HeapAlloc - before
+				}
+				runtime.KeepAlive(g)
+			}
+			b.ReportMetric(float64(retained)/float64(b.N*keys), "retained-B/key")
+		})
+	}
+}
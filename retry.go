@@ -0,0 +1,46 @@
+package timesf
+
+import "time"
+
+// DoRetry 像Do方法，但是方法返回错误时，执行方法的调用者最多重试到attempts次，每两次
+// 之间等待backoff，最后一次的结果才会返回给所有调用者。重试期间重复的调用者等待整个
+// 重试过程，不会自己执行方法。attempts小于1时按1处理。
+func (g *Group) DoRetry(key string, validTime time.Duration, attempts int, backoff time.Duration, fn func() (interface{}, error)) (v interface{}, err error, shared bool) {
+	p := retryPolicy{attempts: attempts, backoff: func(int) time.Duration { return backoff }}
+	return g.Do(key, validTime, p.wrap(fn, nil))
+}
+
+// WithRetry 为Group的所有调用设置重试：方法返回的错误满足retryIf时，执行方法的调用者
+// 最多重试到attempts次，第attempt次失败之后等待backoff(attempt)，attempt从1开始。重试
+// 期间重复的调用者继续等待，只有最后一次的结果会被共享和缓存，重试成功的结果的有效期
+// 从成功时开始计算。retryIf为nil时重试所有错误，backoff为nil时不等待。
+func WithRetry(attempts int, backoff func(attempt int) time.Duration, retryIf func(error) bool) Option {
+	return func(g *Group) {
+		g.retry = &retryPolicy{attempts: attempts, backoff: backoff, retryIf: retryIf}
+	}
+}
+
+// retryPolicy 是方法返回错误时的重试策略。
+type retryPolicy struct {
+	attempts int
+	backoff  func(attempt int) time.Duration
+	retryIf  func(error) bool
+}
+
+// wrap 返回一个按照策略重试fn的方法。retried不为nil时，进行了重试则被设置为true。
+func (p *retryPolicy) wrap(fn func() (interface{}, error), retried *bool) func() (interface{}, error) {
+	return func() (v interface{}, err error) {
+		for attempt := 1; ; attempt++ {
+			v, err = fn()
+			if err == nil || attempt >= p.attempts || p.retryIf != nil && !p.retryIf(err) {
+				return v, err
+			}
+			if retried != nil {
+				*retried = true
+			}
+			if p.backoff != nil {
+				time.Sleep(p.backoff(attempt))
+			}
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package timesf
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDoRetry(t *testing.T) {
+	var g Group
+	someErr := errors.New("some error")
+	var calls int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	fn := func() (interface{}, error) {
+		switch atomic.AddInt32(&calls, 1) {
+		case 1:
+			close(started)
+			<-release
+			return nil, someErr
+		case 2:
+			return nil, someErr
+		}
+		return "bar", nil
+	}
+
+	const n = 10
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if v, err, _ := g.DoRetry("key", time.Hour, 3, time.Millisecond, fn); v != "bar" || err != nil {
+			t.Errorf("leader DoRetry = %v, %v; want bar, nil", v, err)
+		}
+	}()
+	<-started
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// 重复的调用者不能自己执行方法
+			v, err, shared := g.DoRetry("key", time.Hour, 3, time.Millisecond, func() (interface{}, error) {
+				t.Error("duplicate caller executed fn")
+				return nil, nil
+			})
+			if v != "bar" || err != nil || !shared {
+				t.Errorf("DoRetry = %v, %v, %v; want bar, nil, true", v, err, shared)
+			}
+		}()
+	}
+	waitFor(t, func() bool { return g.Stats().Coalesced == n })
+	close(release)
+	wg.Wait()
+	if got := atomic.LoadInt32(&calls); got != 3 {
+		t.Errorf("calls = %d; want 3", got)
+	}
+}
+
+func TestDoRetryGivesUp(t *testing.T) {
+	var g Group
+	someErr := errors.New("some error")
+	var calls int32
+	_, err, _ := g.DoRetry("key", time.Hour, 2, 0, func() (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, someErr
+	})
+	if err != someErr || calls != 2 {
+		t.Errorf("DoRetry = %v after %d calls; want %v after 2", err, calls, someErr)
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+	clock := newFakeClock()
+	transient := errors.New("connection reset")
+	fatal := errors.New("not found")
+	var backoffs []int
+	g := New(WithClock(clock), WithRetry(3, func(attempt int) time.Duration {
+		backoffs = append(backoffs, attempt)
+		clock.Advance(time.Minute)
+		return 0
+	}, func(err error) bool {
+		return err == transient
+	}))
+
+	// 第二次成功，有效期从成功时开始计算
+	var calls int
+	v, err, _ := g.Do("ok", time.Hour, func() (interface{}, error) {
+		calls++
+		if calls == 1 {
+			return nil, transient
+		}
+		return "bar", nil
+	})
+	if v != "bar" || err != nil || calls != 2 {
+		t.Errorf("Do = %v, %v after %d calls; want bar, nil after 2", v, err, calls)
+	}
+	if ttl, _ := g.TTL("ok"); ttl != time.Hour {
+		t.Errorf("TTL = %v; want 1h from the successful attempt", ttl)
+	}
+
+	// 重试次数耗尽
+	calls = 0
+	_, err, _ = g.Do("exhausted", time.Hour, func() (interface{}, error) {
+		calls++
+		return nil, transient
+	})
+	if err != transient || calls != 3 {
+		t.Errorf("Do = %v after %d calls; want %v after 3", err, calls, transient)
+	}
+
+	// retryIf不满足时不重试
+	calls = 0
+	_, err, _ = g.Do("fatal", time.Hour, func() (interface{}, error) {
+		calls++
+		return nil, fatal
+	})
+	if err != fatal || calls != 1 {
+		t.Errorf("Do = %v after %d calls; want %v after 1", err, calls, fatal)
+	}
+	if got, want := fmt.Sprint(backoffs), "[1 1 2]"; got != want {
+		t.Errorf("backoff attempts = %v; want %v", got, want)
+	}
+}
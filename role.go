@@ -0,0 +1,30 @@
+package timesf
+
+// Role 标识调用者是如何拿到结果的，见Result.Role。
+type Role int
+
+const (
+	// RoleNone 标识调用者没有拿到调用的结果，比如被ErrTooManyWaiters拒绝或者等待超时。
+	RoleNone Role = iota
+	// RoleLeader 标识调用者开始了这次调用，方法为它而执行，每一次执行只有一个。
+	RoleLeader
+	// RoleWaiter 标识调用者等待了其他调用者开始的调用或者刷新。
+	RoleWaiter
+	// RoleCachedHit 标识调用者命中了已经完成的结果，没有执行方法也没有等待。
+	RoleCachedHit
+)
+
+// String 返回角色的名称。
+func (r Role) String() string {
+	switch r {
+	case RoleNone:
+		return "none"
+	case RoleLeader:
+		return "leader"
+	case RoleWaiter:
+		return "waiter"
+	case RoleCachedHit:
+		return "cached hit"
+	}
+	return "unknown"
+}
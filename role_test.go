@@ -0,0 +1,78 @@
+package timesf
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestResultRole(t *testing.T) {
+	g := New()
+	for cycle := 0; cycle < 3; cycle++ {
+		release := make(chan struct{})
+		started := make(chan struct{})
+		fn := func() (interface{}, error) {
+			close(started)
+			<-release
+			return cycle, nil
+		}
+		var mu sync.Mutex
+		roles := make(map[Role]int)
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := g.DoDetailed("key", time.Hour, fn)
+			mu.Lock()
+			roles[r.Role]++
+			mu.Unlock()
+		}()
+		<-started
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				r := g.DoDetailed("key", time.Hour, fn)
+				mu.Lock()
+				roles[r.Role]++
+				mu.Unlock()
+			}()
+		}
+		ch := g.DoChan("key", time.Hour, fn)
+		waitFor(t, func() bool { return g.InFlight()["key"] == 6 })
+		close(release)
+		wg.Wait()
+		roles[(<-ch).Role]++
+		if roles[RoleLeader] != 1 || roles[RoleWaiter] != 6 {
+			t.Errorf("cycle %d roles = %v; want 1 leader, 6 waiters", cycle, roles)
+		}
+		if r := g.DoDetailed("key", time.Hour, fn); r.Role != RoleCachedHit {
+			t.Errorf("cycle %d DoDetailed after completion role = %v; want cached hit", cycle, r.Role)
+		}
+		if r := <-g.DoChan("key", time.Hour, fn); r.Role != RoleCachedHit {
+			t.Errorf("cycle %d DoChan after completion role = %v; want cached hit", cycle, r.Role)
+		}
+		g.Forget("key")
+	}
+
+	// DoChan开始的调用，第一个通道是执行方法的调用者
+	release := make(chan struct{})
+	fn := func() (interface{}, error) {
+		<-release
+		return nil, nil
+	}
+	owner := g.DoChan("chan", time.Hour, fn)
+	waiter := g.DoChan("chan", time.Hour, fn)
+	close(release)
+	if r := <-owner; r.Role != RoleLeader {
+		t.Errorf("DoChan owner role = %v; want leader", r.Role)
+	}
+	if r := <-waiter; r.Role != RoleWaiter {
+		t.Errorf("DoChan waiter role = %v; want waiter", r.Role)
+	}
+
+	g.Freeze()
+	if r := g.DoDetailed("frozen", time.Hour, fn); r.Role != RoleNone {
+		t.Errorf("rejected role = %v; want none", r.Role)
+	}
+}
@@ -0,0 +1,28 @@
+package timesf
+
+import "time"
+
+// DoWithSeeds 像Do方法，但是fn在返回key的结果的同时，可以返回顺带得到的其他key的结果，
+// 比如获取父对象时一起加载的子对象。fn成功并且结果被缓存时，seeds中的结果和key的结果在
+// 同一次持有锁时像SetValue一样写入，有效时长相同，之后这些key的调用者直接命中。已经有
+// 正在进行的调用的key被跳过，seeds中的key本身也被忽略。后台刷新同样写入其seeds。
+func (g *Group) DoWithSeeds(key string, validTime time.Duration, fn func() (v interface{}, seeds map[string]interface{}, err error)) (v interface{}, err error, shared bool) {
+	r, _, _ := g.do(key, params{validTime: validTime, seedFn: fn}, nil)
+	return r.Val, r.Err, r.Shared
+}
+
+// seed 写入调用c的方法返回的其他key的结果，ttl是c的有效时长。调用者需要持有锁，并且
+// c刚刚成功完成并被缓存为key的结果。
+func (g *Group) seed(evs []eviction, key string, c *call, ttl time.Duration) []eviction {
+	if c.seeds == nil || c.err != nil {
+		return evs
+	}
+	now := g.now()
+	for k, val := range c.seeds {
+		if k = g.normalize(k); k != key {
+			evs, _ = g.install(evs, k, val, ttl, now)
+		}
+	}
+	c.seeds = nil
+	return evs
+}
@@ -0,0 +1,60 @@
+package timesf
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDoWithSeeds(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithClock(clock))
+	release := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		v, err, _ := g.DoWithSeeds("parent", time.Second, func() (interface{}, map[string]interface{}, error) {
+			<-release
+			return "p", map[string]interface{}{"child": "c", "parent": "ignored"}, nil
+		})
+		if v != "p" || err != nil {
+			t.Errorf("DoWithSeeds = %v, %v; want p, nil", v, err)
+		}
+	}()
+	waitFor(t, func() bool { return g.InFlightCount() == 1 })
+
+	// 等待parent的调用者拿到结果时，child已经被写入
+	waited := make(chan struct{})
+	go func() {
+		defer close(waited)
+		g.Do("parent", time.Second, nil)
+		v, _, _ := g.Do("child", time.Second, func() (interface{}, error) {
+			t.Error("child fn called; want a seeded hit")
+			return nil, nil
+		})
+		if v != "c" {
+			t.Errorf("Do(child) = %v; want c", v)
+		}
+	}()
+	waitFor(t, func() bool { return g.InFlight()["parent"] == 1 })
+	close(release)
+	<-done
+	<-waited
+	if v, _, _ := g.Peek("parent"); v != "p" {
+		t.Errorf("Peek(parent) = %v; want p", v)
+	}
+
+	// seeds和key的结果有相同的有效时长
+	clock.Advance(time.Second)
+	if g.Has("child") {
+		t.Error("seeded child outlived its parent's TTL")
+	}
+
+	// 失败时不写入seeds
+	g.DoWithSeeds("broken", time.Second, func() (interface{}, map[string]interface{}, error) {
+		return nil, map[string]interface{}{"orphan": 1}, errors.New("boom")
+	})
+	if g.Has("orphan") {
+		t.Error("seeds written for a failed call")
+	}
+}
@@ -0,0 +1,107 @@
+package timesf
+
+import "time"
+
+// ShardedGroup 将key按哈希分散到多个独立的Group中，每个分片有自己的锁，用来降低大量
+// 并发调用时的锁竞争。对同一个key的调用总是落在同一个分片上，因此其行为和单个Group
+// 相同。
+type ShardedGroup struct {
+	shards []*Group
+}
+
+// NewSharded 创建一个有n个分片的ShardedGroup，每个分片都使用opts创建，n小于1时按1
+// 处理。WithCapacity等按Group计算的选项作用于每个分片。
+func NewSharded(n int, opts ...Option) *ShardedGroup {
+	if n < 1 {
+		n = 1
+	}
+	s := &ShardedGroup{shards: make([]*Group, n)}
+	for i := range s.shards {
+		s.shards[i] = New(opts...)
+	}
+	return s
+}
+
+// shard 返回key所在的分片，对按照WithKeyFunc转换之后的key使用FNV-1a哈希，转换之后相同
+// 的key总是落在同一个分片上。
+func (s *ShardedGroup) shard(key string) *Group {
+	key = s.shards[0].normalize(key)
+	h := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= 16777619
+	}
+	return s.shards[h%uint32(len(s.shards))]
+}
+
+// Do 见Group.Do。
+func (s *ShardedGroup) Do(key string, validTime time.Duration, fn func() (interface{}, error)) (v interface{}, err error, shared bool) {
+	return s.shard(key).Do(key, validTime, fn)
+}
+
+// DoChan 见Group.DoChan。
+func (s *ShardedGroup) DoChan(key string, validTime time.Duration, fn func() (interface{}, error)) <-chan Result {
+	return s.shard(key).DoChan(key, validTime, fn)
+}
+
+// Forget 见Group.Forget。
+func (s *ShardedGroup) Forget(key string) {
+	s.shard(key).Forget(key)
+}
+
+// ForgetStatus 见Group.ForgetStatus。
+func (s *ShardedGroup) ForgetStatus(key string) (existed, wasInFlight bool) {
+	return s.shard(key).ForgetStatus(key)
+}
+
+// ForgetAll 见Group.ForgetAll，返回所有分片中被遗忘的key的数量。
+func (s *ShardedGroup) ForgetAll() int {
+	n := 0
+	for _, g := range s.shards {
+		n += g.ForgetAll()
+	}
+	return n
+}
+
+// Len 返回所有分片中记录的key数量。
+func (s *ShardedGroup) Len() int {
+	n := 0
+	for _, g := range s.shards {
+		n += g.Len()
+	}
+	return n
+}
+
+// Stats 返回所有分片统计数据之和。
+func (s *ShardedGroup) Stats() Stats {
+	var total Stats
+	for _, g := range s.shards {
+		total.add(g.Stats())
+	}
+	return total
+}
+
+// StatsAndReset 返回所有分片统计数据之和，同时将各个分片的计数清零，见
+// Group.StatsAndReset。
+func (s *ShardedGroup) StatsAndReset() Stats {
+	var total Stats
+	for _, g := range s.shards {
+		total.add(g.StatsAndReset())
+	}
+	return total
+}
+
+// Stop 停止所有分片的后台清理协程，见Group.Stop。
+func (s *ShardedGroup) Stop() {
+	for _, g := range s.shards {
+		g.Stop()
+	}
+}
+
+// Close 关闭所有分片，见Group.Close。
+func (s *ShardedGroup) Close() error {
+	for _, g := range s.shards {
+		g.Close()
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package timesf
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestShardedGroup(t *testing.T) {
+	s := NewSharded(8)
+	var calls int32
+	fn := func() (interface{}, error) {
+		return atomic.AddInt32(&calls, 1), nil
+	}
+
+	const n = 100
+	for i := 0; i < n; i++ {
+		key := strconv.Itoa(i)
+		s.Do(key, time.Hour, fn)
+		if v, _, shared := s.Do(key, time.Hour, fn); v != int32(i+1) || !shared {
+			t.Errorf("second Do(%q) = %v, %v; want %d, true", key, v, shared, i+1)
+		}
+	}
+	if r := <-s.DoChan("0", time.Hour, fn); r.Val != int32(1) || !r.Shared {
+		t.Errorf("DoChan = %+v; want the cached value 1", r)
+	}
+
+	used := 0
+	for _, g := range s.shards {
+		if g.Len() > 0 {
+			used++
+		}
+	}
+	if used != len(s.shards) {
+		t.Errorf("keys landed in %d of %d shards", used, len(s.shards))
+	}
+
+	if existed, wasInFlight := s.ForgetStatus("0"); !existed || wasInFlight {
+		t.Errorf("ForgetStatus = %v, %v; want true, false", existed, wasInFlight)
+	}
+	if v, _, _ := s.Do("0", time.Hour, fn); v != int32(n+1) {
+		t.Errorf("Do after Forget = %v; want %d", v, n+1)
+	}
+	st := s.Stats()
+	if st.Misses != n+1 || st.Hits != n+1 || st.Forgets != 1 || st.Entries != n {
+		t.Errorf("Stats = %+v; want %d misses, %d hits, 1 forget and %d entries", st, n+1, n+1, n)
+	}
+	if got := s.ForgetAll(); got != n {
+		t.Errorf("ForgetAll = %d; want %d", got, n)
+	}
+	if got := s.Len(); got != 0 {
+		t.Errorf("Len after ForgetAll = %d; want 0", got)
+	}
+}
+
+// doer 是BenchmarkDo比较的Group和ShardedGroup共同的方法。
+type doer interface {
+	Do(key string, validTime time.Duration, fn func() (interface{}, error)) (interface{}, error, bool)
+}
+
+func BenchmarkDo(b *testing.B) {
+	const goroutines = 32
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = "key:" + strconv.Itoa(i)
+	}
+	fn := func() (interface{}, error) {
+		return nil, nil
+	}
+	for _, bb := range []struct {
+		name string
+		d    doer
+	}{
+		{"group", New()},
+		{"sharded", NewSharded(32)},
+	} {
+		b.Run(bb.name, func(b *testing.B) {
+			for _, key := range keys {
+				bb.d.Do(key, time.Hour, fn)
+			}
+			b.ResetTimer()
+			var wg sync.WaitGroup
+			for i := 0; i < goroutines; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					for j := i; j < b.N; j += goroutines {
+						bb.d.Do(keys[j%len(keys)], time.Hour, fn)
+					}
+				}(i)
+			}
+			wg.Wait()
+		})
+	}
+}
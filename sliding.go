@@ -0,0 +1,23 @@
+package timesf
+
+import "math"
+
+// WithSlidingExpiration 设置滑动过期：每次命中已完成的结果时，其有效期从命中的时间重新
+// 开始计算，持续被访问的结果不会过期，不再被访问validTime之后才过期。永不过期的结果、
+// 返回错误的结果和有效时长由方法返回的结果不受影响。开启时命中只使用加锁的路径。
+func WithSlidingExpiration(enabled bool) Option {
+	return func(g *Group) {
+		g.sliding = enabled
+	}
+}
+
+// slide 在开启滑动过期时，把key已完成的调用c的有效期从now开始重新计算，返回新的过期
+// 时间，t是原来的过期时间。调用者需要持有锁。
+func (g *Group) slide(key string, c *call, t, now int64) int64 {
+	if !g.sliding || c.err != nil || c.ttlFn != nil || c.validTime <= 0 || t == math.MaxInt64 {
+		return t
+	}
+	t = g.capped(c, addTime(now, c.validTime))
+	g.t[key] = t
+	return t
+}
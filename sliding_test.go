@@ -0,0 +1,62 @@
+package timesf
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSlidingExpiration(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithClock(clock), WithSlidingExpiration(true))
+	calls := 0
+	fn := func() (interface{}, error) {
+		calls++
+		return "bar", nil
+	}
+
+	g.Do("key", 100*time.Millisecond, fn)
+	for i := 0; i < 20; i++ {
+		clock.Advance(50 * time.Millisecond)
+		if i%2 == 0 {
+			g.Do("key", 100*time.Millisecond, fn)
+		} else {
+			<-g.DoChan("key", 100*time.Millisecond, fn)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("fn called %d times while being read; want 1", calls)
+	}
+	if ttl, _ := g.TTL("key"); ttl != 100*time.Millisecond {
+		t.Errorf("TTL after hit = %v; want 100ms", ttl)
+	}
+	g.DoMulti([]string{"key"}, 100*time.Millisecond, nil)
+
+	clock.Advance(99 * time.Millisecond)
+	if !g.Has("key") {
+		t.Error("entry expired before 100ms of inactivity")
+	}
+	clock.Advance(time.Millisecond)
+	if g.Has("key") {
+		t.Error("entry still present after 100ms of inactivity")
+	}
+
+	g.Do("forever", NoExpiration, fn)
+	g.Do("forever", NoExpiration, fn)
+	if ttl, _ := g.TTL("forever"); ttl != time.Duration(1<<63-1) {
+		t.Errorf("TTL of never-expiring entry = %v; want max", ttl)
+	}
+}
+
+func TestSlidingExpirationErrors(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithClock(clock), WithSlidingExpiration(true), WithErrorCaching(true))
+	g.Do("err", 100*time.Millisecond, func() (interface{}, error) {
+		return nil, errors.New("some error")
+	})
+	clock.Advance(50 * time.Millisecond)
+	g.Do("err", 100*time.Millisecond, nil)
+	if ttl, _ := g.TTL("err"); ttl != 50*time.Millisecond {
+		t.Errorf("TTL of errored result after hit = %v; want 50ms", ttl)
+	}
+}
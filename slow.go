@@ -0,0 +1,22 @@
+package timesf
+
+import "time"
+
+// WithSlowCallThreshold 在方法的一次执行超过d时调用onSlow，包括后台刷新。waiters是
+// 方法完成时等待其结果的重复调用者数量，不包括执行方法的调用者。onSlow在单独的协程中
+// 被调用，不会延迟调用者拿到结果，因此可能在调用者返回之后才被调用。d不大于0时不检查。
+// 每一次执行的时长都计入Stats的Latency，不论是否设置。
+func WithSlowCallThreshold(d time.Duration, onSlow func(key string, dur time.Duration, waiters int)) Option {
+	return func(g *Group) {
+		g.slowAfter, g.onSlow = d, onSlow
+	}
+}
+
+// slow 在刚刚完成的调用c的执行超过WithSlowCallThreshold时在新的协程中调用onSlow。调用者
+// 需要持有锁。
+func (g *Group) slow(key string, c *call) {
+	if g.onSlow == nil || g.slowAfter <= 0 || c.took <= g.slowAfter {
+		return
+	}
+	go g.onSlow(key, c.took, c.dups)
+}
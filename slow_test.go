@@ -0,0 +1,57 @@
+package timesf
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithSlowCallThreshold(t *testing.T) {
+	clock := newFakeClock()
+	type slowCall struct {
+		key     string
+		dur     time.Duration
+		waiters int
+	}
+	slow := make(chan slowCall, 1)
+	g := New(WithClock(clock), WithSlowCallThreshold(time.Second, func(key string, dur time.Duration, waiters int) {
+		slow <- slowCall{key, dur, waiters}
+	}))
+
+	g.Do("fast", time.Minute, func() (interface{}, error) {
+		clock.Advance(5 * time.Millisecond)
+		return 1, nil
+	})
+
+	started, release := make(chan struct{}), make(chan struct{})
+	ch := g.DoChan("slow", time.Minute, func() (interface{}, error) {
+		close(started)
+		<-release
+		clock.Advance(8 * time.Second)
+		return 1, nil
+	})
+	<-started
+	waiters := []<-chan Result{g.DoChan("slow", time.Minute, nil), g.DoChan("slow", time.Minute, nil)}
+	close(release)
+	<-ch
+	for _, w := range waiters {
+		<-w
+	}
+	select {
+	case got := <-slow:
+		if want := (slowCall{"slow", 8 * time.Second, 2}); got != want {
+			t.Errorf("onSlow = %+v; want %+v", got, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("onSlow was not called")
+	}
+	select {
+	case got := <-slow:
+		t.Errorf("unexpected onSlow %+v", got)
+	default:
+	}
+
+	want := [latencyBuckets]uint64{0, 1, 0, 0, 1, 0}
+	if got := g.Stats().Latency; got != want {
+		t.Errorf("Latency = %v; want %v", got, want)
+	}
+}
@@ -0,0 +1,199 @@
+package timesf
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// snapshotHeader 是Snapshot写入的数据的开头，用于识别格式和版本。
+const snapshotHeader = "timesf snapshot 1\n"
+
+// ErrInvalidSnapshot 在Restore读到的数据不是Snapshot写入的格式或者不完整时返回。
+var ErrInvalidSnapshot = errors.New("timesf: invalid snapshot")
+
+// Entry 是Export导出的一个已完成的结果，Data是编码之后的值。
+type Entry struct {
+	Key  string
+	Data []byte
+	// ExpiresAt 是结果的过期时间，永不过期时为零值。
+	ExpiresAt time.Time
+	// ComputedAt 是结果完成的时间，为零值时Import使用导入的时间。
+	ComputedAt time.Time
+}
+
+// expiry 返回e.ExpiresAt的纳秒时间戳，永不过期时为math.MaxInt64。
+func (e Entry) expiry() int64 {
+	if e.ExpiresAt.IsZero() {
+		return math.MaxInt64
+	}
+	return e.ExpiresAt.UnixNano()
+}
+
+// Export 返回所有已完成、还未过期并且没有错误的结果，连同其过期时间和完成时间，之后
+// 可以用Import导入，比如在进程重启之后。encode把每一个结果的值编码为字节，返回错误时
+// Export停止并返回此错误。正在调用中的key不会被导出。只在复制时持有锁，encode在释放锁
+// 之后调用。返回的切片是新分配的。
+func (g *Group) Export(encode func(key string, val interface{}) ([]byte, error)) ([]Entry, error) {
+	type entry struct {
+		key       string
+		val       interface{}
+		t, doneAt int64
+	}
+	g.mu.RLock()
+	now := g.now()
+	snap := make([]entry, 0, len(g.m))
+	for key, c := range g.m {
+		if t := g.t[key]; c.done && c.err == nil && t > now {
+			snap = append(snap, entry{key, c.val, t, c.doneAt})
+		}
+	}
+	g.mu.RUnlock()
+
+	entries := make([]Entry, 0, len(snap))
+	for _, e := range snap {
+		data, err := encode(e.key, e.val)
+		if err != nil {
+			return nil, fmt.Errorf("timesf: encode key %q: %w", e.key, err)
+		}
+		entry := Entry{Key: e.key, Data: data, ComputedAt: time.Unix(0, e.doneAt)}
+		if e.t != math.MaxInt64 {
+			entry.ExpiresAt = time.Unix(0, e.t)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// Import 将Export导出的结果写入Group，保持其原来的过期时间和完成时间，导入时已经过期的
+// 结果被跳过。key已经有正在进行的调用，或者有比导入的结果更新的已完成结果时保持不变；
+// 否则导入的结果替换已有的结果，并以EvictReplaced通知移除回调。decode把Data解码为结果
+// 的值，返回错误时Import停止并返回此错误，不写入任何结果。
+func (g *Group) Import(entries []Entry, decode func(key string, data []byte) (interface{}, error)) error {
+	vals := make([]interface{}, len(entries))
+	for i, e := range entries {
+		val, err := decode(e.Key, e.Data)
+		if err != nil {
+			return fmt.Errorf("timesf: decode key %q: %w", e.Key, err)
+		}
+		vals[i] = val
+	}
+
+	var evs []eviction
+	g.mu.Lock()
+	if g.m == nil {
+		g.m = make(map[string]*call)
+		g.t = make(map[string]int64)
+	}
+	now := g.now()
+	for i, e := range entries {
+		t := e.expiry()
+		if t <= now {
+			continue
+		}
+		doneAt := now
+		if !e.ComputedAt.IsZero() {
+			doneAt = e.ComputedAt.UnixNano()
+		}
+		old, ok := g.m[e.Key]
+		if ok && (!old.done || old.doneAt >= doneAt) {
+			continue
+		}
+		if ok {
+			evs = g.evict(evs, e.Key, old, EvictReplaced)
+		}
+		validTime := NoExpiration
+		if t != math.MaxInt64 {
+			validTime = time.Duration(t - doneAt)
+		}
+		c := &call{val: vals[i], done: true, startedAt: doneAt, doneAt: doneAt, params: params{validTime: validTime}}
+		g.m[e.Key] = c
+		g.t[e.Key] = g.capped(c, t)
+		g.unpublish(e.Key)
+		g.track(e.Key, old, c)
+		g.charge(c)
+		evs = g.trim(evs, c)
+	}
+	g.mu.Unlock()
+	g.notifyEvicted(evs)
+	return nil
+}
+
+// Snapshot 像Export方法，但是把导出的结果写入w，之后可以用Restore恢复。
+func (g *Group) Snapshot(w io.Writer, encode func(key string, val interface{}) ([]byte, error)) error {
+	entries, err := g.Export(encode)
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString(snapshotHeader)
+	var buf [binary.MaxVarintLen64]byte
+	writeBytes := func(b []byte) {
+		bw.Write(buf[:binary.PutUvarint(buf[:], uint64(len(b)))])
+		bw.Write(b)
+	}
+	writeInt := func(n int64) {
+		bw.Write(buf[:binary.PutVarint(buf[:], n)])
+	}
+	for _, e := range entries {
+		writeBytes([]byte(e.Key))
+		writeInt(e.expiry())
+		writeInt(e.ComputedAt.UnixNano())
+		writeBytes(e.Data)
+	}
+	return bw.Flush()
+}
+
+// Restore 读取Snapshot写入r的结果，并像Import方法一样写入Group。数据格式不对时返回
+// ErrInvalidSnapshot。
+func (g *Group) Restore(r io.Reader, decode func(key string, data []byte) (interface{}, error)) error {
+	br := bufio.NewReader(r)
+	header := make([]byte, len(snapshotHeader))
+	if _, err := io.ReadFull(br, header); err != nil || string(header) != snapshotHeader {
+		return ErrInvalidSnapshot
+	}
+	readBytes := func() ([]byte, error) {
+		n, err := binary.ReadUvarint(br)
+		if err != nil || n > math.MaxInt32 {
+			return nil, ErrInvalidSnapshot
+		}
+		b := make([]byte, n)
+		if _, err := io.ReadFull(br, b); err != nil {
+			return nil, ErrInvalidSnapshot
+		}
+		return b, nil
+	}
+	var entries []Entry
+	for {
+		if _, err := br.Peek(1); err == io.EOF {
+			break
+		}
+		key, err := readBytes()
+		if err != nil {
+			return err
+		}
+		t, err := binary.ReadVarint(br)
+		if err != nil {
+			return ErrInvalidSnapshot
+		}
+		doneAt, err := binary.ReadVarint(br)
+		if err != nil {
+			return ErrInvalidSnapshot
+		}
+		data, err := readBytes()
+		if err != nil {
+			return err
+		}
+		e := Entry{Key: string(key), Data: data, ComputedAt: time.Unix(0, doneAt)}
+		if t != math.MaxInt64 {
+			e.ExpiresAt = time.Unix(0, t)
+		}
+		entries = append(entries, e)
+	}
+	return g.Import(entries, decode)
+}
@@ -0,0 +1,156 @@
+package timesf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func encodeString(key string, val interface{}) ([]byte, error) {
+	return []byte(val.(string)), nil
+}
+
+func decodeString(key string, data []byte) (interface{}, error) {
+	return string(data), nil
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	clock := newFakeClock()
+	src := New(WithClock(clock), WithErrorCaching(true))
+	value := func(v string) func() (interface{}, error) {
+		return func() (interface{}, error) { return v, nil }
+	}
+	src.Do("short", time.Second, value("short"))
+	src.Do("long", time.Hour, value("long"))
+	src.Do("forever", NoExpiration, value("forever"))
+	src.Do("err", time.Hour, func() (interface{}, error) {
+		return nil, errors.New("some error")
+	})
+	release := make(chan struct{})
+	ch := src.DoChan("inflight", time.Hour, func() (interface{}, error) {
+		<-release
+		return "inflight", nil
+	})
+	defer func() {
+		close(release)
+		<-ch
+	}()
+
+	dst := New(WithClock(clock))
+	dst.Set("older", "stale", time.Hour)
+	clock.Advance(time.Millisecond)
+	src.Do("older", time.Hour, value("snapshot"))
+	src.Do("newer", time.Hour, value("snapshot"))
+	clock.Advance(time.Millisecond)
+	dst.Set("newer", "local", time.Hour)
+
+	var buf bytes.Buffer
+	if err := src.Snapshot(&buf, encodeString); err != nil {
+		t.Fatalf("Snapshot = %v", err)
+	}
+	clock.Advance(2 * time.Second)
+	if err := dst.Restore(&buf, decodeString); err != nil {
+		t.Fatalf("Restore = %v", err)
+	}
+
+	var got []string
+	dst.ForEach(func(key string, val interface{}, expiresAt time.Time) bool {
+		got = append(got, fmt.Sprintf("%s=%v", key, val))
+		return true
+	})
+	sort.Strings(got)
+	if want := "[forever=forever long=long newer=local older=snapshot]"; fmt.Sprint(got) != want {
+		t.Errorf("restored entries = %v; want %v", got, want)
+	}
+	srcTTL, _ := src.TTL("long")
+	if ttl, _ := dst.TTL("long"); ttl != srcTTL {
+		t.Errorf("restored TTL = %v; want %v", ttl, srcTTL)
+	}
+	if ttl, _ := dst.TTL("forever"); ttl != time.Duration(1<<63-1) {
+		t.Errorf("restored forever TTL = %v; want max", ttl)
+	}
+	if v, _, _ := dst.Do("long", time.Hour, nil); v != "long" {
+		t.Errorf("Do after Restore = %v; want long", v)
+	}
+}
+
+func TestRestoreErrors(t *testing.T) {
+	var g Group
+	if err := g.Restore(strings.NewReader("not a snapshot"), decodeString); err != ErrInvalidSnapshot {
+		t.Errorf("Restore garbage = %v; want ErrInvalidSnapshot", err)
+	}
+	g.Set("key", "val", time.Hour)
+	var buf bytes.Buffer
+	if err := g.Snapshot(&buf, encodeString); err != nil {
+		t.Fatalf("Snapshot = %v", err)
+	}
+	data := buf.Bytes()
+	if err := g.Restore(bytes.NewReader(data[:len(data)-1]), decodeString); err != ErrInvalidSnapshot {
+		t.Errorf("Restore truncated = %v; want ErrInvalidSnapshot", err)
+	}
+
+	someErr := errors.New("some error")
+	var dst Group
+	err := dst.Restore(bytes.NewReader(data), func(key string, data []byte) (interface{}, error) {
+		return nil, someErr
+	})
+	if !errors.Is(err, someErr) {
+		t.Errorf("Restore with failing decode = %v; want %v", err, someErr)
+	}
+	if n := dst.Len(); n != 0 {
+		t.Errorf("Len after failed Restore = %d; want 0", n)
+	}
+	err = g.Snapshot(&buf, func(key string, val interface{}) ([]byte, error) {
+		return nil, someErr
+	})
+	if !errors.Is(err, someErr) {
+		t.Errorf("Snapshot with failing encode = %v; want %v", err, someErr)
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	clock := newFakeClock()
+	src := New(WithClock(clock))
+	calls := 0
+	fn := func() (interface{}, error) {
+		calls++
+		return "bar", nil
+	}
+	src.Do("key", time.Minute, fn)
+	src.Do("forever", NoExpiration, fn)
+	src.Do("short", time.Second, fn)
+
+	entries, err := src.Export(encodeString)
+	if err != nil {
+		t.Fatalf("Export = %v", err)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	if len(entries) != 3 || entries[0].Key != "forever" || !entries[0].ExpiresAt.IsZero() ||
+		entries[1].Key != "key" || !entries[1].ExpiresAt.Equal(clock.Now().Add(time.Minute)) || string(entries[1].Data) != "bar" {
+		t.Fatalf("Export = %+v", entries)
+	}
+
+	clock.Advance(2 * time.Second)
+	dst := New(WithClock(clock))
+	if err := dst.Import(entries, decodeString); err != nil {
+		t.Fatalf("Import = %v", err)
+	}
+	for _, key := range []string{"key", "forever"} {
+		if v, err, _ := dst.Do(key, time.Minute, fn); v != "bar" || err != nil {
+			t.Errorf("Do(%q) after Import = %v, %v; want bar, nil", key, v, err)
+		}
+	}
+	if calls != 3 {
+		t.Errorf("fn called %d times; want 3", calls)
+	}
+	if ttl, _ := dst.TTL("key"); ttl != time.Minute-2*time.Second {
+		t.Errorf("TTL after Import = %v; want 58s", ttl)
+	}
+	if dst.Has("short") {
+		t.Error("expired entry was imported")
+	}
+}
@@ -0,0 +1,253 @@
+package timesf
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stats 是Group统计数据的快照。每一次Do或DoChan调用恰好记录为Hits、Coalesced和
+// Misses其中之一，被WithMaxWaiters拒绝的调用除外。
+type Stats struct {
+	Hits       uint64 `json:"hits"`       // 命中已完成并且有效的结果
+	Coalesced  uint64 `json:"coalesced"`  // 加入了正在进行的调用，共享等待其结果
+	Misses     uint64 `json:"misses"`     // 开启了一次新的调用
+	Errors     uint64 `json:"errors"`     // 方法返回了错误
+	Executions uint64 `json:"executions"` // 方法被执行的次数，包括后台刷新
+	Forgets    uint64 `json:"forgets"`    // 被遗忘的key的数量
+	Evictions  uint64 `json:"evictions"`  // 因为过期被替换或者因为错误被删除的结果数量
+	Debounced  uint64 `json:"debounced"`  // 执行方法之前等待了WithDebounce窗口的调用数量
+	Dropped    uint64 `json:"dropped"`    // 因为Notify的订阅者跟不上而被丢弃的事件数量
+
+	// Evicted 以EvictReason为下标，是每一种原因离开Group的结果数量，见WithOnEvict。
+	// JSON中是以原因的名称为键的对象，见MarshalJSON。
+	Evicted [evictReasons]uint64 `json:"evicted"`
+	// Latency 是方法执行时长的分布：Latency[i]是不超过LatencyBuckets[i]的执行次数，
+	// 最后一个桶是更长的执行次数，每一次执行只计入一个桶。JSON中是以桶的上限为键的
+	// 对象，最后一个桶的键为"+Inf"。
+	Latency [latencyBuckets]uint64 `json:"latency"`
+
+	Entries  int `json:"entries"`   // 当前记录的key数量
+	InFlight int `json:"in_flight"` // 当前正在执行的方法数量
+	Queued   int `json:"queued"`    // 当前等待WithMaxConcurrency空闲位置的方法数量
+}
+
+// statsJSON 是Stats的JSON形式。Evicted和Latency以名称为键，而不是数组的下标，新增的
+// 原因或者桶不会改变已有字段的含义。
+type statsJSON struct {
+	statsFields
+	Evicted map[string]uint64 `json:"evicted"`
+	Latency map[string]uint64 `json:"latency"`
+}
+
+// statsFields 和Stats有相同的字段，但是没有Stats的JSON方法。
+type statsFields Stats
+
+// latencyKey 返回Latency第i个桶在JSON中的键。
+func latencyKey(i int) string {
+	if i == len(LatencyBuckets) {
+		return "+Inf"
+	}
+	return LatencyBuckets[i].String()
+}
+
+// MarshalJSON 实现json.Marshaler，Evicted和Latency编码为以名称为键的对象，比如
+// {"expired":1,"forgotten":0,...}和{"1ms":3,...,"+Inf":0}。
+func (s Stats) MarshalJSON() ([]byte, error) {
+	j := statsJSON{statsFields: statsFields(s), Evicted: make(map[string]uint64, evictReasons), Latency: make(map[string]uint64, latencyBuckets)}
+	for i, n := range s.Evicted {
+		j.Evicted[EvictReason(i).String()] = n
+	}
+	for i, n := range s.Latency {
+		j.Latency[latencyKey(i)] = n
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON 实现json.Unmarshaler，解码MarshalJSON的输出，不认识的原因和桶被忽略。
+func (s *Stats) UnmarshalJSON(data []byte) error {
+	var j statsJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	*s = Stats(j.statsFields)
+	for i := range s.Evicted {
+		s.Evicted[i] = j.Evicted[EvictReason(i).String()]
+	}
+	for i := range s.Latency {
+		s.Latency[i] = j.Latency[latencyKey(i)]
+	}
+	return nil
+}
+
+// add 将st累加到s中。
+func (s *Stats) add(st Stats) {
+	s.Hits += st.Hits
+	s.Coalesced += st.Coalesced
+	s.Misses += st.Misses
+	s.Errors += st.Errors
+	s.Executions += st.Executions
+	s.Forgets += st.Forgets
+	s.Evictions += st.Evictions
+	s.Debounced += st.Debounced
+	s.Dropped += st.Dropped
+	for i, n := range st.Evicted {
+		s.Evicted[i] += n
+	}
+	for i, n := range st.Latency {
+		s.Latency[i] += n
+	}
+	s.Entries += st.Entries
+	s.InFlight += st.InFlight
+	s.Queued += st.Queued
+}
+
+// stats 保存Group的统计计数，全部使用原子操作，不需要持有锁。
+type stats struct {
+	hits       atomic.Uint64
+	coalesced  atomic.Uint64
+	misses     atomic.Uint64
+	errors     atomic.Uint64
+	executions atomic.Uint64
+	forgets    atomic.Uint64
+	evictions  atomic.Uint64
+	debounced  atomic.Uint64
+	dropped    atomic.Uint64
+	evicted    [evictReasons]atomic.Uint64
+	latency    [latencyBuckets]atomic.Uint64
+	inFlight   atomic.Int64
+	queued     atomic.Int64
+}
+
+// hitOrCoalesced 根据调用c是否已经完成，记录一次命中或者合并。调用者需要持有锁。
+func (s *stats) hitOrCoalesced(c *call) {
+	if c.done {
+		s.hits.Add(1)
+	} else {
+		s.coalesced.Add(1)
+	}
+}
+
+// execute 执行方法并记录执行次数和正在执行的数量。
+func (s *stats) execute(fn func() (interface{}, error)) (interface{}, error) {
+	s.executions.Add(1)
+	s.inFlight.Add(1)
+	defer s.inFlight.Add(-1)
+	v, err := fn()
+	if err != nil {
+		s.errors.Add(1)
+	}
+	return v, err
+}
+
+// LatencyBuckets 是Stats的Latency中每一个桶的上限，不能修改。
+var LatencyBuckets = [...]time.Duration{
+	time.Millisecond,
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+	10 * time.Second,
+}
+
+// latencyBuckets 是Latency的桶数，比LatencyBuckets多一个记录更长的执行。
+const latencyBuckets = len(LatencyBuckets) + 1
+
+// observe 将一次执行时长d计入分布。
+func (s *stats) observe(d time.Duration) {
+	i := 0
+	for i < len(LatencyBuckets) && d > LatencyBuckets[i] {
+		i++
+	}
+	s.latency[i].Add(1)
+}
+
+// loadLatency 读取执行时长的分布，reset为true时同时清零。
+func (s *stats) loadLatency(reset bool) (n [latencyBuckets]uint64) {
+	for i := range s.latency {
+		if reset {
+			n[i] = s.latency[i].Swap(0)
+		} else {
+			n[i] = s.latency[i].Load()
+		}
+	}
+	return n
+}
+
+// loadEvicted 读取每一种原因的移除次数，reset为true时同时清零。
+func (s *stats) loadEvicted(reset bool) (n [evictReasons]uint64) {
+	for i := range s.evicted {
+		if reset {
+			n[i] = s.evicted[i].Swap(0)
+		} else {
+			n[i] = s.evicted[i].Load()
+		}
+	}
+	return n
+}
+
+// Stats 返回Group当前统计数据的快照。
+func (g *Group) Stats() Stats {
+	return Stats{
+		Hits:       g.stats.hits.Load(),
+		Coalesced:  g.stats.coalesced.Load(),
+		Misses:     g.stats.misses.Load(),
+		Errors:     g.stats.errors.Load(),
+		Executions: g.stats.executions.Load(),
+		Forgets:    g.stats.forgets.Load(),
+		Evictions:  g.stats.evictions.Load(),
+		Debounced:  g.stats.debounced.Load(),
+		Dropped:    g.stats.dropped.Load(),
+		Evicted:    g.stats.loadEvicted(false),
+		Latency:    g.stats.loadLatency(false),
+		Entries:    g.Len(),
+		InFlight:   int(g.stats.inFlight.Load()),
+		Queued:     int(g.stats.queued.Load()),
+	}
+}
+
+// StatsAndReset 像Stats方法，但是在读取的同时将所有计数清零，Entries、InFlight和Queued
+// 是当前的状态，不受影响。每一个计数都是原子地读取并清零的，同时进行的调用记录的统计要么计入
+// 返回的快照，要么计入下一次的快照，不会丢失。不同计数之间不保证是同一时刻的快照。
+func (g *Group) StatsAndReset() Stats {
+	return Stats{
+		Hits:       g.stats.hits.Swap(0),
+		Coalesced:  g.stats.coalesced.Swap(0),
+		Misses:     g.stats.misses.Swap(0),
+		Errors:     g.stats.errors.Swap(0),
+		Executions: g.stats.executions.Swap(0),
+		Forgets:    g.stats.forgets.Swap(0),
+		Evictions:  g.stats.evictions.Swap(0),
+		Debounced:  g.stats.debounced.Swap(0),
+		Dropped:    g.stats.dropped.Swap(0),
+		Evicted:    g.stats.loadEvicted(true),
+		Latency:    g.stats.loadLatency(true),
+		Entries:    g.Len(),
+		InFlight:   int(g.stats.inFlight.Load()),
+		Queued:     int(g.stats.queued.Load()),
+	}
+}
+
+// ResetStats 将所有计数清零，见StatsAndReset。
+func (g *Group) ResetStats() {
+	g.StatsAndReset()
+}
+
+// publishMu 保证检查和注册expvar名称的原子性。
+var publishMu sync.Mutex
+
+// Publish 将Group的统计数据以name注册为expvar变量，值为Stats的JSON。name已经被注册
+// 时返回错误，而不是像expvar.Publish一样panic。
+func (g *Group) Publish(name string) error {
+	publishMu.Lock()
+	defer publishMu.Unlock()
+	if expvar.Get(name) != nil {
+		return fmt.Errorf("timesf: expvar %q is already published", name)
+	}
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return g.Stats()
+	}))
+	return nil
+}
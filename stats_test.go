@@ -0,0 +1,234 @@
+package timesf
+
+import (
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	var g Group
+	if s := g.Stats(); s != (Stats{}) {
+		t.Errorf("Stats of zero Group = %+v; want zero", s)
+	}
+
+	fn := func() (interface{}, error) {
+		return "bar", nil
+	}
+	g.Do("a", time.Second, fn)       // miss
+	g.Do("a", time.Second, fn)       // hit
+	<-g.DoChan("a", time.Second, fn) // hit
+
+	release := make(chan struct{})
+	ch := g.DoChan("b", time.Second, func() (interface{}, error) { // miss
+		<-release
+		return nil, errors.New("some error")
+	})
+	ch2 := g.DoChan("b", time.Second, fn) // coalesced
+	close(release)
+	<-ch
+	<-ch2
+
+	g.Forget("a")
+	want := Stats{Hits: 2, Coalesced: 1, Misses: 2, Errors: 1, Executions: 2, Forgets: 1, Evictions: 1, Evicted: [evictReasons]uint64{EvictForgotten: 1}}
+	s := g.Stats()
+	// 执行时长取决于调度，只检查每一次执行都计入了分布
+	var observed uint64
+	for _, n := range s.Latency {
+		observed += n
+	}
+	if observed != s.Executions {
+		t.Errorf("Latency = %v; want %d executions in total", s.Latency, s.Executions)
+	}
+	s.Latency = [latencyBuckets]uint64{}
+	if s != want {
+		t.Errorf("Stats = %+v; want %+v", s, want)
+	}
+}
+
+func TestStatsHammer(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithClock(clock))
+	var calls int32
+	release := make(chan struct{})
+	fn := func() (interface{}, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			<-release
+		}
+		return "bar", nil
+	}
+
+	const n = 100
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			g.Do("key", time.Minute, fn)
+		}()
+	}
+	waitFor(t, func() bool { return g.Stats().InFlight == 1 })
+	if s := g.Stats(); s.Entries != 1 {
+		t.Errorf("Entries while in flight = %d; want 1", s.Entries)
+	}
+	close(release)
+	wg.Wait()
+
+	s := g.Stats()
+	if total := s.Hits + s.Coalesced + s.Misses; total != n {
+		t.Errorf("Hits+Coalesced+Misses = %d; want %d (%+v)", total, n, s)
+	}
+	if s.Misses != 1 || s.Executions != 1 || s.InFlight != 0 || s.Entries != 1 {
+		t.Errorf("Stats = %+v; want one miss and execution, nothing in flight, one entry", s)
+	}
+}
+
+func TestStatsEvictions(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithClock(clock))
+	fn := func() (interface{}, error) {
+		return nil, nil
+	}
+	g.Do("key", time.Second, fn)
+	clock.Advance(time.Second)
+	g.Do("key", time.Second, fn)
+	g.Do("err", time.Second, func() (interface{}, error) {
+		return nil, errors.New("some error")
+	})
+	if s := g.Stats(); s.Evictions != 2 || s.Executions != 3 {
+		t.Errorf("Stats = %+v; want 2 evictions and 3 executions", s)
+	}
+}
+
+// publishSeq 让每一次运行TestPublish使用不同的expvar名称，expvar的名称不能注销。
+var publishSeq atomic.Int32
+
+func TestPublish(t *testing.T) {
+	prefix := fmt.Sprintf("%s_%d", t.Name(), publishSeq.Add(1))
+	name1, name2 := prefix+"_g1", prefix+"_g2"
+	var g1, g2 Group
+	if err := g1.Publish(name1); err != nil {
+		t.Fatalf("Publish g1 error = %v", err)
+	}
+	if err := g2.Publish(name2); err != nil {
+		t.Fatalf("Publish g2 error = %v", err)
+	}
+	if err := g2.Publish(name1); err == nil {
+		t.Errorf("Publish with a duplicate name returned nil error")
+	}
+
+	g1.Do("key", time.Second, func() (interface{}, error) {
+		return nil, nil
+	})
+	g1.Forget("key")
+	var s Stats
+	if err := json.Unmarshal([]byte(expvar.Get(name1).String()), &s); err != nil {
+		t.Fatalf("unmarshal published stats: %v", err)
+	}
+	want := Stats{Misses: 1, Executions: 1, Forgets: 1, Evicted: [evictReasons]uint64{EvictForgotten: 1}, Latency: s.Latency}
+	if s != want {
+		t.Errorf("published stats = %+v; want %+v", s, want)
+	}
+
+	// Evicted和Latency以名称为键
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(expvar.Get(name1).String()), &fields); err != nil {
+		t.Fatalf("unmarshal published fields: %v", err)
+	}
+	for _, name := range []string{"hits", "coalesced", "misses", "errors", "executions", "forgets", "evictions", "debounced", "dropped", "evicted", "latency", "entries", "in_flight", "queued"} {
+		if _, ok := fields[name]; !ok {
+			t.Errorf("published stats missing field %q", name)
+		}
+	}
+	var evicted, latency map[string]uint64
+	if err := json.Unmarshal(fields["evicted"], &evicted); err != nil {
+		t.Fatalf("unmarshal evicted: %v", err)
+	}
+	if err := json.Unmarshal(fields["latency"], &latency); err != nil {
+		t.Fatalf("unmarshal latency: %v", err)
+	}
+	if len(evicted) != evictReasons || evicted["forgotten"] != 1 || evicted["expired"] != 0 {
+		t.Errorf("evicted = %v; want every reason, forgotten 1", evicted)
+	}
+	var observed uint64
+	for i := range s.Latency {
+		if latency[latencyKey(i)] != s.Latency[i] {
+			t.Errorf("latency[%q] = %d; want %d", latencyKey(i), latency[latencyKey(i)], s.Latency[i])
+		}
+		observed += s.Latency[i]
+	}
+	if len(latency) != latencyBuckets || observed != 1 {
+		t.Errorf("latency = %v; want every bucket, one execution in total", latency)
+	}
+
+	var s2 Stats
+	if err := json.Unmarshal([]byte(expvar.Get(name2).String()), &s2); err != nil {
+		t.Fatalf("unmarshal published g2 stats: %v", err)
+	}
+	if s2 != (Stats{}) {
+		t.Errorf("published g2 stats = %+v; want zero", s2)
+	}
+}
+
+func TestStatsAndReset(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithClock(clock))
+	fn := func() (interface{}, error) {
+		return "bar", nil
+	}
+
+	const workers, ops = 8, 2000
+	var wg sync.WaitGroup
+	var sum Stats
+	done := make(chan struct{})
+	sampled := make(chan struct{})
+	go func() {
+		defer close(sampled)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			s := g.StatsAndReset()
+			sum.Hits += s.Hits
+			sum.Coalesced += s.Coalesced
+			sum.Misses += s.Misses
+			sum.Executions += s.Executions
+			clock.Advance(time.Millisecond)
+		}
+	}()
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < ops; j++ {
+				g.Do(string(rune('a'+(i+j)%4)), time.Millisecond, fn)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(done)
+	<-sampled
+	s := g.StatsAndReset()
+	sum.Hits += s.Hits
+	sum.Coalesced += s.Coalesced
+	sum.Misses += s.Misses
+	sum.Executions += s.Executions
+
+	if total := sum.Hits + sum.Coalesced + sum.Misses; total != workers*ops {
+		t.Errorf("summed Hits+Coalesced+Misses = %d; want %d (%+v)", total, workers*ops, sum)
+	}
+	if sum.Executions != sum.Misses {
+		t.Errorf("summed Executions = %d; want Misses %d", sum.Executions, sum.Misses)
+	}
+	g.ResetStats()
+	if s := g.Stats(); s.Hits != 0 || s.Misses != 0 || s.Entries != 4 {
+		t.Errorf("Stats after ResetStats = %+v; want zero counters, 4 entries", s)
+	}
+}
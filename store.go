@@ -0,0 +1,100 @@
+package timesf
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Store 是Group背后可选的共享存储，比如Redis，让多个进程可以共享同一个key的结果，
+// 见WithStore。expiry为零值表示永不过期。
+type Store interface {
+	// Get 返回key保存的值和过期时间，ok为false表示不存在。
+	Get(ctx context.Context, key string) (val []byte, expiry time.Time, ok bool, err error)
+	// Set 保存key的值，直到expiry过期。
+	Set(ctx context.Context, key string, val []byte, expiry time.Time) error
+}
+
+// storeConfig 是WithStore设置的存储和编解码方法，或者WithL2设置的第二级缓存。
+type storeConfig struct {
+	s         Store
+	marshal   func(val interface{}) ([]byte, error)
+	unmarshal func(data []byte) (interface{}, error)
+	l2        L2
+}
+
+// WithStore 在Group中的结果缺失时，先从s中查找：找到时使用其值和过期时间，而不执行
+// 方法；找不到时执行方法，并把成功的结果以相同的过期时间写回s。marshal和unmarshal在
+// 值和s中的字节之间转换。s或者编解码返回错误时只输出日志，像没有设置s一样执行方法，
+// 不会返回给调用者。同一个进程中仍然只有一个调用者访问s和执行方法，返回错误的结果不会
+// 写入s。DoMulti不使用s。
+func WithStore(s Store, marshal func(val interface{}) ([]byte, error), unmarshal func(data []byte) (interface{}, error)) Option {
+	return func(g *Group) {
+		g.store = &storeConfig{s: s, marshal: marshal, unmarshal: unmarshal}
+	}
+}
+
+// load 从存储中查找key，找到并且还未过期时将其作为调用c的结果，并记录c.expiresAt。
+// 只由执行方法的协程调用。
+func (g *Group) load(ctx context.Context, c *call, key string) bool {
+	if g.store.l2 != nil {
+		return g.loadL2(c, key)
+	}
+	data, expiry, ok, err := g.store.s.Get(ctx, key)
+	if err != nil {
+		g.logStore("get", key, err)
+		return false
+	}
+	if !ok {
+		return false
+	}
+	t := int64(math.MaxInt64)
+	if !expiry.IsZero() {
+		if t = expiry.UnixNano(); t <= g.now() {
+			return false
+		}
+	}
+	val, err := g.store.unmarshal(data)
+	if err != nil {
+		g.logStore("unmarshal", key, err)
+		return false
+	}
+	c.val, c.err, c.expiresAt = val, nil, t
+	return true
+}
+
+// save 将调用c成功的结果写回存储，ttl是run得到的有效时长，不应该被缓存的结果不会被
+// 写入，包括WithNilCaching(false)时的nil值，否则之后的调用者会从存储中读到它。写入时
+// 记录c.expiresAt，使Group和存储中的过期时间相同。只由执行方法的协程调用。
+func (g *Group) save(ctx context.Context, c *call, key string, ttl time.Duration) {
+	if c.ttlFn != nil && ttl <= 0 && ttl != NoExpiration || g.uncached(ttl) || c.val == nil && g.skipNil {
+		return
+	}
+	if g.store.l2 != nil {
+		g.saveL2(c, key, ttl)
+		return
+	}
+	data, err := g.store.marshal(c.val)
+	if err != nil {
+		g.logStore("marshal", key, err)
+		return
+	}
+	g.mu.Lock()
+	t := g.getValidTime(ttl)
+	g.mu.Unlock()
+	var expiry time.Time
+	if t != math.MaxInt64 {
+		expiry = time.Unix(0, t)
+	}
+	c.expiresAt = t
+	if err := g.store.s.Set(ctx, key, data, expiry); err != nil {
+		g.logStore("set", key, err)
+	}
+}
+
+// logStore 在设置了日志时输出访问存储的错误。
+func (g *Group) logStore(op, key string, err error) {
+	if g.logger != nil {
+		g.logger.Logf("timesf: store %s for key %q failed: %v", op, key, err)
+	}
+}
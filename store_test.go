@@ -0,0 +1,150 @@
+package timesf
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memStore 是保存在内存中的Store，用于测试。
+type memStore struct {
+	mu   sync.Mutex
+	m    map[string]memItem
+	err  error
+	gets int
+}
+
+type memItem struct {
+	data   []byte
+	expiry time.Time
+}
+
+func (s *memStore) Get(ctx context.Context, key string) ([]byte, time.Time, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gets++
+	if s.err != nil {
+		return nil, time.Time{}, false, s.err
+	}
+	it, ok := s.m[key]
+	return it.data, it.expiry, ok, nil
+}
+
+func (s *memStore) Set(ctx context.Context, key string, val []byte, expiry time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	if s.m == nil {
+		s.m = make(map[string]memItem)
+	}
+	s.m[key] = memItem{val, expiry}
+	return nil
+}
+
+func marshalString(val interface{}) ([]byte, error) {
+	return []byte(val.(string)), nil
+}
+
+func unmarshalString(data []byte) (interface{}, error) {
+	return string(data), nil
+}
+
+func TestWithStore(t *testing.T) {
+	clock := newFakeClock()
+	var store memStore
+	a := New(WithClock(clock), WithStore(&store, marshalString, unmarshalString))
+	b := New(WithClock(clock), WithStore(&store, marshalString, unmarshalString))
+	calls := 0
+	fn := func() (interface{}, error) {
+		calls++
+		return "bar", nil
+	}
+
+	if v, err, _ := a.Do("key", time.Hour, fn); v != "bar" || err != nil {
+		t.Fatalf("first Do = %v, %v; want bar, nil", v, err)
+	}
+	it := store.m["key"]
+	if string(it.data) != "bar" || !it.expiry.Equal(clock.Now().Add(time.Hour)) {
+		t.Errorf("stored item = %q, %v; want bar, %v", it.data, it.expiry, clock.Now().Add(time.Hour))
+	}
+
+	clock.Advance(time.Minute)
+	if v, err, _ := b.Do("key", time.Hour, fn); v != "bar" || err != nil {
+		t.Errorf("Do on second group = %v, %v; want bar, nil", v, err)
+	}
+	if calls != 1 {
+		t.Errorf("fn called %d times; want 1", calls)
+	}
+	if ttl, _ := b.TTL("key"); ttl != 59*time.Minute {
+		t.Errorf("TTL from store = %v; want 59m", ttl)
+	}
+
+	a.Do("forever", NoExpiration, fn)
+	if it := store.m["forever"]; !it.expiry.IsZero() {
+		t.Errorf("stored expiry for NoExpiration = %v; want zero", it.expiry)
+	}
+	a.Do("err", time.Hour, func() (interface{}, error) {
+		return nil, errors.New("some error")
+	})
+	if _, ok := store.m["err"]; ok {
+		t.Error("errored result was written to store")
+	}
+
+	clock.Advance(time.Hour)
+	b.Do("key", time.Hour, fn)
+	if calls != 3 {
+		t.Errorf("fn called %d times after store expiry; want 3", calls)
+	}
+}
+
+func TestWithStoreErrors(t *testing.T) {
+	var logs logRecorder
+	store := memStore{err: errors.New("store down")}
+	g := New(WithStore(&store, marshalString, unmarshalString), WithLogger(&logs))
+	v, err, _ := g.Do("key", time.Hour, func() (interface{}, error) {
+		return "bar", nil
+	})
+	if v != "bar" || err != nil {
+		t.Errorf("Do with failing store = %v, %v; want bar, nil", v, err)
+	}
+	if v, _, _ := g.Peek("key"); v != "bar" {
+		t.Errorf("Peek = %v; want bar", v)
+	}
+	got := logs.take()
+	for _, want := range []string{
+		`timesf: store get for key "key" failed: store down`,
+		`timesf: store set for key "key" failed: store down`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("logs = %q; missing %q", got, want)
+		}
+	}
+}
+
+func TestWithStoreSkipsNil(t *testing.T) {
+	var store memStore
+	marshal := func(val interface{}) ([]byte, error) {
+		if val == nil {
+			return nil, nil
+		}
+		return marshalString(val)
+	}
+	g := New(WithStore(&store, marshal, unmarshalString), WithNilCaching(false))
+	calls := 0
+	fn := func() (interface{}, error) {
+		calls++
+		return nil, nil
+	}
+	g.Do("key", time.Hour, fn)
+	if _, ok := store.m["key"]; ok {
+		t.Error("nil result was written to store with WithNilCaching(false)")
+	}
+	if v, _, _ := g.Do("key", time.Hour, fn); v != nil || calls != 2 {
+		t.Errorf("second Do = %v (calls %d); want nil, 2 calls", v, calls)
+	}
+}
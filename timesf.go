@@ -5,16 +5,20 @@
 package timesf
 
 import (
+	"container/list"
+	"context"
+	"errors"
 	"math"
+	"math/rand"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // call 是单飞的调用
 type call struct {
-	wg sync.WaitGroup
-
-	// 结果值和错误，只有当等待组完成后，只会写一次。
+	// 结果值和错误，在调用完成之前只会写一次。
 	val interface{}
 	err error
 
@@ -22,63 +26,725 @@ type call struct {
 	// 此标签来辨别次
 	forgotten bool
 
+	// done 标识调用是否已经完成，只有拿到锁时才进行读写。完成后的调用会保留在
+	// map中，直到过期或者被遗忘。
+	done bool
+	// startedAt 是调用开始的时间，创建之后不再改变。doneAt 是调用完成的时间，只有
+	// 拿到锁时才进行读写。
+	startedAt int64
+	doneAt    int64
+
+	// refreshing 是正在后台刷新此结果的调用，没有刷新时为nil。graceUntil 是刷新失败
+	// 之后仍然可以返回旧值的截止时间，见WithStaleIfError。两者只有拿到锁时才进行读写。
+	refreshing *call
+	graceUntil int64
+	// expiredEarly 标识结果被ExpireNow提前过期，在新的结果替换它之前仍然被保留，只有
+	// 拿到锁时才进行读写。
+	expiredEarly bool
+
 	// 重复数量和管道，在等待组还没完成之前，这两个字段在单飞的进行中，当拿到
-	// 锁时进行读和写操作。拿到锁之后，这两个字段将只读不写。
+	// 锁时进行读和写操作。拿到锁之后，这两个字段将只读不写；完成时chans被置为nil。
 	dups  int
 	chans []chan<- Result
+
+	// final 是调用完成时的结果，在ready被关闭之前写入，之后不再改变。
+	final Result
+
+	// ready 在调用完成或者被ForgetAndNotify中止时被关闭，有调用者等待时才会被创建，
+	// 见readyChan。aborted 标识调用被中止，等待者拿到ErrForgotten。两者只有拿到锁时
+	// 才进行读写，ready被关闭之后aborted不再改变。
+	ready   chan struct{}
+	aborted bool
+
+	// elem 是此调用在Group的LRU列表中的位置，没有设置容量时为nil。
+	elem *list.Element
+	// cost 是计入Group总开销的此结果的开销，见WithMaxCost。
+	cost int64
+	// retried 标识方法按照WithRetry进行了重试，canceled 标识执行方法时ctx被取消，
+	// 结果不会被缓存。两者只由执行方法的协程在完成之前写入。
+	retried  bool
+	canceled bool
+	// rejected 标识方法因为WithMaxConcurrency的限制没有被执行，结果不会被缓存。只由
+	// 执行方法的协程在完成之前写入。
+	rejected bool
+	// expiresAt 不为0时是结果来自或者写入共享存储时的过期时间，完成时代替有效时长，
+	// 见WithStore。只由执行方法的协程在完成之前写入。
+	expiresAt int64
+	// hits 是命中此结果的次数，lastHit 是最近一次命中的时间，都使用原子操作，无锁的读取
+	// 路径上同样会更新。took 是方法的执行时长，只由执行方法的协程在完成之前写入。见KeyStats。
+	hits    atomic.Uint64
+	lastHit atomic.Int64
+	took    time.Duration
+	// hot 标识已经因为重复调用者超过WithHotKeyThreshold触发了OnHotKey。
+	hot bool
+	// next 不为nil时是因为WithoutSharedErrors接替此调用的调用，在完成之前写入，等待者在
+	// 完成之后改为等待next。promotions 是已经接替的次数。
+	next       *call
+	promotions int
+	// seeds 是seedFn返回的其他key的结果，只由执行方法的协程在完成之前写入。
+	seeds map[string]interface{}
+	// resultVersion 是versionFn返回的结果的版本，revalidated 标识方法返回了ErrNotModified，
+	// 结果沿用了kept的值，两者只由执行方法的协程在完成之前写入。kept 是被替换的上一次的
+	// 结果，在创建调用时写入，执行方法时清除，见DoIfChanged和DoOrDefault。
+	resultVersion string
+	revalidated   bool
+	kept          *call
+	// notBefore 不为0时方法在此时间之前不会被执行，见WithMinInterval和WithDebounce。
+	notBefore int64
+	// hurry 不为nil时是WithDebounce的窗口，关闭时方法立即执行，见Hurry。在创建调用时
+	// 写入，只有拿到锁时才关闭，hurried 标识已经关闭。
+	hurry   chan struct{}
+	hurried bool
+	// endSpan 不为nil时在调用完成之后结束Tracer开始的span，只由执行方法的协程读写。
+	endSpan func(shared bool, err error)
+	// fnCtx 是传给ctxFn的上下文，cancel 将其取消，见ForgetAndCancel。在创建调用时
+	// 写入，完成时清除，只有拿到锁时才进行读写。
+	fnCtx  context.Context
+	cancel context.CancelFunc
+	// interest 是DoCtx开始的调用仍然在等待结果的调用者数量，包括执行方法的调用者，降为0
+	// 时取消fnCtx，abandoned 是最后离开的调用者拿到的错误，结果不会被缓存。其他调用的
+	// interest为0，不计数。只有拿到锁时才进行读写，见letGo。
+	interest  int
+	abandoned error
+
+	// chanOwner 标识chans[0]属于开始此调用的DoChan调用者。
+	chanOwner bool
+	// progress 是接收方法报告的进度的DoChanProgress通道，只有拿到锁时才进行读写，完成时
+	// 清除。
+	progress []chan<- Update
+
+	// fn 和 params 是产生此结果的方法和参数，用于刷新。
+	fn func() (interface{}, error)
+	params
+}
+
+// params 是一次调用的参数。
+type params struct {
+	// validTime 是结果的有效时长，staleFor 是过期之后仍然可以返回旧值的时长。
+	validTime time.Duration
+	staleFor  time.Duration
+	// fresh 为true时调用者不接受过期的结果，见DoFresh。
+	fresh bool
+
+	// hasErrorTTL 为true时，返回错误的结果使用errorTTL作为有效时长，而不是Group的
+	// 配置，errorTTL为0表示不缓存错误。
+	errorTTL    time.Duration
+	hasErrorTTL bool
+
+	// maxWait 大于0时，重复的调用者最多等待正在进行的调用这么长时间，见DoWithWait。
+	maxWait time.Duration
+	// ownerWait 为true时，执行方法的调用者同样最多等待maxWait，见DoTimeout。
+	ownerWait bool
+	// noWait 为true时，调用者不等待正在进行的调用，见TryDo。force 为true时调用者不使用
+	// 已有的结果，也不加入正在进行的调用，总是开始新的调用，见DoRefresh。
+	noWait bool
+	force  bool
+
+	// ttlFn 不为nil时代替fn被执行，结果的有效时长由其返回值决定，见DoWithTTL。
+	ttlFn func() (interface{}, time.Duration, error)
+
+	// seedFn 不为nil时代替fn被执行，同时返回其他key的结果，见DoWithSeeds。
+	seedFn func() (interface{}, map[string]interface{}, error)
+
+	// expireAt 不为0时是结果的过期时间，代替有效时长，见DoUntil。
+	expireAt int64
+
+	// fallback 为true时方法返回错误使用被替换的过期结果的值，见DoOrDefault。
+	fallback bool
+
+	// versionFn 不为nil时代替fn被执行，version是调用者已知的版本，见DoIfChanged。
+	versionFn func(lastVersion string) (interface{}, string, error)
+	version   string
+
+	// ctxFn 不为nil时代替fn被执行，ctx是调用者的上下文：执行方法时传给ctxFn，等待时
+	// 被取消则不再等待，见DoCtx。后台刷新不使用ctx。
+	ctxFn func(ctx context.Context) (interface{}, error)
+	ctx   context.Context
 }
 
-// Group 标识一个工作类，并且进行管理命名空间。其包含调用结果和获得调用结果的毫秒
+// Group 标识一个工作类，并且进行管理命名空间。其包含调用结果和调用结果有效期的纳秒
 // 时间戳。其可以进行对重复请求的抑制。
 type Group struct {
-	mu sync.Mutex       // protects m
+	mu sync.RWMutex     // protects m; read-only accessors take the read lock
 	m  map[string]*call // lazily initialized
 	t  map[string]int64 // valid time
+
+	// defaultTTL 见WithDefaultTTL。
+	defaultTTL time.Duration
+
+	// refreshAhead 见WithRefreshAhead，refreshBefore 见WithRefreshBefore，都为0时
+	// 不进行提前刷新。
+	refreshAhead  float64
+	refreshBefore time.Duration
+
+	// cacheErrors 和 errorTTL 见WithErrorCaching和WithErrorTTL。
+	cacheErrors bool
+	errorTTL    time.Duration
+	// skipNil 为true时不缓存值为nil的成功结果，见WithNilCaching。
+	skipNil bool
+	// noStaleFallback 见WithStaleFallback。
+	noStaleFallback bool
+	// staleIfError 见WithStaleIfError。
+	staleIfError time.Duration
+	// slowAfter 和onSlow 见WithSlowCallThreshold。
+	slowAfter time.Duration
+	onSlow    func(key string, dur time.Duration, waiters int)
+	// deps 记录每个key被哪些key依赖，见AddDependency。
+	deps map[string]map[string]struct{}
+
+	// clock 见WithClock，为nil时使用系统时间。
+	clock Clock
+
+	// subs 是Sub创建的Namespace，只有拿到锁时才进行读写。
+	subs map[string]*Namespace
+
+	// watchers 是Notify的订阅者，同时拿到mu和watchMu时才进行修改，持有任意一个时可以读取。
+	// 发送事件时持有watchMu的读锁，保证通道不会同时被关闭。
+	watchMu  sync.RWMutex
+	watchers []chan ExpiryEvent
+
+	// onEvict 见WithOnEvict。
+	onEvict func(key string, val interface{}, reason EvictReason)
+
+	// capacity 见WithCapacity，lru 按访问时间记录key，最近访问的在前面。
+	capacity int
+	lru      *list.List
+
+	// maxCost 和 costFn 见WithMaxCost，cost 是已完成结果的总开销。
+	maxCost int64
+	costFn  func(val interface{}) int64
+	cost    int64
+
+	// janitor 见WithJanitor，为nil时不进行后台清理。
+	janitor *janitor
+	// hooks 见WithHooks，为nil时不调用任何钩子。
+	hooks *Hooks
+	// logger 见WithLogger，为nil时不输出日志。
+	logger Logger
+
+	// jitter 见WithJitter。rand 返回[0, 1)之间的随机数，为nil时使用math/rand，
+	// 测试中可以替换。
+	jitter float64
+	rand   func() float64
+
+	// strictTTL 见WithStrictTTL，sliding 见WithSlidingExpiration。
+	strictTTL bool
+	sliding   bool
+
+	// maxAge 见WithMaxAge，为0时不限制。coalesceWindow 见WithCoalesceWindow。
+	maxAge         time.Duration
+	coalesceWindow time.Duration
+
+	// retry 见WithRetry，为nil时不重试。
+	retry *retryPolicy
+
+	// maxWaiters 见WithMaxWaiters，为0时不限制。
+	maxWaiters int
+
+	// observer 见WithObserver，为nil时不进行观测。
+	observer Observer
+	// tracer 见WithTracer。
+	tracer Tracer
+
+	// breaker 见WithCircuitBreaker，为nil时不进行熔断。breakers 记录每一个key的熔断器，
+	// 只有失败过的key才有记录。
+	breaker  *breakerPolicy
+	breakers map[string]*breaker
+
+	// minInterval 见WithMinInterval，为0时不限制。computed 记录每一个key最近一次完成的
+	// 调用，只保留间隔之内的记录。
+	minInterval     time.Duration
+	minIntervalWait bool
+	computed        map[string]*call
+
+	// debounce 见WithDebounce，为0时新调用立即执行方法。
+	debounce time.Duration
+
+	// pending 是g.m中还没有完成的调用数量，见LenByState。
+	pending int
+
+	// forgetMode 见WithForgetMode。
+	forgetMode ForgetMode
+	// releaseFuncs 见WithReleaseFuncs。
+	releaseFuncs bool
+	// ratio 见WithHitRatioWindow，为nil时HitRatio使用累计的统计数据。
+	ratio *hitWindow
+	// forgetGrace 见WithForgetGrace，tombs 是被遗忘的调用的墓碑。
+	forgetGrace time.Duration
+	tombs       map[string]tomb
+
+	// noSharedErrors 见WithoutSharedErrors。
+	noSharedErrors bool
+
+	// hotKeyThreshold 见WithHotKeyThreshold，为0时不检查。
+	hotKeyThreshold int
+
+	// store 见WithStore，为nil时不使用共享存储。
+	store *storeConfig
+
+	// loader 见WithLoader，为nil时Get返回ErrNoLoader。
+	loader func(key string) (interface{}, error)
+
+	// keyFn 见WithKeyFunc，为nil时直接使用调用者的key。
+	keyFn func(key string) string
+
+	// classifier 见WithErrorClassifier，为nil时按照cacheErrors和errorTTL处理错误。
+	classifier func(err error) (cacheable bool, ttl time.Duration)
+
+	// wrapErrors 见WithWrapErrors。
+	wrapErrors bool
+
+	// copier 见WithCopier，为nil时所有调用者共享同一个值。
+	copier func(v interface{}) interface{}
+
+	// sem 见WithMaxConcurrency，为nil时不限制，failFast 为true时不等待空闲的位置。
+	sem      chan struct{}
+	failFast bool
+	// priority 见WithComputePriority，admission 是按照优先级排队的方法。
+	priority  func(key string) int
+	admission admission
+
+	// running 是正在执行方法的调用数量，idle 在running变为0时被关闭，有调用者在Wait
+	// 时才会被创建。refused 不为nil时不再开始新的调用，返回此错误，见Freeze。
+	running int
+	idle    chan struct{}
+	refused error
+
+	stats stats
+
+	// fast 保存可以不加锁返回的命中结果，键是key，值是*fastHit。只有在持有锁时才会
+	// 写入或者删除，读取不需要锁，见publish。
+	fast sync.Map
 }
 
+// NoExpiration 作为有效时长时表示结果永不过期。
+const NoExpiration time.Duration = -1
+
 // Result 保存DO方法的结果，因此Do方法可以通过管道来进行传输。
 type Result struct {
-	Val    interface{}
-	Err    error
+	Val interface{}
+	Err error
+	// Shared 标识此结果被交给了不止一个调用者，对Do和DoChan系列方法的含义相同：等待
+	// 其他调用者开始的调用和命中已完成的结果时总是为true；开始调用的调用者只有在调用
+	// 完成之前有其他调用者加入时才为true，之后才命中此结果的调用者不会改变它已经拿到的值。
 	Shared bool
+
+	// ComputedAt 是产生此结果的方法完成的时间，之后命中同一个结果时保持不变。
+	ComputedAt time.Time
+
+	// Forgotten 标识key在调用完成之前被遗忘了，比如被Forget或者Set替换，结果可能已经
+	// 过时，也不会被缓存。只在调用完成时发送给DoChan通道的结果中设置。
+	Forgotten bool
+
+	// 以下字段只由DoDetailed、DoChan和DoMulti设置。Dups 是共享此次执行或者缓存结果的
+	// 重复调用者数量，无锁路径上的命中不再增加此数量。ExpiresAt 是结果的过期时间，永不
+	// 过期时为零值，不会被缓存的结果为完成的时间。HitAge 是返回时结果已经产生的时长，
+	// 使用Group的时钟计算，执行方法和等待其完成的调用者为0。
+	Dups      int
+	ExpiresAt time.Time
+	HitAge    time.Duration
+
+	// Revalidated 标识DoIfChanged的方法报告结果没有变化，结果沿用了上一次的值，见
+	// ErrNotModified。
+	Revalidated bool
+
+	// Role 标识调用者是如何拿到此结果的：开始了执行方法的调用、等待了其他调用者开始的
+	// 调用，还是命中了已经完成的结果。没有拿到调用的结果时为RoleNone。
+	Role Role
+
+	// from 是产生此结果的调用，用来确认key的结果仍然是调用者拿到的那一个，见DoValidated。
+	from *call
+}
+
+// Age 返回结果产生至今的时长，使用系统时间计算。
+func (r Result) Age() time.Duration {
+	return time.Since(r.ComputedAt)
+}
+
+// result 返回调用c已完成的结果，shared的含义和Result.Shared相同。调用者需要持有锁，
+// 或者已经确认调用完成。
+func (c *call) result(shared bool) Result {
+	return Result{Val: c.val, Err: c.err, Shared: shared, ComputedAt: time.Unix(0, c.doneAt), Revalidated: c.revalidated, from: c}
+}
+
+// waited 返回等待调用c完成的调用者拿到的结果，调用者需要已经确认调用完成。
+func (c *call) waited() Result {
+	r := c.final
+	r.Shared = true
+	r.Role = RoleWaiter
+	return r
+}
+
+// hitResult 返回命中已完成的调用c时的结果，t是其过期时间。调用者需要持有锁。
+func (c *call) hitResult(t, now int64) Result {
+	c.accessed(now)
+	return c.cachedResult(t, now)
+}
+
+// cachedResult 像hitResult方法，但是不记录命中，调用者需要持有锁。
+func (c *call) cachedResult(t, now int64) Result {
+	r := c.result(true)
+	r.Dups = c.dups
+	r.ExpiresAt = expiryTime(t)
+	r.HitAge = time.Duration(now - c.doneAt)
+	r.Role = RoleCachedHit
+	return r
+}
+
+// expiryTime 将过期时间的纳秒时间戳转换为time.Time，永不过期时为零值。
+func expiryTime(t int64) time.Time {
+	if t == math.MaxInt64 {
+		return time.Time{}
+	}
+	return time.Unix(0, t)
 }
 
 // Do 方法执行并返回其方法的结果，确保针对一个key在同一时间只有一次调用。如果有重复的
 // 请求过来，重复请求的调用者将进行等待第一个调用者的结果返回，并得到相同的结果。shared变量
 // 标识此次调用是否此次的结果在多个接受者之间进行了共享。
 func (g *Group) Do(key string, validTime time.Duration, fn func() (interface{}, error)) (v interface{}, err error, shared bool) {
+	v, err, shared, _ = g.DoStale(key, validTime, 0, fn)
+	return v, err, shared
+}
+
+// DoDetailed 像Do方法，但是返回完整的Result，包括Dups、ExpiresAt和HitAge等信息。
+func (g *Group) DoDetailed(key string, validTime time.Duration, fn func() (interface{}, error)) Result {
+	r, _, _ := g.do(key, params{validTime: validTime}, fn)
+	return r
+}
+
+// DoDefault 像Do方法，但是使用Group默认的有效时长，见WithDefaultTTL。
+func (g *Group) DoDefault(key string, fn func() (interface{}, error)) (v interface{}, err error, shared bool) {
+	return g.Do(key, g.defaultTTL, fn)
+}
+
+// DoStale 像Do方法，但是结果过期之后的staleFor时长内，调用者会立即拿到旧的结果，
+// stale变量标识返回的是过期的结果。与此同时只有一个协程在后台执行方法进行刷新，刷新
+// 成功后替换结果并重新计算有效期；刷新失败时继续返回旧的结果，直到staleFor耗尽后
+// 调用者才像Do方法一样进行等待。只有没有错误的结果才会在过期后被返回。
+func (g *Group) DoStale(key string, validTime, staleFor time.Duration, fn func() (interface{}, error)) (v interface{}, err error, shared, stale bool) {
+	r, stale, _ := g.do(key, params{validTime: validTime, staleFor: staleFor}, fn)
+	return r.Val, r.Err, r.Shared, stale
+}
+
+// DoFresh 像DoStale方法，使用相同的有效时长和旧值时长，但是此调用者不接受过期的结果：
+// 结果过期之后、staleFor耗尽之前，DoStale的调用者仍然立即拿到旧的结果，而DoFresh的调用者
+// 开始或者等待后台的刷新，拿到刷新的结果；刷新失败时拿到其错误，旧的结果继续保留。
+// staleFor耗尽之后两者都像Do方法一样重新执行方法。
+func (g *Group) DoFresh(key string, validTime, staleFor time.Duration, fn func() (interface{}, error)) (v interface{}, err error, shared bool) {
+	r, _, _ := g.do(key, params{validTime: validTime, staleFor: staleFor, fresh: true}, fn)
+	return r.Val, r.Err, r.Shared
+}
+
+// DoRefresh 像Do方法，但是不使用key已经缓存的结果，总是开始一次新的执行，适用于调用者
+// 刚刚修改了数据、知道缓存的结果已经过时的情况。检查和开始新的调用在同一次持有锁时完成，
+// DoRefresh开始之后到达的Do调用者共享这次执行，不会拿到之前的结果。同时到达的DoRefresh
+// 调用者同样共享正在进行的DoRefresh开始的执行，避免一次写入之后的大量重新计算。key其他
+// 正在进行的调用可能在修改之前就已经读取了数据，因此不会被加入，而是像Forget一样被取代：
+// 其等待者仍然拿到它的结果，但是结果不会被缓存。WithMinInterval的限制仍然有效，但是不会
+// 返回之前的结果，而是等待到允许执行的时间。
+func (g *Group) DoRefresh(key string, validTime time.Duration, fn func() (interface{}, error)) (v interface{}, err error, shared bool) {
+	r, _, _ := g.do(key, params{validTime: validTime, force: true}, fn)
+	return r.Val, r.Err, r.Shared
+}
+
+// DoWithTTLs 像Do方法，但是成功的结果使用successTTL作为有效时长，返回错误的结果使用
+// errorTTL作为有效时长，errorTTL为0表示不缓存错误。errorTTL会覆盖Group对错误缓存的
+// 配置。
+func (g *Group) DoWithTTLs(key string, successTTL, errorTTL time.Duration, fn func() (interface{}, error)) (v interface{}, err error, shared bool) {
+	r, _, _ := g.do(key, params{validTime: successTTL, errorTTL: errorTTL, hasErrorTTL: true}, fn)
+	return r.Val, r.Err, r.Shared
+}
+
+// DoOnce 像Do方法，但是用于只需要计算一次的key，比如启动时获取的配置：成功的结果永不
+// 过期，之后的调用者总是直接命中，返回的错误不会被缓存，之后的调用会重新执行方法直到
+// 成功为止，不受Group的错误缓存配置影响。Forget系列方法、WithCapacity、WithMaxCost和
+// WithMaxAge仍然可以移除其结果。
+func (g *Group) DoOnce(key string, fn func() (interface{}, error)) (v interface{}, err error, shared bool) {
+	return g.DoWithTTLs(key, NoExpiration, 0, fn)
+}
+
+// DoWithTTL 像Do方法，但是结果的有效时长由fn返回，在方法完成之后才开始计算；方法
+// 执行期间重复的调用者都会等待其结果。返回NoExpiration时结果永不过期，返回的其他不大于
+// 0的有效时长表示结果不会被缓存。
+func (g *Group) DoWithTTL(key string, fn func() (interface{}, time.Duration, error)) (v interface{}, err error, shared bool) {
+	r, _, _ := g.do(key, params{ttlFn: fn}, nil)
+	return r.Val, r.Err, r.Shared
+}
+
+// DoUntil 像Do方法，但是结果在expireAt这一时刻过期，而不是在完成之后的一段时长之后，
+// 比如使用HTTP响应的Expires或者令牌的过期时间，不受WithJitter影响。方法完成时expireAt
+// 已经过去的结果不会被缓存，方法执行期间重复的调用者仍然共享其结果。
+func (g *Group) DoUntil(key string, expireAt time.Time, fn func() (interface{}, error)) (v interface{}, err error, shared bool) {
+	t := g.deadline(expireAt)
+	r, _, _ := g.do(key, params{validTime: time.Duration(t - g.now()), expireAt: t}, fn)
+	return r.Val, r.Err, r.Shared
+}
+
+// DoWithTTLFunc 像DoWithTTL方法，但是有效时长由ttlFunc根据fn的结果计算，比如使用值中
+// 带有的过期时间。ttlFunc返回0时结果永不过期，返回负数时结果不会被缓存。返回错误的结果
+// 仍然按照Group的错误缓存配置处理。
+func (g *Group) DoWithTTLFunc(key string, fn func() (interface{}, error), ttlFunc func(val interface{}, err error) time.Duration) (v interface{}, err error, shared bool) {
+	return g.DoWithTTL(key, func() (interface{}, time.Duration, error) {
+		v, err := fn()
+		switch ttl := ttlFunc(v, err); {
+		case ttl == 0:
+			return v, NoExpiration, err
+		case ttl < 0:
+			return v, 0, err
+		default:
+			return v, ttl, err
+		}
+	})
+}
+
+// LoadOrCompute 像Do方法，但是返回的loaded只在结果来自已经完成并且还未过期的调用、
+// 没有执行fn时为true；执行方法或者等待正在进行的调用时为false。Do方法的shared对这两种
+// 情况都为true。
+func (g *Group) LoadOrCompute(key string, validTime time.Duration, fn func() (interface{}, error)) (val interface{}, loaded bool, err error) {
+	r, _, loaded := g.do(key, params{validTime: validTime}, fn)
+	return r.Val, loaded, r.Err
+}
 
+// do 是Do系列方法的底层实现。stale 标识返回的是过期的结果，loaded 标识结果来自已经
+// 完成的调用，没有执行方法也没有等待，见LoadOrCompute。
+func (g *Group) do(key string, p params, fn func() (interface{}, error)) (r Result, stale, loaded bool) {
+	key = g.normalize(key)
+	if !p.force {
+		if r, ok := g.fastDo(key); ok {
+			return r, false, true
+		}
+	}
 	g.mu.Lock()
 	if g.m == nil {
 		g.m = make(map[string]*call)
 		g.t = make(map[string]int64)
 	}
-	if c, ok := g.m[key]; ok { // 检查call结果是否存在
+	if c, ok := g.m[key]; ok && (!p.force || c.force && !c.done) { // 检查call结果是否存在
 		t, _ := g.t[key]
-		now := time.Now().Unix()
+		now := g.now()
 
-		if t > now { //还未过期需要重新查找
+		if t > now || !c.done { //还未过期或者正在调用中，共享其结果
+			if !c.done && within(p.ctx, c) {
+				g.mu.Unlock()
+				return Result{Err: ErrRecursiveDo}, false, false
+			}
+			if p.noWait && !c.done {
+				g.mu.Unlock()
+				return Result{Err: errBusy}, false, false
+			}
+			if g.tooManyWaiters(c) {
+				g.mu.Unlock()
+				return Result{Err: ErrTooManyWaiters}, false, false
+			}
 			c.dups++
+			g.stats.hitOrCoalesced(c)
+			g.looked(true)
+			g.touch(c)
+			g.maybeRefreshAhead(c, key, t, now)
+			if c.done {
+				t = g.slide(key, c, t, now)
+				g.publish(key, c, t)
+				r = c.hitResult(t, now)
+				g.mu.Unlock()
+				g.hit(key, r.HitAge)
+				return g.copied(r), false, true
+			}
+			c.join()
+			ready := c.readyChan()
+			hot := g.crowded(c)
+			g.mu.Unlock()
+			g.hotKey(key, hot)
+			joined := c
+			c, err := g.wait(p.ctx, c, key, now, ready, p.maxWait)
+			if err != nil {
+				g.leave(key, joined, err)
+				return Result{Err: err, Shared: true}, false, false
+			}
+			return g.copied(c.waited()), false, false
+		}
+		// 过期但可以返回旧值，或者被ExpireNow保留到新的结果替换它
+		if canStale := c.staleUntil(t) > now; c.done && c.err == nil && (canStale || c.expiredEarly) {
+			if c.refreshing == nil && g.refused == nil {
+				g.startRefresh(c, key, p, fn)
+			}
+			if canStale && !p.fresh {
+				c.dups++
+				g.stats.hits.Add(1)
+				g.looked(true)
+				g.touch(c)
+				r = c.hitResult(t, now)
+				g.mu.Unlock()
+				g.hit(key, r.HitAge)
+				return g.copied(r), true, true
+			}
+		}
+		if rc := c.refreshing; rc != nil { // 旧值不能再返回，等待正在进行的刷新
+			if within(p.ctx, rc) {
+				g.mu.Unlock()
+				return Result{Err: ErrRecursiveDo}, false, false
+			}
+			if p.noWait {
+				g.mu.Unlock()
+				return Result{Err: errBusy}, false, false
+			}
+			if g.tooManyWaiters(rc) {
+				g.mu.Unlock()
+				return Result{Err: ErrTooManyWaiters}, false, false
+			}
+			rc.dups++
+			g.stats.coalesced.Add(1)
+			g.looked(true)
+			ready := rc.readyChan()
+			hot := g.crowded(rc)
 			g.mu.Unlock()
-			c.wg.Wait()
-			return c.val, c.err, true
+			g.hotKey(key, hot)
+			if _, err := g.wait(p.ctx, rc, key, now, ready, p.maxWait); err != nil {
+				return Result{Err: err, Shared: true}, false, false
+			}
+			return g.copied(rc.waited()), false, false
 		}
 	}
-	c := new(call)
-	c.wg.Add(1)
+	if tc := g.exhumed(key, p); tc != nil { // 共享刚刚被遗忘的调用
+		tc.dups++
+		g.stats.hitOrCoalesced(tc)
+		g.looked(true)
+		if tc.done {
+			g.mu.Unlock()
+			return g.copied(tc.waited()), false, true
+		}
+		tc.join()
+		ready := tc.readyChan()
+		g.mu.Unlock()
+		now := g.now()
+		c, err := g.wait(p.ctx, tc, key, now, ready, p.maxWait)
+		if err != nil {
+			g.leave(key, tc, err)
+			return Result{Err: err, Shared: true}, false, false
+		}
+		return g.copied(c.waited()), false, false
+	}
+	if err := g.refused; err != nil {
+		g.mu.Unlock()
+		return Result{Err: err}, false, false
+	}
+	if g.circuitOpen(key, g.now()) {
+		g.mu.Unlock()
+		return Result{Err: ErrCircuitOpen}, false, false
+	}
+	last := g.throttled(key, g.now())
+	if last != nil && !g.minIntervalWait && !p.force {
+		g.stats.hits.Add(1)
+		g.looked(true)
+		r = last.hitResult(last.doneAt+int64(g.minInterval), g.now())
+		g.mu.Unlock()
+		g.hit(key, r.HitAge)
+		return g.copied(r), false, true
+	}
+	var evs []eviction
+	old, ok := g.m[key]
+	if ok && !old.done { // 只有DoRefresh会走到这里，取代正在进行的调用
+		evs = g.forget(evs, key, old)
+		old = nil
+	} else if ok {
+		g.stats.evictions.Add(1)
+		evs = g.evict(evs, key, old, EvictExpired)
+	}
+	c := &call{fn: fn, params: p, startedAt: g.now()}
+	c.cancelable()
+	c.revalidating(old)
+	if last != nil {
+		c.notBefore = last.doneAt + int64(g.minInterval)
+	}
+	g.debounced(c)
+	g.stats.misses.Add(1)
+	g.looked(false)
+	g.begin()
 	g.m[key] = c
+	g.pending++
 	// 判断结果，对时间进行赋值
-	g.t[key] = getValidTime(validTime)
+	g.t[key] = g.pendingValidTime(p.validTime)
+	g.unpublish(key)
+	g.track(key, old, c)
+	evs = g.trim(evs, c)
 	g.mu.Unlock()
+	g.notifyEvicted(evs)
+	if g.logger != nil {
+		g.logger.Logf("timesf: new call for key %q, valid for %v", key, p.validTime)
+	}
 
-	g.doCall(c, key, fn)
-
-	return c.val, c.err, c.dups > 0
+	if p.ctx != nil && p.ctx.Done() != nil {
+		return g.doCallCtx(p.ctx, c, key, fn), false, false
+	}
+	if p.ownerWait && p.maxWait > 0 {
+		return g.doCallTimeout(c, key, fn, p.maxWait), false, false
+	}
+	return g.doCall(c, key, fn), false, false
 }
 
-// DoChan 像Do方法，但是不同的是返回一个通道。通道将把结果进行返回。
+// DoChan 像Do方法，但是不同的是返回一个通道。通道将把结果进行返回，之后被关闭。
 func (g *Group) DoChan(key string, validTime time.Duration, fn func() (interface{}, error)) <-chan Result {
+	ch, _ := g.doChan(key, params{validTime: validTime}, fn)
+	return ch
+}
+
+// DoChanDefault 像DoChan方法，但是使用Group默认的有效时长，见WithDefaultTTL。
+func (g *Group) DoChanDefault(key string, fn func() (interface{}, error)) <-chan Result {
+	return g.DoChan(key, g.defaultTTL, fn)
+}
+
+// DoChanWithTTL 像DoWithTTL方法，但是返回一个通道，见DoChan。
+func (g *Group) DoChanWithTTL(key string, fn func() (interface{}, time.Duration, error)) <-chan Result {
+	ch, _ := g.doChan(key, params{ttlFn: fn}, nil)
+	return ch
+}
+
+// DoChanCancel 像DoChan方法，同时返回一个取消方法：调用之后通道不再等待结果，调用完成
+// 时也不会再向其发送，其他调用者不受影响；正在进行的调用不会被取消，结果仍然会被缓存，
+// 只有DoCtx开始的调用在所有调用者都离开之后才会被取消，见DoCtx。
+// 结果已经发送到通道之后，或者重复调用取消方法时什么都不做。
+func (g *Group) DoChanCancel(key string, validTime time.Duration, fn func() (interface{}, error)) (<-chan Result, func()) {
+	ch, c := g.doChan(key, params{validTime: validTime}, fn)
+	if c == nil {
+		return ch, func() {}
+	}
+	return ch, func() {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		if c.done {
+			return
+		}
+		for i, w := range c.chans {
+			if w != ch {
+				continue
+			}
+			// 复制而不是原地删除，已经取出的c.chans可能正在被发送。
+			chans := make([]chan<- Result, 0, len(c.chans)-1)
+			c.chans = append(append(chans, c.chans[:i]...), c.chans[i+1:]...)
+			if i == 0 && c.chanOwner {
+				c.chanOwner = false
+			} else {
+				c.dups--
+			}
+			g.letGo(g.normalize(key), c, 1, context.Canceled)
+			return
+		}
+	}
+}
+
+// doChan 是DoChan系列方法的底层实现。ch被加入了还未完成的调用的等待通道时同时返回此
+// 调用，否则返回nil，见DoChanCancel。
+func (g *Group) doChan(key string, p params, fn func() (interface{}, error)) (chan Result, *call) {
+	key = g.normalize(key)
 	ch := make(chan Result, 1)
+	if h, now, ok := g.loadHit(key); ok {
+		g.stats.hits.Add(1)
+		g.looked(true)
+		r := h.result(now)
+		send(ch, g.copied(r))
+		g.hit(key, r.HitAge)
+		return ch, nil
+	}
 	g.mu.Lock()
 	if g.m == nil {
 		g.m = make(map[string]*call)
@@ -86,61 +752,1070 @@ func (g *Group) DoChan(key string, validTime time.Duration, fn func() (interface
 	}
 	if c, ok := g.m[key]; ok {
 		t, _ := g.t[key]
-		now := time.Now().Unix()
+		now := g.now()
 
-		if t > now { //还未过期需要重新查找
+		if t > now || !c.done { //还未过期或者正在调用中，共享其结果
+			if g.tooManyWaiters(c) {
+				g.mu.Unlock()
+				send(ch, Result{Err: ErrTooManyWaiters})
+				return ch, nil
+			}
 			c.dups++
-			c.chans = append(c.chans, ch)
+			g.stats.hitOrCoalesced(c)
+			g.looked(true)
+			g.touch(c)
+			g.maybeRefreshAhead(c, key, t, now)
+			var r Result
+			var hot int
+			done := c.done
+			if done {
+				t = g.slide(key, c, t, now)
+				r = c.hitResult(t, now)
+				g.publish(key, c, t)
+			} else {
+				c.chans = append(c.chans, ch)
+				c.join()
+				hot = g.crowded(c)
+			}
 			g.mu.Unlock()
-			return ch
+			if done {
+				send(ch, g.copied(r))
+				g.hit(key, r.HitAge)
+				return ch, nil
+			}
+			g.hotKey(key, hot)
+			if g.logger != nil {
+				g.logger.Logf("timesf: duplicate call for key %q suppressed", key)
+			}
+			return ch, c
 		}
 	}
-	c := &call{chans: []chan<- Result{ch}}
-	c.wg.Add(1)
+	if err := g.refused; err != nil {
+		g.mu.Unlock()
+		send(ch, Result{Err: err})
+		return ch, nil
+	}
+	if g.circuitOpen(key, g.now()) {
+		g.mu.Unlock()
+		send(ch, Result{Err: ErrCircuitOpen})
+		return ch, nil
+	}
+	last := g.throttled(key, g.now())
+	if last != nil && !g.minIntervalWait {
+		g.stats.hits.Add(1)
+		g.looked(true)
+		r := last.hitResult(last.doneAt+int64(g.minInterval), g.now())
+		g.mu.Unlock()
+		send(ch, g.copied(r))
+		g.hit(key, r.HitAge)
+		return ch, nil
+	}
+	var evs []eviction
+	old, ok := g.m[key]
+	if ok {
+		g.stats.evictions.Add(1)
+		evs = g.evict(evs, key, old, EvictExpired)
+	}
+	c := &call{chans: []chan<- Result{ch}, chanOwner: true, fn: fn, params: p, startedAt: g.now()}
+	if last != nil {
+		c.notBefore = last.doneAt + int64(g.minInterval)
+	}
+	g.debounced(c)
+	g.stats.misses.Add(1)
+	g.looked(false)
+	g.begin()
 	g.m[key] = c
-	g.t[key] = getValidTime(validTime)
+	g.pending++
+	g.t[key] = g.pendingValidTime(p.validTime)
+	g.unpublish(key)
+	g.track(key, old, c)
+	evs = g.trim(evs, c)
 	g.mu.Unlock()
+	g.notifyEvicted(evs)
+	if g.logger != nil {
+		g.logger.Logf("timesf: new call for key %q, valid for %v", key, p.validTime)
+	}
 
 	go g.doCall(c, key, fn)
 
-	return ch
+	return ch, c
 }
 
-// doCall 底层方法调用逻辑
-func (g *Group) doCall(c *call, key string, fn func() (interface{}, error)) {
-	c.val, c.err = fn()
-	c.wg.Done()
+// doCall 底层方法调用逻辑。调用完成后结果保留在map中，直到过期或者被遗忘。返回执行
+// 方法的调用者拿到的结果，其Shared标识完成时结果是否已经被其他调用者共享。
+func (g *Group) doCall(c *call, key string, fn func() (interface{}, error)) Result {
+	var start time.Time
+	if g.observer != nil {
+		start = time.Now()
+	}
+	ttl := g.run(c, key, fn)
+	var dur time.Duration
+	if g.observer != nil {
+		dur = time.Since(start)
+	}
 
 	g.mu.Lock()
-	if !c.forgotten {
-		delete(g.m, key)
-		delete(g.t, key)
+	d := g.complete(c, key, ttl)
+	g.mu.Unlock()
+	g.deliver(d)
+	if g.observer != nil {
+		g.observer.ObserveCompute(key, dur, d.r.Err, d.r.Shared)
+	}
+	if c.endSpan != nil {
+		c.endSpan(d.r.Shared, d.r.Err)
+	}
+	return d.r
+}
+
+// delivery 是释放锁之后需要发送给等待通道的结果，以及需要通知的移除。owner 为true时
+// chans[0]属于开始此调用的DoChan调用者，其余的通道属于等待的调用者。
+type delivery struct {
+	chans []chan<- Result
+	owner bool
+	r     Result
+	evs   []eviction
+}
+
+// deliver 将结果发送给所有等待的通道并关闭通道，通知移除，调用者不能持有锁。
+func (g *Group) deliver(d delivery) {
+	g.notifyEvicted(d.evs)
+	for i, ch := range d.chans {
+		r := d.r
+		if r.Role == RoleLeader && (i > 0 || !d.owner) {
+			r.Role = RoleWaiter
+		}
+		send(ch, g.copied(r))
+	}
+}
+
+// send 将结果r发送给DoChan的通道ch之后关闭通道，每一个通道只收到一个结果。
+func send(ch chan<- Result, r Result) {
+	ch <- r
+	close(ch)
+}
+
+// complete 在调用c的方法执行完成之后记录结果的有效期，并返回需要发送给等待通道的结果，
+// ttl是run返回的有效时长。调用者需要持有锁，并在释放锁之后调用deliver。
+func (g *Group) complete(c *call, key string, ttl time.Duration) delivery {
+	var evs []eviction
+	if g.m[key] == c {
+		g.pending--
+	}
+	c.done = true
+	c.doneAt = g.now()
+	c.fnCtx, c.cancel = nil, nil
+	c.progress = nil
+	g.end()
+	g.slow(key, c)
+	if c.abandoned != nil {
+		c.val, c.err, c.canceled = nil, c.abandoned, true
+	}
+	if !c.canceled && !c.rejected {
+		g.recordOutcome(key, c.err)
+		g.remember(key, c)
+	}
+	if g.m[key] == c && !g.promote(c, key) {
+		cacheErrors, errorTTL := g.cacheErrors, g.errorTTL
+		if c.hasErrorTTL {
+			cacheErrors, errorTTL = c.errorTTL > 0, c.errorTTL
+		}
+		switch {
+		case c.canceled || c.rejected:
+			g.drop(key, c)
+		case c.err != nil && !cacheErrors:
+			g.release(key, c)
+		case c.err != nil && errorTTL > 0:
+			g.t[key] = g.getValidTime(errorTTL)
+		case c.err == nil && c.val == nil && g.skipNil:
+			g.release(key, c)
+		case c.expireAt != 0 && c.expireAt <= c.doneAt:
+			g.release(key, c)
+		case c.expireAt != 0:
+			g.t[key] = c.expireAt
+		case c.expiresAt != 0:
+			g.t[key] = c.expiresAt
+		case c.ttlFn != nil && ttl <= 0 && ttl != NoExpiration:
+			g.release(key, c)
+		case c.ttlFn != nil:
+			g.t[key] = g.getValidTime(ttl)
+		case g.uncached(ttl):
+			g.release(key, c)
+		case c.retried && c.err == nil:
+			g.t[key] = g.getValidTime(ttl)
+		}
+		if g.m[key] == c {
+			g.t[key] = g.capped(c, g.graced(c, g.t[key]))
+			g.charge(c)
+			evs = g.trim(evs, c)
+			evs = g.seed(evs, key, c, ttl)
+		}
+	}
+	r := c.result(c.dups > 0)
+	r.Forgotten = c.forgotten
+	r.Dups = c.dups
+	r.Role = RoleLeader
+	r.ExpiresAt = time.Unix(0, c.doneAt)
+	if g.m[key] == c {
+		r.ExpiresAt = expiryTime(g.t[key])
+	}
+	c.final = r
+	c.closeReady()
+	d := delivery{c.chans, c.chanOwner, r, evs}
+	// 结果已经交给这些通道，之后不会再有等待者。
+	c.chans = nil
+	g.dropFuncs(c)
+	return d
+}
+
+// run 执行调用c的方法并保存结果，返回结果的有效时长：设置了ttlFn时为其返回值，否则为
+// c.validTime。DoCtx的方法的上下文被取消之后，结果不会写入共享存储。
+func (g *Group) run(c *call, key string, fn func() (interface{}, error)) time.Duration {
+	ttl := c.validTime
+	if c.ttlFn != nil {
+		fn = func() (v interface{}, err error) {
+			v, ttl, err = c.ttlFn()
+			return v, err
+		}
+	}
+	if c.seedFn != nil {
+		fn = func() (v interface{}, err error) {
+			v, c.seeds, err = c.seedFn()
+			return v, err
+		}
+	}
+	if c.versionFn != nil {
+		fn = c.versioned()
 	}
-	for _, ch := range c.chans {
-		ch <- Result{c.val, c.err, c.dups > 0}
+	if c.fallback {
+		fn = c.orKept(fn)
 	}
+	ctx := context.Background()
+	if c.ctxFn != nil {
+		fnCtx, cancel := c.fnCtx, c.cancel
+		defer cancel()
+		ctx = fnCtx
+		if g.tracer != nil {
+			fnCtx, c.endSpan = g.tracer.StartSpan(fnCtx, key)
+		}
+		fn = func() (interface{}, error) {
+			return c.ctxFn(fnCtx)
+		}
+	}
+	if g.retry != nil {
+		fn = g.retry.wrap(fn, &c.retried)
+	}
+	if c.notBefore != 0 {
+		start := g.now()
+		g.pause(ctx, c.notBefore, c.hurry)
+		if c.hurry != nil {
+			g.settled(key, time.Duration(g.now()-start))
+		}
+	}
+	if g.store != nil && g.load(ctx, c, key) {
+		c.ctx = nil
+		return time.Duration(c.expiresAt - g.now())
+	}
+	var ok bool
+	c.val, c.err, ok = g.limited(g.priorityOf(key), func() (interface{}, error) {
+		start := g.now()
+		defer func() {
+			c.took = time.Duration(g.now() - start)
+			g.stats.observe(c.took)
+		}()
+		return g.execute(key, fn)
+	})
+	c.rejected = !ok
+	if ok {
+		g.classify(c)
+		c.err = g.wrapped(key, c.err)
+	}
+	if g.store != nil && c.err == nil && ctx.Err() == nil {
+		g.save(ctx, c, key, ttl)
+	}
+	// 不再持有调用者的上下文，之后的刷新也不会使用它。
+	c.ctx = nil
+	return ttl
+}
+
+// drop 删除key不应该被缓存的结果c，调用者需要持有锁。
+func (g *Group) drop(key string, c *call) {
+	g.untrack(c)
+	delete(g.m, key)
+	delete(g.t, key)
+	g.unpublish(key)
+	g.stats.evictions.Add(1)
+}
+
+// execute 执行方法，记录统计数据，调用BeforeCall和AfterCall钩子并输出日志。方法
+// panic时先输出日志，panic继续向上传递。
+func (g *Group) execute(key string, fn func() (interface{}, error)) (v interface{}, err error) {
+	h, l := g.hooks, g.logger
+	if h == nil && l == nil {
+		return g.stats.execute(fn)
+	}
+	if h != nil && h.BeforeCall != nil {
+		h.BeforeCall(key)
+	}
+	start := g.now()
+	if l != nil {
+		defer func() {
+			if r := recover(); r != nil {
+				l.Logf("timesf: call for key %q panicked after %v: %v", key, time.Duration(g.now()-start), r)
+				panic(r)
+			}
+		}()
+	}
+	v, err = g.stats.execute(fn)
+	dur := time.Duration(g.now() - start)
+	if l != nil && err != nil {
+		l.Logf("timesf: call for key %q failed after %v: %v", key, dur, err)
+	}
+	if h != nil && h.AfterCall != nil {
+		h.AfterCall(key, dur, err)
+	}
+	return v, err
+}
+
+// startRefresh 开启一个后台协程为调用c重新执行方法，调用者需要持有锁。
+func (g *Group) startRefresh(c *call, key string, p params, fn func() (interface{}, error)) {
+	p.ctx = nil
+	rc := &call{fn: fn, params: p, startedAt: g.now()}
+	rc.cancelable()
+	rc.revalidating(c)
+	c.refreshing = rc
+	g.begin()
+	go g.refresh(c, rc, key)
+}
+
+// refresh 在后台执行刷新调用rc。只有刷新成功并且c仍然是key当前的调用时，才会用新的
+// 结果进行替换；否则继续保留旧的结果。
+func (g *Group) refresh(c, rc *call, key string) {
+	ttl := g.run(rc, key, rc.fn)
+
+	var evs []eviction
+	g.mu.Lock()
+	rc.done = true
+	rc.doneAt = g.now()
+	rc.fnCtx, rc.cancel = nil, nil
+	g.end()
+	g.slow(key, rc)
+	c.refreshing = nil
+	if rc.err != nil && g.m[key] == c && g.staleIfError > 0 {
+		c.graceUntil = addTime(rc.doneAt, g.staleIfError)
+	}
+	if rc.err == nil {
+		g.remember(key, rc)
+	}
+	if g.m[key] == c && rc.err == nil && !(rc.val == nil && g.skipNil) && (rc.ttlFn == nil || ttl > 0 || ttl == NoExpiration || rc.expiresAt != 0) {
+		evs = g.evict(evs, key, c, EvictReplaced)
+		g.m[key] = rc
+		if rc.expiresAt != 0 {
+			g.t[key] = g.capped(rc, rc.expiresAt)
+		} else {
+			g.t[key] = g.capped(rc, g.getValidTime(ttl))
+		}
+		g.unpublish(key)
+		g.track(key, c, rc)
+		g.charge(rc)
+		evs = g.trim(evs, rc)
+		evs = g.seed(evs, key, rc, ttl)
+	}
+	rc.final = rc.result(true)
+	rc.final.Dups = rc.dups
+	rc.final.ExpiresAt = time.Unix(0, rc.doneAt)
+	if g.m[key] == rc {
+		rc.final.ExpiresAt = expiryTime(g.t[key])
+	}
+	rc.closeReady()
+	g.dropFuncs(rc)
+	shared := rc.dups > 0
 	g.mu.Unlock()
+	g.notifyEvicted(evs)
+	if rc.err != nil {
+		g.refreshFailed(key, rc.err)
+	}
+	if rc.endSpan != nil {
+		rc.endSpan(shared, rc.err)
+	}
+}
+
+// maybeRefreshAhead 在开启提前刷新时，如果已完成的调用c剩余的有效期不足，则开启
+// 一个后台刷新。调用者需要持有锁，t是c的过期时间。
+func (g *Group) maybeRefreshAhead(c *call, key string, t, now int64) {
+	if g.refreshAhead <= 0 && g.refreshBefore <= 0 || g.refused != nil || !c.done || c.refreshing != nil || c.err != nil || c.fn == nil || c.validTime <= 0 || c.expireAt != 0 {
+		return
+	}
+	if float64(t-now) < g.refreshAhead*float64(c.validTime) || t-now < int64(g.refreshBefore) {
+		g.startRefresh(c, key, c.params, c.fn)
+	}
 }
 
 // Forget 方法告诉单飞去遗忘掉一个key。将来对Do方法的调用将调用方法去拿结果，
-// 而不是等待之前的结果。
+// 而不是等待之前的结果。依赖key的其他key也一起被遗忘，见AddDependency。需要知道key
+// 是否存在时使用ForgetStatus。
 func (g *Group) Forget(key string) {
+	g.ForgetStatus(key)
+}
+
+// ForgetStatus 像Forget方法，同时报告做了什么：existed标识key是否存在，wasInFlight
+// 标识被遗忘的调用是否还在进行中。
+func (g *Group) ForgetStatus(key string) (existed, wasInFlight bool) {
+	key = g.normalize(key)
+	var f forgetting
 	g.mu.Lock()
-	if c, ok := g.m[key]; ok {
-		c.forgotten = true
+	existed, wasInFlight = g.forgetKey(&f, key)
+	g.mu.Unlock()
+	f.finish(g)
+	return existed, wasInFlight
+}
+
+// ForgetMany 像对keys中的每一个key调用Forget方法，但是只持有一次锁，返回其中存在的key。
+// 被遗忘的正在进行的调用同样不会写入结果。
+func (g *Group) ForgetMany(keys []string) []string {
+	var existed []string
+	var f forgetting
+	g.mu.Lock()
+	for _, key := range keys {
+		if ok, _ := g.forgetKey(&f, g.normalize(key)); ok {
+			existed = append(existed, key)
+		}
+	}
+	g.mu.Unlock()
+	f.finish(g)
+	return existed
+}
+
+// forgetting 收集Forget系列方法持有锁时产生的移除和需要发送给等待通道的结果。
+type forgetting struct {
+	evs []eviction
+	ds  []delivery
+}
+
+// finish 通知f收集的移除并发送结果，调用者不能持有锁。
+func (f *forgetting) finish(g *Group) {
+	g.notifyEvicted(f.evs)
+	for _, d := range f.ds {
+		g.deliver(d)
+	}
+}
+
+// forgetKey 遗忘key以及依赖key的其他key，返回值的含义和ForgetStatus相同。调用者需要持有锁，
+// 并在释放锁之后调用f.finish。
+func (g *Group) forgetKey(f *forgetting, key string) (existed, wasInFlight bool) {
+	forget := func(key string, c *call) {
+		var d delivery
+		f.evs = g.forget(f.evs, key, c)
+		f.evs, d = g.orphan(f.evs, key, c)
+		// 已完成的调用没有等待的通道，不为它分配delivery。
+		if len(d.chans) > 0 {
+			f.ds = append(f.ds, d)
+		}
+	}
+	c, existed := g.m[key]
+	if existed {
+		wasInFlight = !c.done
+		forget(key, c)
+		g.bury(key, c)
+	}
+	g.cascade(key, forget)
+	return existed, wasInFlight
+}
+
+// Invalidate 让key已完成的结果失效，之后的调用者会重新执行方法，返回key的结果是否被删除。
+// 和Forget不同，正在进行的调用不受影响：其等待者仍然拿到结果，完成后结果照常被缓存。
+// 也就是说Invalidate只丢弃已经缓存的结果，Forget则连同正在进行的调用一起放弃。依赖key
+// 的其他key已完成的结果也一起失效，见AddDependency。
+func (g *Group) Invalidate(key string) bool {
+	key = g.normalize(key)
+	var evs []eviction
+	invalidate := func(key string, c *call) {
+		if c.done {
+			evs = g.evict(evs, key, c, EvictExpired)
+			g.drop(key, c)
+		}
+	}
+	g.mu.Lock()
+	c, ok := g.m[key]
+	ok = ok && c.done
+	if ok {
+		invalidate(key, c)
+	}
+	g.cascade(key, invalidate)
+	g.mu.Unlock()
+	g.notifyEvicted(evs)
+	return ok
+}
+
+// ErrForgotten 是ForgetAndNotify中止的调用交给等待者的错误。
+var ErrForgotten = errors.New("timesf: key was forgotten")
+
+// ForgetAndNotify 像Forget方法，但是如果key的调用还在进行中，等待其结果的调用者立即
+// 拿到ErrForgotten：Do系列方法返回ErrForgotten，DoChan的通道收到Err为ErrForgotten的
+// Result之后被关闭。执行方法的调用者仍然拿到方法的结果，但结果不会被缓存。返回key
+// 是否存在。
+func (g *Group) ForgetAndNotify(key string) bool {
+	return g.forgetAndNotify(key, false)
+}
+
+// forgetAndNotify 是ForgetAndNotify和ForgetAndCancel的底层实现，cancel为true时同时取消
+// 正在执行的方法的上下文。
+func (g *Group) forgetAndNotify(key string, cancel bool) bool {
+	key = g.normalize(key)
+	var evs []eviction
+	var d delivery
+	g.mu.Lock()
+	c, ok := g.m[key]
+	if ok {
+		if cancel {
+			c.cancelRunning()
+		}
+		evs = g.forget(evs, key, c)
+		if !c.done {
+			d = g.abort(key, c)
+		}
+	}
+	g.mu.Unlock()
+	g.notifyEvicted(evs)
+	g.deliver(d)
+	return ok
+}
+
+// forget 遗忘key的调用c，调用者需要持有锁，并在释放锁之后调用notifyEvicted。
+func (g *Group) forget(evs []eviction, key string, c *call) []eviction {
+	if !c.done {
+		g.pending--
 	}
+	c.forgotten = true
+	g.stats.forgets.Add(1)
+	evs = g.evict(evs, key, c, EvictForgotten)
+	g.untrack(c)
 	delete(g.m, key)
 	delete(g.t, key)
+	g.unpublish(key)
+	return evs
+}
+
+// ForgetUnshared 像Forget方法，但是只有key没有正在进行并且被共享的调用时才遗忘。
+// key被遗忘或者不存在时返回true；key的调用还在进行中并且已经有其他调用者在等待其
+// 结果时返回false，此时key保持不变。
+func (g *Group) ForgetUnshared(key string) bool {
+	key = g.normalize(key)
+	var evs []eviction
+	g.mu.Lock()
+	c, ok := g.m[key]
+	if !ok {
+		g.mu.Unlock()
+		return true
+	}
+	if !c.done && c.dups > 0 {
+		g.mu.Unlock()
+		return false
+	}
+	evs = g.forget(evs, key, c)
 	g.mu.Unlock()
+	g.notifyEvicted(evs)
+	return true
 }
 
-// 根据配置的可以时间，获得最终有效时间。
-func getValidTime(validTime time.Duration) int64 {
+// CompareAndForget 像Forget方法，但是只有match对key已完成并且没有错误的结果的值返回true
+// 时才遗忘，返回key是否被遗忘。检查和遗忘在同一次持有锁时进行，之间写入的新结果不会被
+// 误删，因此match不能调用Group的方法。key不存在、结果是错误或者还在调用中时返回false，
+// 不调用match，正在进行的调用不受影响。
+func (g *Group) CompareAndForget(key string, match func(val interface{}) bool) bool {
+	key = g.normalize(key)
+	var evs []eviction
+	g.mu.Lock()
+	c, ok := g.m[key]
+	if !ok || !c.done || c.err != nil || !match(c.val) {
+		g.mu.Unlock()
+		return false
+	}
+	evs = g.forget(evs, key, c)
+	g.mu.Unlock()
+	g.notifyEvicted(evs)
+	return true
+}
+
+// Set 将val作为key已完成的结果写入，有效时长validTime的含义和Do方法相同。如果key
+// 正在调用中，像Forget一样将其遗忘：已经在等待的调用者仍然拿到其结果，但之后的调用
+// 者拿到的是写入的val。
+func (g *Group) Set(key string, val interface{}, validTime time.Duration) {
+	g.set(key, val, validTime, true)
+}
+
+// Swap 像Set方法，同时返回被替换的结果：prev是key之前已完成、还未过期并且没有错误的
+// 结果的值，existed标识是否有这样的结果。读取和写入在同一次持有锁时进行。
+func (g *Group) Swap(key string, val interface{}, validTime time.Duration) (prev interface{}, existed bool) {
+	return g.set(key, val, validTime, true)
+}
+
+// SetValue 像Set方法，但是key正在调用中时什么都不做，正在进行的调用的结果不会被替换。
+// 已完成的结果会被替换，并以EvictReplaced通知移除回调。像Swap一样返回被替换的结果。
+func (g *Group) SetValue(key string, val interface{}, validTime time.Duration) (prev interface{}, existed bool) {
+	return g.set(key, val, validTime, false)
+}
+
+// set 是Set、Swap和SetValue的底层实现，replaceInFlight标识是否替换正在进行的调用。返回
+// 被替换的结果，见Swap。
+func (g *Group) set(key string, val interface{}, validTime time.Duration, replaceInFlight bool) (prev interface{}, existed bool) {
+	key = g.normalize(key)
+	g.mu.Lock()
+	if g.m == nil {
+		g.m = make(map[string]*call)
+		g.t = make(map[string]int64)
+	}
+	var evs []eviction
+	old, ok := g.m[key]
+	if ok && !old.done && !replaceInFlight {
+		g.mu.Unlock()
+		return nil, false
+	}
+	now := g.now()
+	if ok {
+		if !old.done {
+			old.forgotten = true
+			g.pending--
+		} else if old.err == nil && g.t[key] > now {
+			prev, existed = old.val, true
+		}
+		evs = g.evict(evs, key, old, EvictReplaced)
+	}
+	c := &call{val: val, done: true, startedAt: now, doneAt: now, params: params{validTime: validTime}}
+	g.m[key] = c
+	g.t[key] = g.capped(c, g.getValidTime(validTime))
+	g.unpublish(key)
+	g.track(key, old, c)
+	g.charge(c)
+	evs = g.trim(evs, c)
+	g.mu.Unlock()
+	g.notifyEvicted(evs)
+	return prev, existed
+}
+
+// Touch 将key已完成并且还未过期的结果的有效期重新设置为validTime，而不重新执行方法，
+// validTime的含义和Do方法相同，但不会超过WithMaxAge的限制。key不存在、已经过期或者
+// 还在调用中时返回false，并且什么都不做。
+func (g *Group) Touch(key string, validTime time.Duration) bool {
+	key = g.normalize(key)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	c, ok := g.m[key]
+	if !ok || !c.done || g.t[key] <= g.now() {
+		return false
+	}
+	g.t[key] = g.capped(c, g.getValidTime(validTime))
+	g.unpublish(key)
+	g.touch(c)
+	return true
+}
+
+// ExpireNow 让key已完成并且还未过期的结果立即过期，但是在新的结果替换它之前保留它。
+// 这和删除结果的Forget以及Invalidate不同：之后的Do系列调用者在后台刷新key并等待刷新的
+// 结果，DoStale的调用者在staleFor之内仍然立即拿到旧值；刷新完成之前Peek和PeekResult
+// 仍然返回保留的结果，剩余的有效时长为0，刷新失败时结果继续被保留。janitor不会删除被
+// 保留的结果。key不存在、已经过期或者还在调用中时返回false，并且什么都不做。
+func (g *Group) ExpireNow(key string) bool {
+	key = g.normalize(key)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	c, ok := g.m[key]
+	now := g.now()
+	if !ok || !c.done || g.t[key] <= now {
+		return false
+	}
+	g.t[key] = now
+	c.expiredEarly = true
+	g.unpublish(key)
+	return true
+}
+
+// Update 将key已完成并且还未过期的结果的值替换为val，保持原来的过期时间，比如写入路径
+// 已经知道了新的值，不需要再重新执行方法。原来的结果以EvictReplaced通知移除回调。key
+// 不存在、已经过期或者还在调用中时返回false，并且什么都不做，不会写入新的key。
+func (g *Group) Update(key string, val interface{}) bool {
+	key = g.normalize(key)
+	var evs []eviction
+	g.mu.Lock()
+	old, ok := g.m[key]
+	now := g.now()
+	if !ok || !old.done || g.t[key] <= now {
+		g.mu.Unlock()
+		return false
+	}
+	evs = g.replace(evs, key, old, val, now)
+	g.mu.Unlock()
+	g.notifyEvicted(evs)
+	return true
+}
+
+// UpdateFunc 像Update方法，但是新的值由apply根据原来的值计算，比如在缓存的计数上加上
+// 一个增量。key已完成并且还未过期时exists为true，old是其值，否则old为nil。keep为true时
+// 用new替换原来的值，保持原来的过期时间；keep为false时移除key，像Forget方法一样。apply
+// 在锁外被调用，不能修改old，期间key被其他调用者改变时以新的值重新调用apply，因此
+// apply可能被调用多次。并发的调用者只会看到原来的值或者新的值。替换或者移除了key时
+// 返回true，exists为false时只返回false，不会写入新的key。
+func (g *Group) UpdateFunc(key string, apply func(old interface{}, exists bool) (new interface{}, keep bool)) bool {
+	key = g.normalize(key)
+	for {
+		g.mu.RLock()
+		old, ok := g.m[key]
+		exists := ok && old.done && g.t[key] > g.now()
+		var val interface{}
+		if exists {
+			val = old.val
+		}
+		g.mu.RUnlock()
+		val, keep := apply(val, exists)
+		if !exists {
+			return false
+		}
+
+		var evs []eviction
+		g.mu.Lock()
+		now := g.now()
+		if g.m[key] != old || g.t[key] <= now { // apply期间key被改变或者已经过期
+			g.mu.Unlock()
+			continue
+		}
+		if keep {
+			evs = g.replace(evs, key, old, val, now)
+		} else {
+			evs = g.forget(evs, key, old)
+		}
+		g.mu.Unlock()
+		g.notifyEvicted(evs)
+		return true
+	}
+}
+
+// replace 将key已完成的结果old替换为值为val的新结果，保持原来的过期时间，原来的结果以
+// EvictReplaced移除。调用者需要持有锁。
+func (g *Group) replace(evs []eviction, key string, old *call, val interface{}, now int64) []eviction {
+	evs = g.evict(evs, key, old, EvictReplaced)
+	c := &call{fn: old.fn, params: old.rerun(), val: val, done: true, startedAt: now, doneAt: now}
+	g.m[key] = c
+	g.unpublish(key)
+	g.track(key, old, c)
+	g.charge(c)
+	return g.trim(evs, c)
+}
+
+// Len 返回当前记录的key数量，是EntryCount和InFlightCount之和。
+func (g *Group) Len() int {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return len(g.m)
+}
+
+// EntryCount 返回当前记录的已完成结果的数量，包括已经过期但还没有被移除的结果。
+// 设置了WithMaxCost时，这些结果的总开销见Cost。
+func (g *Group) EntryCount() int {
+	completed, _ := g.LenByState()
+	return completed
+}
+
+// InFlightCount 返回当前正在调用中的key数量，不包括后台刷新。和EntryCount一起可以
+// 区分Len的增长来自缓存的结果还是迟迟没有完成的调用。
+func (g *Group) InFlightCount() int {
+	_, inflight := g.LenByState()
+	return inflight
+}
+
+// LenByState 同时返回EntryCount和InFlightCount，两者是同一时刻的快照，之和等于Len。
+// 计数随着key的写入和移除维护，不需要遍历所有的key。
+func (g *Group) LenByState() (completed, inflight int) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return len(g.m) - g.pending, g.pending
+}
+
+// Keys 返回当前正在调用或者还未过期的key，顺序不固定。返回的切片是新分配的，
+// 调用者可以随意修改。
+func (g *Group) Keys() []string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	now := g.now()
+	keys := make([]string, 0, len(g.m))
+	for key, c := range g.m {
+		if c.done && g.t[key] <= now {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// InFlight 返回每一个正在调用中的key当前等待其结果的重复调用者数量，用来发现被大量
+// 并发访问的key。返回的map是新分配的。
+func (g *Group) InFlight() map[string]int {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	m := make(map[string]int)
+	for key, c := range g.m {
+		if !c.done {
+			m[key] = c.dups
+		}
+	}
+	return m
+}
+
+// InFlightKeys 返回当前正在调用中的key，顺序不固定。返回的切片是新分配的，调用者可以
+// 随意修改。
+func (g *Group) InFlightKeys() []string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	var keys []string
+	for key, c := range g.m {
+		if !c.done {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// EntryInfo 描述Group中的一个key，见Entries。
+type EntryInfo struct {
+	Key string
+	// InFlight 标识调用还没有完成。
+	InFlight bool
+	// StartedAt 是调用开始的时间，Set写入的结果为写入的时间。ComputedAt 是调用完成的
+	// 时间，WithMaxAge从此开始计算，调用中的key为零值。
+	StartedAt  time.Time
+	ComputedAt time.Time
+	// ExpiresAt 是记录的过期时间，永不过期时为零值。调用中的key为其开始时记录的有效期。
+	ExpiresAt time.Time
+	// Dups 是共享此调用结果的重复调用者数量。
+	Dups int
+	// KeyStats 是已完成的结果的统计数据，调用中的key为零值。
+	KeyStats
+}
+
+// Entries 返回Group中每一个key的信息，包括已经过期但还没有被移除的结果，顺序不固定，
+// 用于调试。只在复制时持有锁，返回的切片是新分配的。
+func (g *Group) Entries() []EntryInfo {
+	g.mu.RLock()
+	entries := make([]EntryInfo, 0, len(g.m))
+	for key, c := range g.m {
+		e := EntryInfo{Key: key, InFlight: !c.done, StartedAt: time.Unix(0, c.startedAt), Dups: c.dups}
+		if c.done {
+			e.ComputedAt = time.Unix(0, c.doneAt)
+			e.KeyStats = c.keyStats()
+		}
+		if t := g.t[key]; t != math.MaxInt64 {
+			e.ExpiresAt = time.Unix(0, t)
+		}
+		entries = append(entries, e)
+	}
+	g.mu.RUnlock()
+	return entries
+}
+
+// Has 报告key当前是否正在调用中或者有还未过期的结果，和Keys的判断相同。不会等待或者
+// 延长调用。
+func (g *Group) Has(key string) bool {
+	key = g.normalize(key)
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	c, ok := g.m[key]
+	return ok && (!c.done || g.t[key] > g.now())
+}
+
+// ForEach 对每一个已完成并且还未过期的结果调用fn，fn返回false时停止，顺序不固定。
+// 永不过期的结果expiresAt为零值。ForEach先在持有锁时复制一份快照，再在释放锁之后调用
+// fn，因此fn可以调用Group的方法，但看到的是调用ForEach时的状态。
+func (g *Group) ForEach(fn func(key string, val interface{}, expiresAt time.Time) bool) {
+	type entry struct {
+		key string
+		val interface{}
+		t   int64
+	}
+	g.mu.RLock()
+	now := g.now()
+	entries := make([]entry, 0, len(g.m))
+	for key, c := range g.m {
+		if t := g.t[key]; c.done && t > now {
+			entries = append(entries, entry{key, c.val, t})
+		}
+	}
+	g.mu.RUnlock()
+
+	for _, e := range entries {
+		var expiresAt time.Time
+		if e.t != math.MaxInt64 {
+			expiresAt = time.Unix(0, e.t)
+		}
+		if !fn(e.key, e.val, expiresAt) {
+			return
+		}
+	}
+}
+
+// TTL 返回key的结果剩余的有效时长。key不存在或者已经过期时返回(0, false)；永不
+// 过期的结果返回time.Duration的最大值；还在调用中的key返回其记录的有效期。不会等待
+// 或者延长调用。
+func (g *Group) TTL(key string) (time.Duration, bool) {
+	key = g.normalize(key)
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	t, ok := g.t[key]
+	if !ok {
+		return 0, false
+	}
+	return remaining(t, g.now())
+}
+
+// Peek 返回key已完成并且还未过期的结果，不会执行方法，也不会等待正在进行的调用。
+// key不存在、已经过期或者还在调用中时ok为false，被ExpireNow保留的结果除外。
+func (g *Group) Peek(key string) (val interface{}, err error, ok bool) {
+	r, _, ok := g.PeekResult(key)
+	return r.Val, r.Err, ok
+}
+
+// PeekResult 像Peek方法，同时返回结果剩余的有效时长。Result的Shared标识此结果是否
+// 已经在多个调用者之间共享过。
+func (g *Group) PeekResult(key string) (r Result, ttl time.Duration, ok bool) {
+	key = g.normalize(key)
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	c, ok := g.m[key]
+	if !ok || !c.done {
+		return Result{}, 0, false
+	}
+	ttl, ok = remaining(g.t[key], g.now())
+	if !ok && !c.expiredEarly {
+		return Result{}, 0, false
+	}
+	return c.result(c.dups > 0), ttl, true
+}
+
+// remaining 返回过期时间t距离now的剩余时长，已经过期时返回(0, false)。
+func remaining(t, now int64) (time.Duration, bool) {
+	if t == math.MaxInt64 {
+		return math.MaxInt64, true
+	}
+	if t <= now {
+		return 0, false
+	}
+	return time.Duration(t - now), true
+}
+
+// ForgetAll 方法像对每一个key调用Forget一样遗忘掉所有的key，返回被遗忘的key的
+// 数量。正在进行的调用仍然会把结果交给已经在等待的调用者。
+func (g *Group) ForgetAll() int {
+	var evs []eviction
+	var pending map[string]*call
+	g.mu.Lock()
+	n := len(g.m)
+	for key, c := range g.m {
+		c.forgotten = true
+		evs = g.evict(evs, key, c, EvictReset)
+		pending = g.pend(pending, key, c)
+	}
+	g.m = nil
+	g.t = nil
+	g.lru = nil
+	g.cost = 0
+	g.pending = 0
+	g.tombs = nil
+	g.unpublishAll()
+	g.stats.forgets.Add(uint64(n))
+	ds := g.orphanAll(&evs, pending)
+	g.mu.Unlock()
+	g.notifyEvicted(evs)
+	for _, d := range ds {
+		g.deliver(d)
+	}
+	return n
+}
+
+// ForgetFunc 方法遗忘掉所有match返回true的key，返回被遗忘的key的数量。match在持有
+// 锁时被调用，不能再调用Group的方法。
+func (g *Group) ForgetFunc(match func(key string) bool) int {
+	var evs []eviction
+	var pending map[string]*call
+	g.mu.Lock()
+	n := 0
+	for key, c := range g.m {
+		if match(key) {
+			if !c.done {
+				g.pending--
+			}
+			c.forgotten = true
+			g.untrack(c)
+			delete(g.m, key)
+			delete(g.t, key)
+			g.unpublish(key)
+			n++
+			evs = g.evict(evs, key, c, EvictForgotten)
+			pending = g.pend(pending, key, c)
+		}
+	}
+	g.stats.forgets.Add(uint64(n))
+	ds := g.orphanAll(&evs, pending)
+	g.mu.Unlock()
+	g.notifyEvicted(evs)
+	for _, d := range ds {
+		g.deliver(d)
+	}
+	return n
+}
+
+// ForgetPrefix 方法遗忘掉所有以prefix开头的key，返回被遗忘的key的数量。
+func (g *Group) ForgetPrefix(prefix string) int {
+	return g.ForgetFunc(func(key string) bool {
+		return strings.HasPrefix(key, prefix)
+	})
+}
+
+// getValidTime 根据配置的可以时间，获得最终有效时间的纳秒时间戳：NoExpiration
+// 以及默认模式下的0对应math.MaxInt64，严格模式下不缓存的有效时长对应当前时间，
+// 即立即过期。
+func (g *Group) getValidTime(validTime time.Duration) int64 {
 	var t int64
-	if validTime == 0 {
+	if validTime == NoExpiration || validTime == 0 && !g.strictTTL {
 		t = math.MaxInt64
+	} else if g.uncached(validTime) {
+		t = g.now()
 	} else {
-		t = time.Now().Unix() + int64(validTime/time.Second)
+		t = addTime(g.now(), g.jittered(validTime))
 	}
 	return t
 }
+
+// pendingValidTime 像getValidTime，但是用于新建的调用：不缓存的调用在执行期间同样
+// 可以被重复的调用者共享，直到完成时才被删除。
+func (g *Group) pendingValidTime(validTime time.Duration) int64 {
+	if g.uncached(validTime) {
+		return math.MaxInt64
+	}
+	return g.getValidTime(validTime)
+}
+
+// uncached 报告严格模式下有效时长validTime是否表示不缓存结果，见WithStrictTTL。
+func (g *Group) uncached(validTime time.Duration) bool {
+	return g.strictTTL && validTime <= 0 && validTime != NoExpiration
+}
+
+// jittered 根据WithJitter的配置为有效时长加入随机抖动，抖动后的时长至少为1纳秒。
+func (g *Group) jittered(validTime time.Duration) time.Duration {
+	if g.jitter <= 0 || validTime <= 0 {
+		return validTime
+	}
+	random := g.rand
+	if random == nil {
+		random = rand.Float64
+	}
+	d := time.Duration(float64(validTime) * (1 + g.jitter*(2*random()-1)))
+	if d < 1 {
+		d = 1
+	}
+	return d
+}
+
+// addTime 将时长加到纳秒时间戳上，溢出时返回math.MaxInt64。
+func addTime(t int64, d time.Duration) int64 {
+	if d > 0 && t > math.MaxInt64-int64(d) {
+		return math.MaxInt64
+	}
+	return t + int64(d)
+}
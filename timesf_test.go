@@ -1,8 +1,11 @@
 package timesf
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"math"
+	"sort"
 	"strconv"
 	"sync"
 	"sync/atomic"
@@ -158,32 +161,2131 @@ func TestForget(t *testing.T) {
 }
 
 func TestDoValidTime(t *testing.T) {
-	var g Group
+	clock := newFakeClock()
+	g := New(WithClock(clock))
 	var count int64 = 0
 	key := "key"
 
+	release := make(chan struct{})
 	fn := func() (i interface{}, e error) {
-		atomic.AddInt64(&count, 1)
-		c := count
-		time.Sleep(1 * time.Second)
+		c := atomic.AddInt64(&count, 1)
+		<-release
 		return "result" + strconv.FormatInt(c, 10), nil
 	}
 
+	var wg sync.WaitGroup
 	fnGo := func(round int) {
+		defer wg.Done()
 		result, err, shared := g.Do(key, 1*time.Second, fn)
-		fmt.Printf("%d:   result %v, err: %v, shared: %v\n", round, result, err, shared)
+		if round <= 2 && result != "result1" || round > 2 && result != "result2" {
+			t.Errorf("%d: result %v, err: %v, shared: %v", round, result, err, shared)
+		}
+	}
+	calls := func() uint64 {
+		s := g.Stats()
+		return s.Hits + s.Coalesced + s.Misses
 	}
 
+	wg.Add(2)
 	go fnGo(1)
 	go fnGo(2)
+	waitFor(t, func() bool { return calls() == 2 })
+	close(release)
+	wg.Wait()
 
-	time.Sleep(2 * time.Second)
+	clock.Advance(2 * time.Second)
 
+	wg.Add(2)
 	go fnGo(3)
 	go fnGo(4)
-	time.Sleep(3 * time.Second)
+	wg.Wait()
 
 	if count != 2 {
 		t.Errorf("valid time is not working")
 	}
 }
+
+func TestLen(t *testing.T) {
+	var g Group
+	if n := g.Len(); n != 0 {
+		t.Errorf("Len of zero Group = %d; want 0", n)
+	}
+
+	release := make(chan struct{})
+	fn := func() (interface{}, error) {
+		<-release
+		return nil, nil
+	}
+	chs := make([]<-chan Result, 0, 3)
+	for i, key := range []string{"a", "b", "c"} {
+		chs = append(chs, g.DoChan(key, 100*time.Second, fn))
+		if n := g.Len(); n != i+1 {
+			t.Errorf("Len after %d keys = %d; want %d", i+1, n, i+1)
+		}
+	}
+
+	g.Forget("a")
+	if n := g.Len(); n != 2 {
+		t.Errorf("Len after Forget = %d; want 2", n)
+	}
+
+	close(release)
+	for _, ch := range chs {
+		<-ch
+	}
+	if n := g.Len(); n != 2 {
+		t.Errorf("Len after completion = %d; want 2", n)
+	}
+}
+
+func TestDoCached(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithClock(clock))
+	var calls int32
+	fn := func() (interface{}, error) {
+		return atomic.AddInt32(&calls, 1), nil
+	}
+	for i := 0; i < 3; i++ {
+		v, err, _ := g.Do("key", 100*time.Millisecond, fn)
+		if err != nil || v != int32(1) {
+			t.Errorf("Do = %v, %v; want 1, nil", v, err)
+		}
+	}
+	r := <-g.DoChan("key", 100*time.Millisecond, fn)
+	if r.Val != int32(1) || !r.Shared {
+		t.Errorf("DoChan on cached key = %+v; want {1 <nil> true}", r)
+	}
+
+	clock.Advance(100 * time.Millisecond)
+	if v, _, _ := g.Do("key", 100*time.Millisecond, fn); v != int32(2) {
+		t.Errorf("Do after expiry = %v; want 2", v)
+	}
+}
+
+func TestDoStale(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithClock(clock))
+	var calls int32
+	refreshing := make(chan struct{})
+	fn := func() (interface{}, error) {
+		n := atomic.AddInt32(&calls, 1)
+		if n > 1 {
+			<-refreshing
+		}
+		return n, nil
+	}
+
+	v, _, _, stale := g.DoStale("key", 50*time.Millisecond, time.Second, fn)
+	if v != int32(1) || stale {
+		t.Fatalf("DoStale = %v, stale %v; want 1, false", v, stale)
+	}
+	clock.Advance(80 * time.Millisecond)
+
+	// 过期之后并发的调用者都立即拿到旧值，只有一个协程进行刷新。
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err, shared, stale := g.DoStale("key", 50*time.Millisecond, time.Second, fn)
+			if v != int32(1) || err != nil || !shared || !stale {
+				t.Errorf("DoStale = %v, %v, %v, %v; want 1, nil, true, true", v, err, shared, stale)
+			}
+		}()
+	}
+	wg.Wait()
+	close(refreshing)
+
+	waitFor(t, func() bool {
+		_, _, ok := g.Peek("key")
+		return ok
+	})
+	v, _, _, stale = g.DoStale("key", 50*time.Millisecond, time.Second, fn)
+	if v != int32(2) || stale {
+		t.Errorf("DoStale after refresh = %v, stale %v; want 2, false", v, stale)
+	}
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Errorf("number of calls = %d; want 2", got)
+	}
+}
+
+func TestDoStaleRefreshError(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithClock(clock))
+	someErr := errors.New("some error")
+	var calls int32
+	fn := func() (interface{}, error) {
+		if atomic.AddInt32(&calls, 1) > 1 {
+			return nil, someErr
+		}
+		return "old", nil
+	}
+
+	g.DoStale("key", 20*time.Millisecond, 100*time.Millisecond, fn)
+	clock.Advance(30 * time.Millisecond)
+	for i := 0; i < 3; i++ {
+		v, err, _, stale := g.DoStale("key", 20*time.Millisecond, 100*time.Millisecond, fn)
+		if v != "old" || err != nil || !stale {
+			t.Errorf("DoStale = %v, %v, stale %v; want old, nil, true", v, err, stale)
+		}
+		waitFor(t, func() bool { return g.Stats().Errors == uint64(i+1) })
+		clock.Advance(10 * time.Millisecond)
+	}
+
+	// 旧值超过staleFor之后，调用者像Do一样等待方法的结果。
+	clock.Advance(100 * time.Millisecond)
+	v, err, _, stale := g.DoStale("key", 20*time.Millisecond, 100*time.Millisecond, fn)
+	if v != nil || err != someErr || stale {
+		t.Errorf("DoStale after stale window = %v, %v, stale %v; want nil, someErr, false", v, err, stale)
+	}
+}
+
+func TestKeys(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithClock(clock))
+	if keys := g.Keys(); len(keys) != 0 {
+		t.Errorf("Keys of zero Group = %v; want empty", keys)
+	}
+
+	fn := func() (interface{}, error) {
+		return nil, nil
+	}
+	g.Do("a", 100*time.Second, fn)
+	g.Do("b", 100*time.Second, fn)
+	g.Do("c", 100*time.Second, fn)
+	g.Do("expired", 10*time.Millisecond, fn)
+	g.Forget("b")
+	clock.Advance(20 * time.Millisecond)
+
+	keys := g.Keys()
+	sort.Strings(keys)
+	if got, want := fmt.Sprint(keys), "[a c]"; got != want {
+		t.Errorf("Keys = %v; want %v", got, want)
+	}
+
+	keys[0] = "mutated"
+	if keys := g.Keys(); len(keys) != 2 || keys[0] == "mutated" || keys[1] == "mutated" {
+		t.Errorf("Keys returned shared slice: %v", keys)
+	}
+}
+
+func TestHas(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithClock(clock))
+	fn := func() (interface{}, error) {
+		return nil, nil
+	}
+	g.Do("cached", time.Minute, fn)
+	g.Do("expired", time.Second, fn)
+	release := make(chan struct{})
+	ch := g.DoChan("inflight", time.Second, func() (interface{}, error) {
+		<-release
+		return nil, nil
+	})
+	defer func() {
+		close(release)
+		<-ch
+	}()
+	clock.Advance(2 * time.Second)
+
+	for key, want := range map[string]bool{"cached": true, "expired": false, "inflight": true, "absent": false} {
+		if got := g.Has(key); got != want {
+			t.Errorf("Has(%q) = %v; want %v", key, got, want)
+		}
+	}
+}
+
+func TestTTL(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithClock(clock))
+	fn := func() (interface{}, error) {
+		return nil, nil
+	}
+	g.Do("fresh", time.Hour, fn)
+	g.Do("near", 30*time.Millisecond, fn)
+	g.Do("expired", 10*time.Millisecond, fn)
+	g.Do("forever", 0, fn)
+	clock.Advance(15 * time.Millisecond)
+
+	tests := []struct {
+		key string
+		ok  bool
+		ttl time.Duration
+	}{
+		{"fresh", true, time.Hour - 15*time.Millisecond},
+		{"near", true, 15 * time.Millisecond},
+		{"expired", false, 0},
+		{"absent", false, 0},
+		{"forever", true, math.MaxInt64},
+	}
+	for _, tt := range tests {
+		d, ok := g.TTL(tt.key)
+		if ok != tt.ok || d != tt.ttl {
+			t.Errorf("TTL(%q) = %v, %v; want %v, %v", tt.key, d, ok, tt.ttl, tt.ok)
+		}
+	}
+}
+
+func TestLoadOrCompute(t *testing.T) {
+	var g Group
+	release := make(chan struct{})
+	started := make(chan struct{})
+	leader := make(chan bool)
+	go func() {
+		v, loaded, err := g.LoadOrCompute("key", time.Hour, func() (interface{}, error) {
+			close(started)
+			<-release
+			return "bar", nil
+		})
+		if v != "bar" || err != nil {
+			t.Errorf("leader LoadOrCompute = %v, %v; want bar, nil", v, err)
+		}
+		leader <- loaded
+	}()
+	<-started
+
+	coalesced := make(chan bool)
+	go func() {
+		v, loaded, _ := g.LoadOrCompute("key", time.Hour, nil)
+		if v != "bar" {
+			t.Errorf("coalesced LoadOrCompute = %v; want bar", v)
+		}
+		coalesced <- loaded
+	}()
+	waitFor(t, func() bool { return g.InFlight()["key"] == 1 })
+	close(release)
+	if <-leader {
+		t.Error("leader loaded = true; want false")
+	}
+	if <-coalesced {
+		t.Error("coalesced loaded = true; want false")
+	}
+
+	for i := 0; i < 2; i++ { // 第一次加锁命中，之后走无锁路径
+		v, loaded, err := g.LoadOrCompute("key", time.Hour, nil)
+		if v != "bar" || !loaded || err != nil {
+			t.Errorf("cached LoadOrCompute = %v, %v, %v; want bar, true, nil", v, loaded, err)
+		}
+	}
+}
+
+func TestPeek(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithClock(clock))
+	if _, _, ok := g.Peek("absent"); ok {
+		t.Errorf("Peek on zero Group ok = true; want false")
+	}
+
+	var calls int32
+	fn := func() (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		return "bar", nil
+	}
+	g.Do("key", time.Hour, fn)
+	g.Do("expired", 10*time.Millisecond, fn)
+	release := make(chan struct{})
+	ch := g.DoChan("inflight", time.Hour, func() (interface{}, error) {
+		<-release
+		return "baz", nil
+	})
+	clock.Advance(15 * time.Millisecond)
+
+	if v, err, ok := g.Peek("key"); v != "bar" || err != nil || !ok {
+		t.Errorf("Peek(key) = %v, %v, %v; want bar, nil, true", v, err, ok)
+	}
+	if r, ttl, ok := g.PeekResult("key"); r.Val != "bar" || !ok || ttl != time.Hour-15*time.Millisecond {
+		t.Errorf("PeekResult(key) = %+v, %v, %v; want bar, %v, true", r, ttl, ok, time.Hour-15*time.Millisecond)
+	}
+	for _, key := range []string{"expired", "inflight", "absent"} {
+		if _, _, ok := g.Peek(key); ok {
+			t.Errorf("Peek(%q) ok = true; want false", key)
+		}
+	}
+
+	close(release)
+	<-ch
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Errorf("number of calls = %d; want 2", got)
+	}
+	if s := g.Stats(); s.Hits != 0 {
+		t.Errorf("Peek counted as hit: %+v", s)
+	}
+}
+
+func TestSet(t *testing.T) {
+	var g Group
+	var calls int32
+	fn := func() (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		return "computed", nil
+	}
+
+	g.Set("key", "primed", time.Hour)
+	if v, _, shared := g.Do("key", time.Hour, fn); v != "primed" || !shared {
+		t.Errorf("Do after Set = %v, shared %v; want primed, true", v, shared)
+	}
+
+	g.Set("forever", "primed", 0)
+	if ttl, ok := g.TTL("forever"); !ok || ttl != math.MaxInt64 {
+		t.Errorf("TTL after Set with 0 = %v, %v; want no expiry", ttl, ok)
+	}
+
+	// 正在进行的调用为已有的等待者完成，之后的调用者拿到写入的值。
+	release := make(chan struct{})
+	ch := g.DoChan("inflight", time.Hour, func() (interface{}, error) {
+		<-release
+		return "old", nil
+	})
+	ch2 := g.DoChan("inflight", time.Hour, fn)
+	g.Set("inflight", "new", time.Hour)
+	close(release)
+	if r := <-ch; r.Val != "old" {
+		t.Errorf("in-flight caller got %v; want old", r.Val)
+	}
+	if r := <-ch2; r.Val != "old" {
+		t.Errorf("in-flight waiter got %v; want old", r.Val)
+	}
+	if v, _, _ := g.Do("inflight", time.Hour, fn); v != "new" {
+		t.Errorf("Do after Set on in-flight key = %v; want new", v)
+	}
+	if got := atomic.LoadInt32(&calls); got != 0 {
+		t.Errorf("number of calls = %d; want 0", got)
+	}
+}
+
+func TestDoStaleJoinsRefreshAfterWindow(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithClock(clock))
+	var calls int32
+	release := make(chan struct{})
+	fn := func() (interface{}, error) {
+		n := atomic.AddInt32(&calls, 1)
+		if n > 1 {
+			<-release
+		}
+		return n, nil
+	}
+
+	g.DoStale("key", 10*time.Millisecond, 20*time.Millisecond, fn)
+	clock.Advance(15 * time.Millisecond)
+
+	// 第一个过期后的调用者立即拿到旧值。
+	if v, _, _, stale := g.DoStale("key", 10*time.Millisecond, 20*time.Millisecond, fn); v != int32(1) || !stale {
+		t.Errorf("DoStale = %v, stale %v; want 1, true", v, stale)
+	}
+
+	// 旧值不能再返回后，调用者等待正在进行的刷新，而不是再执行一次方法。
+	clock.Advance(20 * time.Millisecond)
+	done := make(chan interface{})
+	go func() {
+		v, _, _, _ := g.DoStale("key", 10*time.Millisecond, 20*time.Millisecond, fn)
+		done <- v
+	}()
+	waitFor(t, func() bool { return g.Stats().Coalesced == 1 })
+	close(release)
+	if v := <-done; v != int32(2) {
+		t.Errorf("DoStale after stale window = %v; want 2", v)
+	}
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Errorf("number of calls = %d; want 2", got)
+	}
+}
+
+func TestForgetAll(t *testing.T) {
+	var g Group
+	if n := g.ForgetAll(); n != 0 {
+		t.Errorf("ForgetAll on zero Group = %d; want 0", n)
+	}
+
+	var calls int32
+	fn := func() (interface{}, error) {
+		return atomic.AddInt32(&calls, 1), nil
+	}
+	g.Do("a", time.Hour, fn)
+	g.Do("b", time.Hour, fn)
+	release := make(chan struct{})
+	ch := g.DoChan("inflight", time.Hour, func() (interface{}, error) {
+		<-release
+		return "old", nil
+	})
+	ch2 := g.DoChan("inflight", time.Hour, fn)
+
+	if n := g.ForgetAll(); n != 3 {
+		t.Errorf("ForgetAll = %d; want 3", n)
+	}
+	if n := g.Len(); n != 0 {
+		t.Errorf("Len after ForgetAll = %d; want 0", n)
+	}
+
+	close(release)
+	if r := <-ch; r.Val != "old" {
+		t.Errorf("in-flight caller got %v; want old", r.Val)
+	}
+	if r := <-ch2; r.Val != "old" {
+		t.Errorf("in-flight waiter got %v; want old", r.Val)
+	}
+	if n := g.Len(); n != 0 {
+		t.Errorf("Len after forgotten call completed = %d; want 0", n)
+	}
+	if v, _, _ := g.Do("a", time.Hour, fn); v != int32(3) {
+		t.Errorf("Do after ForgetAll = %v; want 3", v)
+	}
+}
+
+func TestForgetPrefix(t *testing.T) {
+	var g Group
+	fn := func() (interface{}, error) {
+		return nil, nil
+	}
+	for _, key := range []string{"tenant:1:user:1", "tenant:1:user:2", "tenant:12:user:1", "tenant:2:user:1"} {
+		g.Do(key, time.Hour, fn)
+	}
+	if n := g.ForgetPrefix("tenant:1:"); n != 2 {
+		t.Errorf("ForgetPrefix = %d; want 2", n)
+	}
+	keys := g.Keys()
+	sort.Strings(keys)
+	if got, want := fmt.Sprint(keys), "[tenant:12:user:1 tenant:2:user:1]"; got != want {
+		t.Errorf("Keys after ForgetPrefix = %v; want %v", got, want)
+	}
+
+	if n := g.ForgetFunc(func(key string) bool { return key == "tenant:2:user:1" }); n != 1 {
+		t.Errorf("ForgetFunc = %d; want 1", n)
+	}
+	if n := g.Len(); n != 1 {
+		t.Errorf("Len after ForgetFunc = %d; want 1", n)
+	}
+}
+
+func TestForgetPrefixInFlight(t *testing.T) {
+	var g Group
+	release := make(chan struct{})
+	var calls int32
+	fn := func() (interface{}, error) {
+		<-release
+		return atomic.AddInt32(&calls, 1), nil
+	}
+	inflight := g.DoChan("user:123:profile", time.Hour, fn)
+	other := g.DoChan("user:456:profile", time.Hour, fn)
+	if n := g.ForgetPrefix("user:123:"); n != 1 {
+		t.Errorf("ForgetPrefix = %d; want 1", n)
+	}
+	close(release)
+	<-inflight
+	<-other
+
+	// 被遗忘的调用完成后不能再写回结果
+	if _, _, ok := g.Peek("user:123:profile"); ok {
+		t.Error("forgotten in-flight call repopulated its key")
+	}
+	if _, _, ok := g.Peek("user:456:profile"); !ok {
+		t.Error("ForgetPrefix removed a key without the prefix")
+	}
+}
+
+func BenchmarkForgetPrefix(b *testing.B) {
+	const n = 1000000
+	var g Group
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		for j := 0; j < n; j++ {
+			g.Set("tenant:"+strconv.Itoa(j%100)+":user:"+strconv.Itoa(j), j, time.Hour)
+		}
+		b.StartTimer()
+		g.ForgetPrefix("tenant:7:")
+	}
+}
+
+func TestDoWithTTLs(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithErrorCaching(true), WithClock(clock))
+	someErr := errors.New("some error")
+	var errCalls, okCalls int32
+	errFn := func() (interface{}, error) {
+		atomic.AddInt32(&errCalls, 1)
+		return nil, someErr
+	}
+	okFn := func() (interface{}, error) {
+		atomic.AddInt32(&okCalls, 1)
+		return "bar", nil
+	}
+
+	g.DoWithTTLs("err", time.Hour, 20*time.Millisecond, errFn)
+	g.DoWithTTLs("ok", 200*time.Millisecond, 20*time.Millisecond, okFn)
+	g.DoWithTTLs("err", time.Hour, 20*time.Millisecond, errFn)
+	if got := atomic.LoadInt32(&errCalls); got != 1 {
+		t.Errorf("error calls inside error ttl = %d; want 1", got)
+	}
+
+	clock.Advance(40 * time.Millisecond)
+	g.DoWithTTLs("err", time.Hour, 20*time.Millisecond, errFn)
+	g.DoWithTTLs("ok", 200*time.Millisecond, 20*time.Millisecond, okFn)
+	if got := atomic.LoadInt32(&errCalls); got != 2 {
+		t.Errorf("error calls after error ttl = %d; want 2", got)
+	}
+	if got := atomic.LoadInt32(&okCalls); got != 1 {
+		t.Errorf("success calls inside success ttl = %d; want 1", got)
+	}
+
+	// errorTTL为0时不缓存错误，即使Group开启了错误缓存。
+	g.DoWithTTLs("nocache", time.Hour, 0, errFn)
+	g.DoWithTTLs("nocache", time.Hour, 0, errFn)
+	if got := atomic.LoadInt32(&errCalls); got != 4 {
+		t.Errorf("error calls with zero error ttl = %d; want 4", got)
+	}
+}
+
+func TestForgetStatus(t *testing.T) {
+	var g Group
+	g.Do("cached", time.Hour, func() (interface{}, error) {
+		return nil, nil
+	})
+	release := make(chan struct{})
+	ch := g.DoChan("inflight", time.Hour, func() (interface{}, error) {
+		<-release
+		return nil, nil
+	})
+	defer func() {
+		close(release)
+		<-ch
+	}()
+
+	tests := []struct {
+		key         string
+		existed     bool
+		wasInFlight bool
+	}{
+		{"cached", true, false},
+		{"inflight", true, true},
+		{"absent", false, false},
+		{"cached", false, false},
+	}
+	for _, tt := range tests {
+		existed, wasInFlight := g.ForgetStatus(tt.key)
+		if existed != tt.existed || wasInFlight != tt.wasInFlight {
+			t.Errorf("ForgetStatus(%q) = %v, %v; want %v, %v", tt.key, existed, wasInFlight, tt.existed, tt.wasInFlight)
+		}
+	}
+}
+
+func TestForgetUnshared(t *testing.T) {
+	var g Group
+	release := make(chan struct{})
+	fn := func() (interface{}, error) {
+		<-release
+		return nil, nil
+	}
+	unshared := g.DoChan("unshared", time.Hour, fn)
+	shared := g.DoChan("shared", time.Hour, fn)
+	dup := g.DoChan("shared", time.Hour, fn)
+	defer func() {
+		close(release)
+		<-unshared
+		<-shared
+		<-dup
+	}()
+
+	if !g.ForgetUnshared("unshared") {
+		t.Error("ForgetUnshared(unshared) = false; want true")
+	}
+	if _, ok := g.TTL("unshared"); ok {
+		t.Error("unshared key still present after ForgetUnshared")
+	}
+	if g.ForgetUnshared("shared") {
+		t.Error("ForgetUnshared(shared) = true; want false")
+	}
+	if _, ok := g.TTL("shared"); !ok {
+		t.Error("shared key removed by ForgetUnshared")
+	}
+	if !g.ForgetUnshared("absent") {
+		t.Error("ForgetUnshared(absent) = false; want true")
+	}
+}
+
+func TestDoWithTTL(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithClock(clock))
+	release := make(chan struct{})
+	started := make(chan struct{})
+	var calls int32
+	fn := func() (interface{}, time.Duration, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(started)
+			<-release
+		}
+		return "bar", time.Minute, nil
+	}
+
+	ch := g.DoChanWithTTL("key", fn)
+	<-started
+	// 方法执行期间有效期还没有开始计算
+	clock.Advance(time.Hour)
+	done := make(chan struct{})
+	go func() {
+		if v, _, shared := g.DoWithTTL("key", fn); v != "bar" || !shared {
+			t.Errorf("DoWithTTL = %v, %v; want bar, true", v, shared)
+		}
+		close(done)
+	}()
+	waitFor(t, func() bool { return g.Stats().Coalesced == 1 })
+	close(release)
+	<-ch
+	<-done
+	if ttl, ok := g.TTL("key"); !ok || ttl != time.Minute {
+		t.Errorf("TTL = %v, %v; want 1m, true", ttl, ok)
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("calls = %d; want 1", n)
+	}
+
+	for _, ttl := range []time.Duration{0, -time.Second} {
+		g.DoWithTTL("uncached", func() (interface{}, time.Duration, error) {
+			return "bar", ttl, nil
+		})
+		if _, ok := g.TTL("uncached"); ok {
+			t.Errorf("result with TTL %v was cached", ttl)
+		}
+	}
+}
+
+func TestDoWithTTLFunc(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithClock(clock))
+	ttlFunc := func(val interface{}, err error) time.Duration {
+		switch val {
+		case "short":
+			return time.Second
+		case "forever":
+			return 0
+		}
+		return -1
+	}
+	for _, key := range []string{"short", "forever", "uncached"} {
+		key := key
+		v, err, _ := g.DoWithTTLFunc(key, func() (interface{}, error) {
+			return key, nil
+		}, ttlFunc)
+		if v != key || err != nil {
+			t.Errorf("DoWithTTLFunc(%q) = %v, %v; want %v, nil", key, v, err, key)
+		}
+	}
+
+	if ttl, ok := g.TTL("short"); !ok || ttl != time.Second {
+		t.Errorf("TTL(short) = %v, %v; want 1s, true", ttl, ok)
+	}
+	if ttl, ok := g.TTL("forever"); !ok || ttl != time.Duration(math.MaxInt64) {
+		t.Errorf("TTL(forever) = %v, %v; want max, true", ttl, ok)
+	}
+	if _, ok := g.TTL("uncached"); ok {
+		t.Error("result with negative TTL was cached")
+	}
+	clock.Advance(2 * time.Second)
+	if g.Has("short") || !g.Has("forever") {
+		t.Errorf("Has after 2s = %v, %v; want false, true", g.Has("short"), g.Has("forever"))
+	}
+}
+
+func TestTouch(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithClock(clock))
+	fn := func() (interface{}, error) {
+		return nil, nil
+	}
+	g.Do("cached", time.Minute, fn)
+	g.Do("expired", time.Second, fn)
+	g.Do("forgotten", time.Minute, fn)
+	g.Forget("forgotten")
+	release := make(chan struct{})
+	ch := g.DoChan("inflight", time.Minute, func() (interface{}, error) {
+		<-release
+		return nil, nil
+	})
+	defer func() {
+		close(release)
+		<-ch
+	}()
+	clock.Advance(time.Second)
+
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"cached", true},
+		{"expired", false},
+		{"inflight", false},
+		{"forgotten", false},
+		{"absent", false},
+	}
+	for _, tt := range tests {
+		if got := g.Touch(tt.key, time.Hour); got != tt.want {
+			t.Errorf("Touch(%q) = %v; want %v", tt.key, got, tt.want)
+		}
+	}
+	if ttl, _ := g.TTL("cached"); ttl != time.Hour {
+		t.Errorf("TTL after Touch = %v; want 1h", ttl)
+	}
+	if _, ok := g.TTL("forgotten"); ok {
+		t.Error("Touch resurrected a forgotten key")
+	}
+}
+
+func TestDoChanAbandonedWaiter(t *testing.T) {
+	var g Group
+	release := make(chan struct{})
+	fn := func() (interface{}, error) {
+		<-release
+		return "bar", nil
+	}
+	const n = 50
+	chans := make([]<-chan Result, n)
+	for i := range chans {
+		chans[i] = g.DoChan("key", time.Hour, fn)
+	}
+	close(release)
+
+	// 第一个通道永远不被读取，其余的调用者和之后的操作都不受影响
+	for _, ch := range chans[1:] {
+		if r := <-ch; r.Val != "bar" || !r.Shared {
+			t.Errorf("DoChan result = %+v; want shared bar", r)
+		}
+	}
+	waitFor(t, func() bool {
+		_, _, ok := g.Peek("key")
+		return ok
+	})
+	if v, _, _ := g.Do("other", time.Hour, func() (interface{}, error) { return "baz", nil }); v != "baz" {
+		t.Errorf("Do(other) = %v; want baz", v)
+	}
+	g.Forget("key")
+	if n := g.Len(); n != 1 {
+		t.Errorf("Len = %d; want 1", n)
+	}
+}
+
+func TestDoSharedRace(t *testing.T) {
+	// 在-race下运行：执行方法的调用者不能在释放锁之后读取重复数量
+	var g Group
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		key := strconv.Itoa(i)
+		for j := 0; j < 4; j++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				g.Do(key, time.Hour, func() (interface{}, error) {
+					time.Sleep(time.Millisecond)
+					return nil, nil
+				})
+			}()
+		}
+	}
+	wg.Wait()
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	leader := make(chan bool)
+	go func() {
+		_, _, shared := g.Do("shared", time.Hour, func() (interface{}, error) {
+			close(started)
+			<-release
+			return nil, nil
+		})
+		leader <- shared
+	}()
+	<-started
+	ch := g.DoChan("shared", time.Hour, nil)
+	close(release)
+	<-ch
+	if !<-leader {
+		t.Error("leader shared = false; want true after another caller joined")
+	}
+	if _, _, shared := g.Do("alone", time.Hour, func() (interface{}, error) { return nil, nil }); shared {
+		t.Error("shared = true for a call nobody joined")
+	}
+}
+
+func TestDoExpiredInFlight(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithClock(clock))
+	var running, maxRunning, calls int32
+	release := make(chan struct{})
+	fn := func() (interface{}, error) {
+		n := atomic.AddInt32(&running, 1)
+		if n > atomic.LoadInt32(&maxRunning) {
+			atomic.StoreInt32(&maxRunning, n)
+		}
+		atomic.AddInt32(&calls, 1)
+		<-release
+		atomic.AddInt32(&running, -1)
+		return "bar", nil
+	}
+
+	first := g.DoChan("key", time.Millisecond, fn)
+	waitFor(t, func() bool { return atomic.LoadInt32(&calls) == 1 })
+	// 有效期在方法执行期间过去，新的调用者仍然加入正在进行的调用
+	clock.Advance(time.Second)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, _, shared := g.Do("key", time.Millisecond, fn); v != "bar" || !shared {
+				t.Errorf("Do = %v, %v; want bar, true", v, shared)
+			}
+		}()
+	}
+	second := g.DoChan("key", time.Millisecond, fn)
+	waitFor(t, func() bool { return g.Stats().Coalesced == 11 })
+	close(release)
+	wg.Wait()
+	<-first
+	<-second
+	if c, m := atomic.LoadInt32(&calls), atomic.LoadInt32(&maxRunning); c != 1 || m != 1 {
+		t.Errorf("calls = %d, max concurrent = %d; want 1, 1", c, m)
+	}
+}
+
+func TestInFlight(t *testing.T) {
+	var g Group
+	g.Do("done", time.Hour, func() (interface{}, error) {
+		return nil, nil
+	})
+	release := make(chan struct{})
+	fn := func() (interface{}, error) {
+		<-release
+		return nil, nil
+	}
+	const n = 5
+	chans := []<-chan Result{g.DoChan("idle", time.Hour, fn)}
+	for i := 0; i <= n; i++ {
+		chans = append(chans, g.DoChan("hot", time.Hour, fn))
+	}
+	if got, want := fmt.Sprint(g.InFlight()), "map[hot:5 idle:0]"; got != want {
+		t.Errorf("InFlight = %v; want %v", got, want)
+	}
+	close(release)
+	for _, ch := range chans {
+		<-ch
+	}
+	if m := g.InFlight(); len(m) != 0 {
+		t.Errorf("InFlight after completion = %v; want empty", m)
+	}
+}
+
+func TestEntries(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithClock(clock))
+	start := clock.Now()
+	g.Do("done", time.Hour, func() (interface{}, error) {
+		return nil, nil
+	})
+	g.Set("forever", "v", NoExpiration)
+	clock.Advance(time.Second)
+	release := make(chan struct{})
+	fn := func() (interface{}, error) {
+		<-release
+		return nil, nil
+	}
+	ch := g.DoChan("hot", time.Minute, fn)
+	dup := g.DoChan("hot", time.Minute, fn)
+
+	if got, want := fmt.Sprint(g.InFlightKeys()), "[hot]"; got != want {
+		t.Errorf("InFlightKeys = %v; want %v", got, want)
+	}
+	entries := g.Entries()
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	want := []EntryInfo{
+		{Key: "done", StartedAt: start, ExpiresAt: start.Add(time.Hour)},
+		{Key: "forever", StartedAt: start},
+		{Key: "hot", InFlight: true, StartedAt: start.Add(time.Second), ExpiresAt: start.Add(time.Second + time.Minute), Dups: 1},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("Entries = %+v; want %+v", entries, want)
+	}
+	for i := range want {
+		e, w := entries[i], want[i]
+		if e.Key != w.Key || e.InFlight != w.InFlight || !e.StartedAt.Equal(w.StartedAt) || !e.ExpiresAt.Equal(w.ExpiresAt) || e.Dups != w.Dups {
+			t.Errorf("Entries[%d] = %+v; want %+v", i, e, w)
+		}
+	}
+
+	close(release)
+	<-ch
+	<-dup
+	if keys := g.InFlightKeys(); len(keys) != 0 {
+		t.Errorf("InFlightKeys after completion = %v; want empty", keys)
+	}
+}
+
+func TestDoDetailed(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithClock(clock))
+	// 有效期从调用开始时计算
+	expires := clock.Now().Add(time.Minute)
+	release := make(chan struct{})
+	started := make(chan struct{})
+	owner := make(chan Result)
+	go func() {
+		owner <- g.DoDetailed("key", time.Minute, func() (interface{}, error) {
+			close(started)
+			<-release
+			return "bar", nil
+		})
+	}()
+	<-started
+	waiter := make(chan Result)
+	go func() {
+		waiter <- g.DoDetailed("key", time.Minute, nil)
+	}()
+	ch := g.DoChan("key", time.Minute, nil)
+	waitFor(t, func() bool { return g.InFlight()["key"] == 2 })
+	clock.Advance(time.Second)
+	close(release)
+
+	for name, r := range map[string]Result{"owner": <-owner, "waiter": <-waiter, "DoChan": <-ch} {
+		if r.Val != "bar" || !r.Shared || r.Dups != 2 || r.HitAge != 0 || !r.ExpiresAt.Equal(expires) {
+			t.Errorf("%s result = %+v; want bar, shared, 2 dups, no age, expiring at %v", name, r, expires)
+		}
+	}
+
+	clock.Advance(10 * time.Second)
+	for i, r := range []Result{g.DoDetailed("key", time.Minute, nil), <-g.DoChan("key", time.Minute, nil), g.DoDetailed("key", time.Minute, nil)} {
+		if r.Val != "bar" || r.HitAge != 10*time.Second || !r.ExpiresAt.Equal(expires) {
+			t.Errorf("hit %d = %+v; want bar, 10s old, expiring at %v", i, r, expires)
+		}
+	}
+
+	r := g.DoDetailed("forever", NoExpiration, func() (interface{}, error) {
+		return nil, nil
+	})
+	if r.Shared || r.Dups != 0 || !r.ExpiresAt.IsZero() {
+		t.Errorf("unshared never-expiring result = %+v; want not shared, 0 dups, zero expiry", r)
+	}
+}
+
+func TestResultForgotten(t *testing.T) {
+	var g Group
+	release := make(chan struct{})
+	started := make(chan struct{})
+	owner := g.DoChan("key", time.Hour, func() (interface{}, error) {
+		close(started)
+		<-release
+		return "old", nil
+	})
+	<-started
+	waiter := g.DoChan("key", time.Hour, nil)
+	g.Forget("key")
+	close(release)
+	for _, ch := range []<-chan Result{owner, waiter} {
+		if r := <-ch; r.Val != "old" || !r.Forgotten {
+			t.Errorf("result forgotten mid-flight = %+v; want old, Forgotten", r)
+		}
+	}
+
+	r := <-g.DoChan("key", time.Hour, func() (interface{}, error) {
+		return "new", nil
+	})
+	if r.Val != "new" || r.Forgotten {
+		t.Errorf("fresh result = %+v; want new, not Forgotten", r)
+	}
+}
+
+func TestForgetAndNotify(t *testing.T) {
+	var g Group
+	release := make(chan struct{})
+	started := make(chan struct{})
+	leader := make(chan interface{})
+	go func() {
+		v, _, _ := g.Do("key", time.Hour, func() (interface{}, error) {
+			close(started)
+			<-release
+			return "stale", nil
+		})
+		leader <- v
+	}()
+	<-started
+	ch := g.DoChan("key", time.Hour, nil)
+	waiter := make(chan error)
+	go func() {
+		_, err, _ := g.Do("key", time.Hour, nil)
+		waiter <- err
+	}()
+	waitFor(t, func() bool { return g.Stats().Coalesced == 2 })
+
+	if !g.ForgetAndNotify("key") {
+		t.Error("ForgetAndNotify = false; want true")
+	}
+	if err := <-waiter; !errors.Is(err, ErrForgotten) {
+		t.Errorf("Do waiter err = %v; want ErrForgotten", err)
+	}
+	if r := <-ch; !errors.Is(r.Err, ErrForgotten) {
+		t.Errorf("DoChan result = %+v; want ErrForgotten", r)
+	}
+	if _, ok := <-ch; ok {
+		t.Error("DoChan channel not closed after ForgetAndNotify")
+	}
+
+	// 执行方法的调用者仍然拿到结果，但结果不会被缓存
+	close(release)
+	if v := <-leader; v != "stale" {
+		t.Errorf("leader value = %v; want stale", v)
+	}
+	if g.Has("key") {
+		t.Error("aborted call repopulated its key")
+	}
+	if g.ForgetAndNotify("key") {
+		t.Error("ForgetAndNotify of an absent key = true; want false")
+	}
+}
+
+func TestResultComputedAt(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithClock(clock))
+	computed := clock.Now()
+	r := <-g.DoChan("key", time.Hour, func() (interface{}, error) {
+		return "bar", nil
+	})
+	if !r.ComputedAt.Equal(computed) {
+		t.Errorf("ComputedAt = %v; want %v", r.ComputedAt, computed)
+	}
+
+	// 之后的命中保留原来的计算时间
+	clock.Advance(time.Minute)
+	for i := 0; i < 3; i++ {
+		if r := <-g.DoChan("key", time.Hour, nil); !r.ComputedAt.Equal(computed) {
+			t.Errorf("ComputedAt of hit %d = %v; want %v", i, r.ComputedAt, computed)
+		}
+	}
+	if r, _, _ := g.PeekResult("key"); !r.ComputedAt.Equal(computed) {
+		t.Errorf("PeekResult ComputedAt = %v; want %v", r.ComputedAt, computed)
+	}
+	if age := (Result{ComputedAt: time.Now().Add(-time.Hour)}).Age(); age < time.Hour {
+		t.Errorf("Age = %v; want at least 1h", age)
+	}
+}
+
+func TestSetValue(t *testing.T) {
+	var rec evictRecorder
+	g := New(WithOnEvict(rec.onEvict))
+	g.SetValue("key", "seeded", time.Hour)
+	v, _, _ := g.Do("key", time.Hour, func() (interface{}, error) {
+		t.Error("fn called for a seeded key")
+		return nil, nil
+	})
+	if v != "seeded" {
+		t.Errorf("Do = %v; want seeded", v)
+	}
+	if prev, ok := g.SetValue("key", "newer", time.Hour); prev != "seeded" || !ok {
+		t.Errorf("SetValue = %v, %v; want seeded, true", prev, ok)
+	}
+	if got, want := fmt.Sprint(rec.take()), "[key=seeded:replaced]"; got != want {
+		t.Errorf("evictions = %v; want %v", got, want)
+	}
+
+	// 正在调用中的key不会被替换
+	release := make(chan struct{})
+	ch := g.DoChan("inflight", time.Hour, func() (interface{}, error) {
+		<-release
+		return "computed", nil
+	})
+	if prev, ok := g.SetValue("inflight", "seeded", time.Hour); prev != nil || ok {
+		t.Errorf("SetValue on in-flight key = %v, %v; want nil, false", prev, ok)
+	}
+	close(release)
+	<-ch
+	if v, _, _ := g.Peek("inflight"); v != "computed" {
+		t.Errorf("Peek(inflight) = %v; want computed", v)
+	}
+}
+
+func TestSwap(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithClock(clock))
+	if prev, ok := g.Swap("key", 1, time.Second); prev != nil || ok {
+		t.Errorf("Swap on absent key = %v, %v; want nil, false", prev, ok)
+	}
+	if prev, ok := g.Swap("key", 2, time.Second); prev != 1 || !ok {
+		t.Errorf("Swap = %v, %v; want 1, true", prev, ok)
+	}
+	clock.Advance(time.Second)
+	if prev, ok := g.Swap("key", 3, time.Second); prev != nil || ok {
+		t.Errorf("Swap on expired key = %v, %v; want nil, false", prev, ok)
+	}
+
+	// 和Set一样替换正在进行的调用，但是没有被替换的值
+	release := make(chan struct{})
+	ch := g.DoChan("inflight", time.Second, func() (interface{}, error) {
+		<-release
+		return "computed", nil
+	})
+	if prev, ok := g.Swap("inflight", "swapped", time.Second); prev != nil || ok {
+		t.Errorf("Swap on in-flight key = %v, %v; want nil, false", prev, ok)
+	}
+	close(release)
+	<-ch
+	if v, _, _ := g.Peek("inflight"); v != "swapped" {
+		t.Errorf("Peek(inflight) = %v; want swapped", v)
+	}
+}
+
+func TestInvalidate(t *testing.T) {
+	var g Group
+	var calls int32
+	release := make(chan struct{})
+	fn := func() (interface{}, error) {
+		<-release
+		return atomic.AddInt32(&calls, 1), nil
+	}
+
+	// 正在进行的调用：Invalidate不影响，完成后结果照常缓存
+	ch := g.DoChan("invalidated", time.Hour, fn)
+	if g.Invalidate("invalidated") {
+		t.Error("Invalidate of an in-flight key = true; want false")
+	}
+	// 正在进行的调用：Forget之后结果不再缓存
+	forgotten := g.DoChan("forgotten", time.Hour, fn)
+	g.Forget("forgotten")
+	close(release)
+	<-ch
+	<-forgotten
+	if _, _, ok := g.Peek("invalidated"); !ok {
+		t.Error("in-flight result not cached after Invalidate")
+	}
+	if _, _, ok := g.Peek("forgotten"); ok {
+		t.Error("in-flight result cached after Forget")
+	}
+
+	// 已完成的结果被删除，下一次调用重新执行方法
+	if !g.Invalidate("invalidated") {
+		t.Error("Invalidate of a cached key = false; want true")
+	}
+	if v, _, _ := g.Do("invalidated", time.Hour, fn); v != int32(3) {
+		t.Errorf("Do after Invalidate = %v; want 3", v)
+	}
+	if g.Invalidate("absent") {
+		t.Error("Invalidate of an absent key = true; want false")
+	}
+}
+
+func TestForEach(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithClock(clock))
+	g.Set("a", 1, time.Minute)
+	g.Set("b", 2, 0)
+	g.Set("expired", 3, time.Second)
+	release := make(chan struct{})
+	ch := g.DoChan("inflight", time.Minute, func() (interface{}, error) {
+		<-release
+		return nil, nil
+	})
+	defer func() {
+		close(release)
+		<-ch
+	}()
+	clock.Advance(time.Second)
+
+	var seen []string
+	g.ForEach(func(key string, val interface{}, expiresAt time.Time) bool {
+		seen = append(seen, fmt.Sprintf("%s=%v@%v", key, val, expiresAt.IsZero()))
+		// 回调中可以调用Group的方法
+		g.Len()
+		return true
+	})
+	sort.Strings(seen)
+	if got, want := fmt.Sprint(seen), "[a=1@false b=2@true]"; got != want {
+		t.Errorf("ForEach visited %v; want %v", got, want)
+	}
+
+	n := 0
+	g.ForEach(func(string, interface{}, time.Time) bool {
+		n++
+		return false
+	})
+	if n != 1 {
+		t.Errorf("ForEach called fn %d times after it returned false; want 1", n)
+	}
+}
+
+func TestSharedSemantics(t *testing.T) {
+	do := func(g *Group, key string, fn func() (interface{}, error)) <-chan bool {
+		ch := make(chan bool, 1)
+		go func() {
+			_, _, shared := g.Do(key, time.Hour, fn)
+			ch <- shared
+		}()
+		return ch
+	}
+	doChan := func(g *Group, key string, fn func() (interface{}, error)) <-chan bool {
+		ch := make(chan bool, 1)
+		rc := g.DoChan(key, time.Hour, fn)
+		go func() {
+			ch <- (<-rc).Shared
+		}()
+		return ch
+	}
+	apis := []struct {
+		name string
+		call func(g *Group, key string, fn func() (interface{}, error)) <-chan bool
+	}{
+		{"Do", do},
+		{"DoChan", doChan},
+	}
+	for _, owner := range apis {
+		for _, joiner := range apis {
+			t.Run(owner.name+"/"+joiner.name, func(t *testing.T) {
+				var g Group
+				if shared := <-owner.call(&g, "alone", func() (interface{}, error) { return nil, nil }); shared {
+					t.Error("owner alone shared = true; want false")
+				}
+
+				release := make(chan struct{})
+				started := make(chan struct{})
+				fn := func() (interface{}, error) {
+					close(started)
+					<-release
+					return nil, nil
+				}
+				o := owner.call(&g, "key", fn)
+				<-started
+				w := joiner.call(&g, "key", fn)
+				waitFor(t, func() bool { return g.InFlight()["key"] == 1 })
+				close(release)
+				if shared := <-o; !shared {
+					t.Error("owner with concurrent waiter shared = false; want true")
+				}
+				if shared := <-w; !shared {
+					t.Error("concurrent waiter shared = false; want true")
+				}
+				if shared := <-joiner.call(&g, "alone", nil); !shared {
+					t.Error("post-completion hit shared = false; want true")
+				}
+			})
+		}
+	}
+}
+
+func TestDoChanCancel(t *testing.T) {
+	var g Group
+	release := make(chan struct{})
+	fn := func() (interface{}, error) {
+		<-release
+		return "bar", nil
+	}
+	owner, cancelOwner := g.DoChanCancel("key", time.Hour, fn)
+	waiters := make([]<-chan Result, 3)
+	cancels := make([]func(), 3)
+	for i := range waiters {
+		waiters[i], cancels[i] = g.DoChanCancel("key", time.Hour, fn)
+	}
+	cancels[1]()
+	cancels[1]()
+	cancelOwner()
+	if n := g.InFlight()["key"]; n != 2 {
+		t.Errorf("dups after canceling one waiter = %d; want 2", n)
+	}
+	close(release)
+
+	for _, i := range []int{0, 2} {
+		if r := <-waiters[i]; r.Val != "bar" || r.Role != RoleWaiter {
+			t.Errorf("waiter %d = %+v; want bar as waiter", i, r)
+		}
+	}
+	waitFor(t, func() bool { return g.Has("key") })
+	select {
+	case r := <-waiters[1]:
+		t.Errorf("canceled waiter received %+v", r)
+	case r := <-owner:
+		t.Errorf("canceled owner received %+v", r)
+	default:
+	}
+
+	// 结果已经发送之后取消什么都不做
+	cancels[0]()
+	ch, cancel := g.DoChanCancel("key", time.Hour, fn)
+	cancel()
+	if r := <-ch; r.Val != "bar" {
+		t.Errorf("hit after cancel = %+v; want bar", r)
+	}
+}
+
+func TestDoFreshZones(t *testing.T) {
+	const validTime, staleFor = 50 * time.Millisecond, time.Second
+	tests := []struct {
+		zone      string
+		advance   time.Duration
+		fresh     bool
+		want      int32
+		wantStale bool
+	}{
+		{"fresh", 10 * time.Millisecond, false, 1, false},
+		{"fresh", 10 * time.Millisecond, true, 1, false},
+		{"soft-stale", 100 * time.Millisecond, false, 1, true},
+		{"soft-stale", 100 * time.Millisecond, true, 2, false},
+		{"hard-expired", 2 * time.Second, false, 2, false},
+		{"hard-expired", 2 * time.Second, true, 2, false},
+	}
+	for _, tt := range tests {
+		clock := newFakeClock()
+		g := New(WithClock(clock))
+		var calls int32
+		fn := func() (interface{}, error) {
+			return atomic.AddInt32(&calls, 1), nil
+		}
+		g.DoStale("key", validTime, staleFor, fn)
+		clock.Advance(tt.advance)
+
+		var v interface{}
+		stale := false
+		if tt.fresh {
+			v, _, _ = g.DoFresh("key", validTime, staleFor, fn)
+		} else {
+			v, _, _, stale = g.DoStale("key", validTime, staleFor, fn)
+		}
+		if v != tt.want || stale != tt.wantStale {
+			t.Errorf("%s zone, fresh %v: got %v, stale %v; want %v, stale %v", tt.zone, tt.fresh, v, stale, tt.want, tt.wantStale)
+		}
+		waitFor(t, func() bool {
+			_, ttl := g.TTL("key")
+			return ttl
+		})
+		if v, _, _, stale := g.DoStale("key", validTime, staleFor, fn); v != atomic.LoadInt32(&calls) || stale {
+			t.Errorf("%s zone, fresh %v: DoStale afterwards = %v, stale %v; want latest, not stale", tt.zone, tt.fresh, v, stale)
+		}
+	}
+}
+
+func TestDoFreshRefreshError(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithClock(clock))
+	someErr := errors.New("some error")
+	var calls int32
+	fn := func() (interface{}, error) {
+		if atomic.AddInt32(&calls, 1) > 1 {
+			return nil, someErr
+		}
+		return "old", nil
+	}
+	g.DoStale("key", 20*time.Millisecond, 100*time.Millisecond, fn)
+	clock.Advance(30 * time.Millisecond)
+	if _, err, _ := g.DoFresh("key", 20*time.Millisecond, 100*time.Millisecond, fn); err != someErr {
+		t.Errorf("DoFresh err = %v; want some error", err)
+	}
+	// 刷新失败之后旧的结果仍然可以返回给接受旧值的调用者
+	if v, err, _, stale := g.DoStale("key", 20*time.Millisecond, 100*time.Millisecond, fn); v != "old" || err != nil || !stale {
+		t.Errorf("DoStale after failed refresh = %v, %v, stale %v; want old, nil, true", v, err, stale)
+	}
+}
+
+func TestDoAndDoChanSharedRace(t *testing.T) {
+	// 在-race下运行：Shared在持有锁时确定，重复调用者可以一直加入到调用完成为止
+	var g Group
+	fn := func() (interface{}, error) {
+		time.Sleep(time.Millisecond)
+		return nil, nil
+	}
+	var wg sync.WaitGroup
+	for round := 0; round < 20; round++ {
+		key := strconv.Itoa(round)
+		for i := 0; i < 16; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				if i%2 == 0 {
+					g.Do(key, time.Hour, fn)
+				} else {
+					<-g.DoChan(key, time.Hour, fn)
+				}
+			}(i)
+		}
+	}
+	wg.Wait()
+	if s := g.Stats(); s.Executions != 20 {
+		t.Errorf("Executions = %d; want 20", s.Executions)
+	}
+}
+
+func TestEntryAndInFlightCount(t *testing.T) {
+	var g Group
+	counts := func() [2]int {
+		return [2]int{g.EntryCount(), g.InFlightCount()}
+	}
+	if c := counts(); c != [2]int{0, 0} {
+		t.Errorf("counts of zero Group = %v; want [0 0]", c)
+	}
+
+	release := make(chan struct{})
+	ch := g.DoChan("slow", time.Hour, func() (interface{}, error) {
+		<-release
+		return nil, nil
+	})
+	if c := counts(); c != [2]int{0, 1} {
+		t.Errorf("counts while blocked = %v; want [0 1]", c)
+	}
+	g.Do("fast", time.Hour, func() (interface{}, error) {
+		return nil, nil
+	})
+	if c := counts(); c != [2]int{1, 1} {
+		t.Errorf("counts after fast call = %v; want [1 1]", c)
+	}
+	close(release)
+	<-ch
+	if c := counts(); c != [2]int{2, 0} || g.Len() != 2 {
+		t.Errorf("counts after completion = %v, Len %d; want [2 0], 2", c, g.Len())
+	}
+}
+
+func TestDoChanDeliveryOutsideLock(t *testing.T) {
+	// 拷贝在发送结果时进行，阻塞的拷贝让发送停在中途，其他key的调用不受影响
+	block := make(chan struct{})
+	var blocked sync.Once
+	inDelivery := make(chan struct{})
+	g := New(WithCopier(func(v interface{}) interface{} {
+		if v == "slow" {
+			blocked.Do(func() {
+				close(inDelivery)
+				<-block
+			})
+		}
+		return v
+	}))
+	release := make(chan struct{})
+	const n = 5000
+	chans := make([]<-chan Result, n)
+	chans[0] = g.DoChan("slow", time.Hour, func() (interface{}, error) {
+		<-release
+		return "slow", nil
+	})
+	for i := 1; i < n; i++ {
+		chans[i] = g.DoChan("slow", time.Hour, nil)
+	}
+	close(release)
+	<-inDelivery
+
+	done := make(chan struct{})
+	go func() {
+		g.Do("other", time.Hour, func() (interface{}, error) {
+			return nil, nil
+		})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Do on another key blocked during delivery")
+	}
+	close(block)
+
+	for i, ch := range chans {
+		r, ok := <-ch
+		if !ok || r.Val != "slow" {
+			t.Fatalf("chans[%d] = %v, %v; want slow, true", i, r.Val, ok)
+		}
+		if _, ok := <-ch; ok {
+			t.Fatalf("chans[%d] not closed after delivery", i)
+		}
+	}
+	hit := g.DoChan("slow", time.Hour, nil)
+	<-hit
+	if _, ok := <-hit; ok {
+		t.Error("hit channel not closed")
+	}
+}
+
+func TestDoOnce(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithClock(clock), WithErrorCaching(true), WithStrictTTL(true))
+	var calls int
+	fn := func() (interface{}, error) {
+		calls++
+		if calls < 3 {
+			return nil, errors.New("not yet")
+		}
+		return calls, nil
+	}
+	// 错误不被缓存，之后的调用重新执行方法直到成功
+	for i := 1; i <= 2; i++ {
+		if _, err, _ := g.DoOnce("key", fn); err == nil || calls != i {
+			t.Fatalf("DoOnce #%d = %v, calls %d; want an error, %d calls", i, err, calls, i)
+		}
+	}
+	for i := 0; i < 10; i++ {
+		if v, err, _ := g.DoOnce("key", fn); v != 3 || err != nil {
+			t.Fatalf("DoOnce = %v, %v; want 3, nil", v, err)
+		}
+		clock.Advance(24 * time.Hour)
+	}
+	if calls != 3 {
+		t.Errorf("calls = %d; want 3", calls)
+	}
+}
+
+func TestLenByStateHammer(t *testing.T) {
+	g := New(WithForgetMode(ForgetReattach), WithCapacity(50))
+	fn := func() (interface{}, error) {
+		time.Sleep(time.Microsecond)
+		return nil, nil
+	}
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				key := strconv.Itoa((w + i) % 64)
+				switch i % 7 {
+				case 0:
+					g.Forget(key)
+				case 1:
+					g.Set(key, i, time.Hour)
+				case 2:
+					<-g.DoChan(key, time.Hour, fn)
+				case 3:
+					g.ForgetPrefix("1")
+				case 4:
+					g.DoMulti([]string{key, "m" + key}, time.Hour, func(missing []string) (map[string]interface{}, error) {
+						return nil, nil
+					})
+				default:
+					g.Do(key, time.Millisecond, fn)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	waitFor(t, func() bool { return g.Stats().InFlight == 0 })
+	completed, inflight := g.LenByState()
+	if inflight != len(g.InFlight()) || completed+inflight != g.Len() {
+		t.Errorf("LenByState = %d, %d; want %d in flight and a sum of Len %d", completed, inflight, len(g.InFlight()), g.Len())
+	}
+	if inflight != 0 {
+		t.Errorf("in flight after quiescing = %d; want 0", inflight)
+	}
+}
+
+func TestExpireNow(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithClock(clock))
+	var calls int32
+	release := make(chan struct{})
+	fn := func() (interface{}, error) {
+		n := atomic.AddInt32(&calls, 1)
+		if n == 2 {
+			<-release
+		}
+		return n, nil
+	}
+	g.Do("key", time.Hour, fn)
+	if g.ExpireNow("absent") {
+		t.Error("ExpireNow of an absent key = true")
+	}
+	if !g.ExpireNow("key") || g.ExpireNow("key") {
+		t.Error("ExpireNow = false for a cached key, or true for an expired one")
+	}
+	if g.Has("key") {
+		t.Error("Has after ExpireNow = true; want expired")
+	}
+	if v, _, ok := g.Peek("key"); v != int32(1) || !ok {
+		t.Errorf("Peek after ExpireNow = %v, %v; want kept 1, true", v, ok)
+	}
+	if _, ttl, _ := g.PeekResult("key"); ttl != 0 {
+		t.Errorf("PeekResult ttl = %v; want 0", ttl)
+	}
+	g.deleteExpired()
+
+	// 下一次Do重新执行方法，刷新完成之前Peek仍然拿到保留的值
+	done := make(chan interface{})
+	go func() {
+		v, _, _ := g.Do("key", time.Hour, fn)
+		done <- v
+	}()
+	waitFor(t, func() bool { return atomic.LoadInt32(&calls) == 2 })
+	if v, _, ok := g.Peek("key"); v != int32(1) || !ok {
+		t.Errorf("Peek during recompute = %v, %v; want kept 1, true", v, ok)
+	}
+	close(release)
+	if v := <-done; v != int32(2) {
+		t.Errorf("Do after ExpireNow = %v; want recomputed 2", v)
+	}
+	if v, _, ok := g.Peek("key"); v != int32(2) || !ok {
+		t.Errorf("Peek after recompute = %v, %v; want 2, true", v, ok)
+	}
+
+	// DoStale缓存的结果在staleFor之内仍然立即作为旧值返回
+	g.DoStale("stale", time.Hour, time.Hour, fn)
+	g.ExpireNow("stale")
+	if v, _, _, stale := g.DoStale("stale", time.Hour, time.Hour, fn); v != int32(3) || !stale {
+		t.Errorf("DoStale = %v, stale %v; want old 3, stale", v, stale)
+	}
+	g.Wait(context.Background())
+	if v, _, _ := g.Do("stale", time.Hour, fn); v != int32(4) {
+		t.Errorf("Do after refresh = %v; want refreshed 4", v)
+	}
+	if n := atomic.LoadInt32(&calls); n != 4 {
+		t.Errorf("fn called %d times; want 4", n)
+	}
+
+	// 刷新失败时结果继续被保留
+	g.ExpireNow("key")
+	if _, err, _ := g.Do("key", time.Hour, func() (interface{}, error) {
+		return nil, errors.New("boom")
+	}); err == nil {
+		t.Error("Do with a failing refresh returned nil error")
+	}
+	if v, _, ok := g.Peek("key"); v != int32(2) || !ok {
+		t.Errorf("Peek after failed refresh = %v, %v; want kept 2, true", v, ok)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithClock(clock), WithLoader(func(key string) (interface{}, error) {
+		return "loaded", nil
+	}))
+	if g.Update("key", "new") || g.Has("key") {
+		t.Error("Update created an absent key")
+	}
+	g.Get("key", time.Minute)
+	clock.Advance(30 * time.Second)
+	if !g.Update("key", "new") {
+		t.Fatal("Update = false; want true")
+	}
+	if v, _, _ := g.Get("key", time.Minute); v != "new" {
+		t.Errorf("Get after Update = %v; want new", v)
+	}
+	if ttl, _ := g.TTL("key"); ttl != 30*time.Second {
+		t.Errorf("TTL after Update = %v; want the original 30s", ttl)
+	}
+	clock.Advance(30 * time.Second)
+	if g.Update("key", "newer") {
+		t.Error("Update of expired key = true; want false")
+	}
+
+	// 正在调用中的key不受影响
+	release := make(chan struct{})
+	ch := g.DoChan("inflight", time.Minute, func() (interface{}, error) {
+		<-release
+		return "computed", nil
+	})
+	if g.Update("inflight", "new") {
+		t.Error("Update of in-flight key = true; want false")
+	}
+	close(release)
+	if r := <-ch; r.Val != "computed" {
+		t.Errorf("in-flight result = %v; want computed", r.Val)
+	}
+}
+
+func TestUpdateDuringRefresh(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithClock(clock), WithRefreshAhead(0.5))
+	started, release := make(chan struct{}), make(chan struct{})
+	refreshing := false
+	fn := func() (interface{}, error) {
+		if !refreshing {
+			return "old", nil
+		}
+		close(started)
+		<-release
+		return "refreshed", nil
+	}
+	g.Do("key", time.Minute, fn)
+	clock.Advance(40 * time.Second)
+
+	// 后台刷新使用保存的方法
+	refreshing = true
+	g.Do("key", time.Minute, fn)
+	<-started
+	if !g.Update("key", "newer") {
+		t.Fatal("Update during refresh = false; want true")
+	}
+	close(release)
+	if err := g.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// 刷新开始之后key的结果已经被替换，刷新的结果被丢弃
+	if v, _, _ := g.Peek("key"); v != "newer" {
+		t.Errorf("value after refresh = %v; want the updated newer", v)
+	}
+	if ttl, _ := g.TTL("key"); ttl != 20*time.Second {
+		t.Errorf("TTL after refresh = %v; want the original 20s", ttl)
+	}
+}
+
+func TestUpdateFunc(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithClock(clock))
+	add := func(n int) func(interface{}, bool) (interface{}, bool) {
+		return func(old interface{}, exists bool) (interface{}, bool) {
+			if !exists {
+				return nil, false
+			}
+			return old.(int) + n, true
+		}
+	}
+	if g.UpdateFunc("key", add(1)) || g.Has("key") {
+		t.Error("UpdateFunc created an absent key")
+	}
+	g.Do("key", time.Minute, func() (interface{}, error) { return 0, nil })
+	clock.Advance(30 * time.Second)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if !g.UpdateFunc("key", add(1)) {
+				t.Error("UpdateFunc = false; want true")
+			}
+		}()
+	}
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, _, ok := g.Peek("key"); !ok || v.(int) < 0 {
+				t.Errorf("Peek during UpdateFunc = %v, %v", v, ok)
+			}
+		}()
+	}
+	wg.Wait()
+	if v, _, _ := g.Peek("key"); v != 50 {
+		t.Errorf("value after concurrent UpdateFunc = %v; want 50", v)
+	}
+	if ttl, _ := g.TTL("key"); ttl != 30*time.Second {
+		t.Errorf("TTL after UpdateFunc = %v; want the original 30s", ttl)
+	}
+
+	// keep为false时移除key
+	if !g.UpdateFunc("key", func(interface{}, bool) (interface{}, bool) { return nil, false }) {
+		t.Error("removing UpdateFunc = false; want true")
+	}
+	if g.Has("key") {
+		t.Error("key still cached after UpdateFunc with keep=false")
+	}
+}
+
+func TestCompareAndForget(t *testing.T) {
+	var g Group
+	version := func(v int) func(interface{}) bool {
+		return func(val interface{}) bool { return val == v }
+	}
+	if g.CompareAndForget("key", version(1)) {
+		t.Error("CompareAndForget of absent key = true; want false")
+	}
+	g.Do("key", time.Minute, func() (interface{}, error) { return 2, nil })
+	// 过时的失效消息不删除更新的值
+	if g.CompareAndForget("key", version(1)) || !g.Has("key") {
+		t.Error("CompareAndForget with stale version forgot the key")
+	}
+	if !g.CompareAndForget("key", version(2)) || g.Has("key") {
+		t.Error("CompareAndForget with current version kept the key")
+	}
+
+	release := make(chan struct{})
+	ch := g.DoChan("inflight", time.Minute, func() (interface{}, error) {
+		<-release
+		return 1, nil
+	})
+	if g.CompareAndForget("inflight", func(interface{}) bool { return true }) {
+		t.Error("CompareAndForget of in-flight key = true; want false")
+	}
+	close(release)
+	<-ch
+	if !g.Has("inflight") {
+		t.Error("in-flight call was forgotten")
+	}
+}
+
+func TestDoCachedHitShared(t *testing.T) {
+	// 无锁的命中路径和开启容量限制之后加锁的命中路径
+	for _, g := range []*Group{New(), New(WithCapacity(10))} {
+		calls := 0
+		fn := func() (interface{}, error) {
+			calls++
+			return "v", nil
+		}
+		if _, _, shared := g.Do("key", time.Minute, fn); shared {
+			t.Error("first Do shared = true; want false")
+		}
+		for i := 0; i < 3; i++ {
+			if v, err, shared := g.Do("key", time.Minute, fn); v != "v" || err != nil || !shared {
+				t.Errorf("cached Do #%d = %v, %v, %v; want v, nil, true", i, v, err, shared)
+			}
+		}
+		if calls != 1 {
+			t.Errorf("fn called %d times; want 1", calls)
+		}
+	}
+}
+
+func TestDoUntil(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithClock(clock), WithJitter(0.5))
+	calls := 0
+	fn := func() (interface{}, error) {
+		calls++
+		return calls, nil
+	}
+	expireAt := clock.Now().Add(1500*time.Millisecond + 7)
+	g.DoUntil("key", expireAt, fn)
+	clock.Advance(1500*time.Millisecond + 6)
+	if v, _, _ := g.DoUntil("key", expireAt, fn); v != 1 {
+		t.Errorf("DoUntil 1ns before expiry = %v; want cached 1", v)
+	}
+	clock.Advance(1)
+	if g.Has("key") {
+		t.Error("key still valid at expireAt")
+	}
+	if v, _, _ := g.DoUntil("key", expireAt, fn); v != 2 {
+		t.Errorf("DoUntil after expiry = %v; want 2", v)
+	}
+	if g.Has("key") {
+		t.Error("result with expireAt in the past was cached")
+	}
+}
+
+func TestDoUntilWallClock(t *testing.T) {
+	var g Group
+	var calls int32
+	fn := func() (interface{}, error) {
+		return atomic.AddInt32(&calls, 1), nil
+	}
+	// 没有单调时钟读数的时刻，比如从HTTP响应中解析的Expires
+	g.DoUntil("past", time.Now().Round(0).Add(-time.Second), fn)
+	if g.Has("past") {
+		t.Error("result with expireAt already in the past was cached")
+	}
+
+	expireAt := time.Now().Round(0).Add(time.Minute)
+	g.DoUntil("soon", expireAt, fn)
+	ttl, ok := g.TTL("soon")
+	if !ok || ttl > time.Minute || ttl < 59*time.Second {
+		t.Errorf("TTL = %v, %v; want about 1m", ttl, ok)
+	}
+	if v, _, _ := g.DoUntil("soon", expireAt, fn); v != int32(2) {
+		t.Errorf("DoUntil before expiry = %v; want cached 2", v)
+	}
+}
+
+func TestDoRefreshCoalesces(t *testing.T) {
+	var g Group
+	g.Do("key", time.Hour, func() (interface{}, error) { return "old", nil })
+	var calls int32
+	release := make(chan struct{})
+	fn := func() (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return "fresh", nil
+	}
+	const n = 8
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, _, _ := g.DoRefresh("key", time.Hour, fn); v != "fresh" {
+				t.Errorf("DoRefresh = %v; want fresh", v)
+			}
+		}()
+	}
+	for {
+		g.mu.RLock()
+		c := g.m["key"]
+		joined := c.force && c.dups == n-1
+		g.mu.RUnlock()
+		if joined {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("fn called %d times; want 1", n)
+	}
+	if v, _, _ := g.Peek("key"); v != "fresh" {
+		t.Errorf("cached value = %v; want fresh", v)
+	}
+}
+
+func TestForgetMany(t *testing.T) {
+	var g Group
+	var calls int32
+	fn := func() (interface{}, error) {
+		return atomic.AddInt32(&calls, 1), nil
+	}
+	g.Do("a", time.Minute, fn)
+	g.Do("b", time.Minute, fn)
+	release := make(chan struct{})
+	ch := g.DoChan("inflight", time.Minute, func() (interface{}, error) {
+		<-release
+		return "stale", nil
+	})
+
+	existed := g.ForgetMany([]string{"a", "absent", "inflight"})
+	if fmt.Sprint(existed) != "[a inflight]" {
+		t.Errorf("ForgetMany = %v; want [a inflight]", existed)
+	}
+	close(release)
+	if r := <-ch; r.Val != "stale" || !r.Forgotten {
+		t.Errorf("in-flight result = %+v; want stale and forgotten", r)
+	}
+	if g.Has("a") || g.Has("inflight") || !g.Has("b") {
+		t.Errorf("keys after ForgetMany = %v; want [b]", g.Keys())
+	}
+	if v, _, _ := g.Do("inflight", time.Minute, fn); v != int32(3) {
+		t.Errorf("Do after ForgetMany = %v; want a new call", v)
+	}
+}
+
+func TestDoRefresh(t *testing.T) {
+	var g Group
+	var version int64
+	fn := func() (interface{}, error) {
+		v := atomic.AddInt64(&version, 1)
+		time.Sleep(time.Microsecond)
+		return v, nil
+	}
+	g.Do("key", time.Hour, fn)
+	if v, _, _ := g.DoRefresh("key", time.Hour, fn); v != int64(2) {
+		t.Fatalf("DoRefresh = %v; want a new execution", v)
+	}
+
+	// DoRefresh返回之后开始的Do不会拿到之前的结果
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				g.Do("key", time.Hour, fn)
+			}
+		}()
+	}
+	for i := 0; i < 200; i++ {
+		v, _, _ := g.DoRefresh("key", time.Hour, fn)
+		if got, _, _ := g.Do("key", time.Hour, fn); got.(int64) < v.(int64) {
+			t.Fatalf("Do after DoRefresh returned %v = %v; want at least %v", v, got, v)
+		}
+	}
+	close(done)
+	wg.Wait()
+
+	// 正在进行的调用被取代，其结果不会被缓存
+	release := make(chan struct{})
+	started := make(chan struct{})
+	old := g.DoChan("busy", time.Hour, func() (interface{}, error) {
+		close(started)
+		<-release
+		return "old", nil
+	})
+	<-started
+	if v, _, _ := g.DoRefresh("busy", time.Hour, func() (interface{}, error) {
+		return "new", nil
+	}); v != "new" {
+		t.Errorf("DoRefresh over in-flight call = %v; want new", v)
+	}
+	close(release)
+	if r := <-old; r.Val != "old" {
+		t.Errorf("superseded call result = %v; want old", r.Val)
+	}
+	if v, _, _ := g.Peek("busy"); v != "new" {
+		t.Errorf("Peek after superseded call = %v; want new", v)
+	}
+}
+
+func TestReadersAndWritersHammer(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithClock(clock))
+	fn := func() (interface{}, error) {
+		return "bar", nil
+	}
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; ; j++ {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				key := string(rune('a' + (i+j)%3))
+				g.Do(key, time.Millisecond, fn)
+				if j%5 == 0 {
+					g.Forget(key)
+				}
+				clock.Advance(time.Millisecond)
+			}
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				key := string(rune('a' + i%3))
+				if v, _, ok := g.Peek(key); ok && v != "bar" {
+					t.Errorf("Peek = %v; want bar", v)
+				}
+				g.Has(key)
+				g.TTL(key)
+				g.Keys()
+				if completed, inflight := g.LenByState(); completed < 0 || inflight < 0 || completed+inflight > 3 {
+					t.Errorf("LenByState = %d, %d; want at most 3 keys", completed, inflight)
+				}
+			}
+		}(i)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(done)
+	wg.Wait()
+}
+
+func TestDoChanFanOutConsistent(t *testing.T) {
+	var g Group
+	release := make(chan struct{})
+	started := make(chan struct{})
+	fn := func() (interface{}, error) {
+		close(started)
+		<-release
+		return "bar", errors.New("partial")
+	}
+	chans := []<-chan Result{g.DoChan("key", time.Hour, fn)}
+	<-started
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		chans = append(chans, g.DoChan("key", time.Hour, nil))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, _, shared := g.Do("key", time.Hour, nil); !shared {
+				t.Error("Do waiter shared = false; want true")
+			}
+		}()
+	}
+	waitFor(t, func() bool { return g.InFlight()["key"] == 10 })
+	close(release)
+	wg.Wait()
+
+	first := <-chans[0]
+	if first.Role != RoleLeader || !first.Shared || first.Val != "bar" {
+		t.Fatalf("owner result = %+v; want shared leader result", first)
+	}
+	for i, ch := range chans[1:] {
+		r := <-ch
+		if r.Val != first.Val || r.Err != first.Err || r.Shared != first.Shared || r.Dups != first.Dups || r.Role != RoleWaiter {
+			t.Errorf("DoChan %d result = %+v; want the owner's %+v as waiter", i+1, r, first)
+		}
+	}
+}
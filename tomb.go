@@ -0,0 +1,66 @@
+package timesf
+
+import "time"
+
+// WithForgetGrace 让Forget和ForgetStatus遗忘正在进行的调用时保留其一个墓碑d时长：期间
+// key没有新的调用时，第一个到达的调用者共享被遗忘的调用，而不是再执行一次方法，调用还没
+// 完成时等待其结果，已经完成时直接拿到其结果，之后墓碑被删除。被遗忘的调用返回错误时不会
+// 被共享。适用于写入之后遗忘key、而正在进行的调用已经能看到写入的情况，可以减少遗忘时
+// 多余的重新计算。只在ForgetKeep模式下生效，已完成的结果被遗忘时不保留。d不大于0时不
+// 保留，这是默认值。
+func WithForgetGrace(d time.Duration) Option {
+	return func(g *Group) {
+		g.forgetGrace = d
+	}
+}
+
+// tomb 是被遗忘的正在进行的调用，见WithForgetGrace。
+type tomb struct {
+	c     *call
+	until int64
+}
+
+// bury 在WithForgetGrace开启时为刚刚被遗忘的正在进行的调用c保留墓碑。调用者需要持有锁。
+func (g *Group) bury(key string, c *call) {
+	if g.forgetGrace <= 0 || c.done || g.forgetMode != ForgetKeep {
+		return
+	}
+	if g.tombs == nil {
+		g.tombs = make(map[string]tomb)
+	}
+	g.tombs[key] = tomb{c, addTime(g.now(), g.forgetGrace)}
+}
+
+// exhume 在key没有调用时取出并删除其还没有过期的墓碑，返回可以共享的调用，没有时返回
+// nil。调用者需要持有锁。
+func (g *Group) exhume(key string, now int64) *call {
+	tb, ok := g.tombs[key]
+	if !ok {
+		return nil
+	}
+	if _, ok := g.m[key]; ok {
+		return nil
+	}
+	delete(g.tombs, key)
+	if tb.until <= now || tb.c.done && tb.c.err != nil || tb.c.aborted {
+		return nil
+	}
+	return tb.c
+}
+
+// exhumed 像exhume方法，但是DoRefresh和TryDo的调用者不使用墓碑。调用者需要持有锁。
+func (g *Group) exhumed(key string, p params) *call {
+	if p.force || p.noWait || len(g.tombs) == 0 {
+		return nil
+	}
+	return g.exhume(key, g.now())
+}
+
+// sweepTombs 删除已经过期的墓碑，调用者需要持有锁。
+func (g *Group) sweepTombs(now int64) {
+	for key, tb := range g.tombs {
+		if tb.until <= now {
+			delete(g.tombs, key)
+		}
+	}
+}
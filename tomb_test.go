@@ -0,0 +1,61 @@
+package timesf
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithForgetGrace(t *testing.T) {
+	g := New(WithForgetGrace(time.Minute))
+	var calls int32
+	release := make(chan struct{})
+	fn := func() (interface{}, error) {
+		n := atomic.AddInt32(&calls, 1)
+		<-release
+		return n, nil
+	}
+
+	// 调用还在进行时到达的调用者等待被遗忘的调用
+	leader := g.DoChan("key", time.Minute, fn)
+	g.Forget("key")
+	straggler := make(chan interface{})
+	go func() {
+		v, _, _ := g.Do("key", time.Minute, fn)
+		straggler <- v
+	}()
+	for {
+		g.mu.RLock()
+		n := len(g.tombs)
+		g.mu.RUnlock()
+		if n == 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	if r := <-leader; r.Val != int32(1) {
+		t.Errorf("leader = %v; want 1", r.Val)
+	}
+	if v := <-straggler; v != int32(1) {
+		t.Errorf("straggler = %v; want the forgotten call's 1", v)
+	}
+
+	// 已经完成的被遗忘的调用同样可以被读取一次
+	g.Forget("key")
+	release = make(chan struct{})
+	leader = g.DoChan("key", time.Minute, fn)
+	g.Forget("key")
+	close(release)
+	<-leader
+	if v, _, _ := g.Do("key", time.Minute, fn); v != int32(2) {
+		t.Errorf("straggler after completion = %v; want 2", v)
+	}
+	g.Forget("key")
+	if v, _, _ := g.Do("key", time.Minute, fn); v != int32(3) {
+		t.Errorf("Do after forgetting a cached value = %v; want a new call", v)
+	}
+	if n := atomic.LoadInt32(&calls); n != 3 {
+		t.Errorf("fn called %d times; want 3", n)
+	}
+}
@@ -0,0 +1,19 @@
+package timesf
+
+import "context"
+
+// Tracer 为DoCtx执行的方法创建span，可以用来接入OpenTelemetry等分布式追踪，而不需要
+// 依赖其实现。
+type Tracer interface {
+	// StartSpan 在执行key的方法之前被调用，返回的上下文被传给方法。end在调用完成并且
+	// 结果交给等待者之后被调用，shared标识结果是否被其他调用者共享，err是调用的错误。
+	StartSpan(ctx context.Context, key string) (spanCtx context.Context, end func(shared bool, err error))
+}
+
+// WithTracer 设置Group的Tracer，每一次执行DoCtx的方法都有一个span，包括后台刷新，等待
+// 结果的重复调用者和命中缓存的调用者没有span。没有接收上下文的方法不会被追踪。
+func WithTracer(t Tracer) Option {
+	return func(g *Group) {
+		g.tracer = t
+	}
+}
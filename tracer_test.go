@@ -0,0 +1,86 @@
+package timesf
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type spanKey struct{}
+
+// fakeSpan 是fakeTracer记录的一个span。
+type fakeSpan struct {
+	key    string
+	ended  bool
+	shared bool
+	err    error
+}
+
+// fakeTracer 记录所有的span。
+type fakeTracer struct {
+	mu    sync.Mutex
+	spans []*fakeSpan
+}
+
+func (f *fakeTracer) StartSpan(ctx context.Context, key string) (context.Context, func(bool, error)) {
+	s := &fakeSpan{key: key}
+	f.mu.Lock()
+	f.spans = append(f.spans, s)
+	f.mu.Unlock()
+	return context.WithValue(ctx, spanKey{}, s), func(shared bool, err error) {
+		f.mu.Lock()
+		s.ended, s.shared, s.err = true, shared, err
+		f.mu.Unlock()
+	}
+}
+
+func TestWithTracer(t *testing.T) {
+	var tr fakeTracer
+	g := New(WithTracer(&tr))
+	errBoom := errors.New("boom")
+	release := make(chan struct{})
+	started := make(chan struct{})
+	var inSpan bool
+	fn := func(ctx context.Context) (interface{}, error) {
+		_, inSpan = ctx.Value(spanKey{}).(*fakeSpan)
+		close(started)
+		<-release
+		return nil, errBoom
+	}
+
+	done := make(chan struct{})
+	go func() {
+		g.DoCtx(context.Background(), "key", time.Hour, fn)
+		close(done)
+	}()
+	<-started
+	waiter := make(chan struct{})
+	go func() {
+		g.DoCtx(context.Background(), "key", time.Hour, fn)
+		close(waiter)
+	}()
+	waitFor(t, func() bool { return g.InFlight()["key"] == 1 })
+	close(release)
+	<-done
+	<-waiter
+
+	g.DoCtx(context.Background(), "ok", time.Hour, func(ctx context.Context) (interface{}, error) {
+		return 1, nil
+	})
+	g.DoCtx(context.Background(), "ok", time.Hour, nil) // 命中缓存
+	g.Do("plain", time.Hour, func() (interface{}, error) { return nil, nil })
+
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	if len(tr.spans) != 2 {
+		t.Fatalf("spans = %d; want 2", len(tr.spans))
+	}
+	if s := tr.spans[0]; s.key != "key" || !s.ended || !s.shared || s.err != errBoom || !inSpan {
+		t.Errorf("first span = %+v (fn in span %v); want ended shared key with boom", s, inSpan)
+	}
+	if s := tr.spans[1]; s.key != "ok" || !s.ended || s.shared || s.err != nil {
+		t.Errorf("second span = %+v; want ended unshared ok without error", s)
+	}
+}
@@ -0,0 +1,160 @@
+package timesf
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TypedGroup 是Group的泛型封装，调用方无需再对interface{}进行类型断言。
+// 其零值可以直接使用。
+//
+// 键直接按K的相等性比较，不会被格式化成字符串：每个不同的键第一次出现时分配一个底层
+// Group使用的编号，之后的调用只需要查一次map[K]，命中时不会分配内存。
+type TypedGroup[K comparable, V any] struct {
+	g Group
+
+	mu sync.RWMutex
+	// ids 保存每个键在底层Group中的编号。底层Group中已经不存在、也没有正在进行的调用
+	// 引用的编号会在map增长到sweepAt时被清理。
+	ids     map[K]*typedID
+	seq     uint64
+	sweepAt int
+}
+
+// typedID 是一个键在底层Group中的编号。refs是正在使用这个编号的调用数量，
+// 不为0时编号不会被清理，否则同一个键可能同时存在两个编号。
+type typedID struct {
+	key  string
+	refs atomic.Int32
+}
+
+// minTypedSweep 是TypedGroup第一次清理编号时map的大小。
+const minTypedSweep = 64
+
+// TypedResult 保存TypedGroup.DoChan方法的结果。
+type TypedResult[V any] struct {
+	Val    V
+	Err    error
+	Shared bool
+}
+
+// Do 同Group.Do，但是键和值都是具体类型。出现错误时返回V的零值。
+func (t *TypedGroup[K, V]) Do(key K, validTime time.Duration, fn func() (V, error)) (v V, err error, shared bool) {
+	id := t.acquire(key)
+	defer id.refs.Add(-1)
+	if r, ok := t.g.fastDo(id.key); ok { // 命中时不需要创建闭包
+		return typedVal[V](r.Val), r.Err, r.Shared
+	}
+	val, err, shared := t.g.Do(id.key, validTime, func() (interface{}, error) {
+		return fn()
+	})
+	return typedVal[V](val), err, shared
+}
+
+// DoChan 同Group.DoChan，通道返回的是TypedResult，收到结果之后通道同样被关闭。
+func (t *TypedGroup[K, V]) DoChan(key K, validTime time.Duration, fn func() (V, error)) <-chan TypedResult[V] {
+	ch := make(chan TypedResult[V], 1)
+	id := t.acquire(key)
+	rc := t.g.DoChan(id.key, validTime, func() (interface{}, error) {
+		return fn()
+	})
+	go func() {
+		r := <-rc
+		id.refs.Add(-1)
+		ch <- TypedResult[V]{Val: typedVal[V](r.Val), Err: r.Err, Shared: r.Shared}
+		close(ch)
+	}()
+	return ch
+}
+
+// Forget 同Group.Forget。
+func (t *TypedGroup[K, V]) Forget(key K) {
+	if k, ok := t.lookup(key); ok {
+		t.g.Forget(k)
+	}
+}
+
+// Peek 同Group.Peek。
+func (t *TypedGroup[K, V]) Peek(key K) (v V, err error, ok bool) {
+	k, ok := t.lookup(key)
+	if !ok {
+		return v, nil, false
+	}
+	val, err, ok := t.g.Peek(k)
+	return typedVal[V](val), err, ok
+}
+
+// Has 同Group.Has。
+func (t *TypedGroup[K, V]) Has(key K) bool {
+	k, ok := t.lookup(key)
+	return ok && t.g.Has(k)
+}
+
+// Stats 同Group.Stats。
+func (t *TypedGroup[K, V]) Stats() Stats {
+	return t.g.Stats()
+}
+
+// acquire 返回key的编号并增加其引用计数，调用者用完之后需要调用refs.Add(-1)。
+func (t *TypedGroup[K, V]) acquire(key K) *typedID {
+	t.mu.RLock()
+	id := t.ids[key]
+	if id != nil {
+		id.refs.Add(1)
+	}
+	t.mu.RUnlock()
+	if id != nil {
+		return id
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if id = t.ids[key]; id == nil {
+		if t.ids == nil {
+			t.ids = make(map[K]*typedID)
+		}
+		if len(t.ids) >= t.sweepAt {
+			t.sweep()
+		}
+		t.seq++
+		id = &typedID{key: strconv.FormatUint(t.seq, 36)}
+		t.ids[key] = id
+	}
+	id.refs.Add(1)
+	return id
+}
+
+// lookup 返回key已经分配的编号，没有分配过时返回false。
+func (t *TypedGroup[K, V]) lookup(key K) (string, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if id := t.ids[key]; id != nil {
+		return id.key, true
+	}
+	return "", false
+}
+
+// sweep 删除没有被引用、在底层Group中也不存在的编号，并将下一次清理的大小设为剩余
+// 编号数量的两倍。引用计数只会在持有读锁时增加，因此持有写锁时为0的编号不会再被使用。
+// 调用者需要持有t.mu的写锁。
+func (t *TypedGroup[K, V]) sweep() {
+	t.g.mu.RLock()
+	for k, id := range t.ids {
+		if _, ok := t.g.m[id.key]; !ok && id.refs.Load() == 0 {
+			delete(t.ids, k)
+		}
+	}
+	t.g.mu.RUnlock()
+	t.sweepAt = 2 * len(t.ids)
+	if t.sweepAt < minTypedSweep {
+		t.sweepAt = minTypedSweep
+	}
+}
+
+// typedVal 将底层的结果转换为V，若结果为nil（比如出错时）则返回V的零值。
+func typedVal[V any](val interface{}) V {
+	v, _ := val.(V)
+	return v
+}
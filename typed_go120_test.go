@@ -0,0 +1,39 @@
+//go:build go1.20
+
+package timesf
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// 接口类型从go1.20开始才满足comparable约束。
+func TestTypedInterfaceKey(t *testing.T) {
+	type key struct {
+		S string
+	}
+	var g TypedGroup[any, string]
+	keys := []any{"1", 1, int64(1), key{"1"}, nil}
+	for _, k := range keys {
+		want := fmt.Sprintf("%T", k)
+		v, _, _ := g.Do(k, time.Hour, func() (string, error) {
+			return want, nil
+		})
+		if v != want {
+			t.Errorf("Do(%#v) = %q; want %q", k, v, want)
+		}
+	}
+	for _, k := range keys {
+		if v, _, ok := g.Peek(k); !ok || v != fmt.Sprintf("%T", k) {
+			t.Errorf("Peek(%#v) = %q, %v; want %q, true", k, v, ok, fmt.Sprintf("%T", k))
+		}
+	}
+	g.Forget(1)
+	if g.Has(1) {
+		t.Errorf("Has(1) after Forget(1) = true")
+	}
+	if !g.Has("1") || !g.Has(int64(1)) {
+		t.Errorf("Forget(1) removed another key")
+	}
+}
@@ -0,0 +1,261 @@
+package timesf
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type typedProfile struct {
+	Name string
+	Age  int
+}
+
+func TestTypedDo(t *testing.T) {
+	var g TypedGroup[int, typedProfile]
+	v, err, shared := g.Do(1, 9*time.Second, func() (typedProfile, error) {
+		return typedProfile{Name: "bar", Age: 3}, nil
+	})
+	if err != nil {
+		t.Errorf("Do error = %v", err)
+	}
+	if shared {
+		t.Errorf("Do shared = %v", shared)
+	}
+	if v.Name != "bar" || v.Age != 3 {
+		t.Errorf("Do value = %#v", v)
+	}
+}
+
+func TestTypedDoErrZeroValue(t *testing.T) {
+	var g TypedGroup[string, typedProfile]
+	someErr := errors.New("some error")
+	v, err, _ := g.Do("key", 9*time.Second, func() (typedProfile, error) {
+		return typedProfile{}, someErr
+	})
+	if err != someErr {
+		t.Errorf("Do error = %v; want someErr %v", err, someErr)
+	}
+	if v != (typedProfile{}) {
+		t.Errorf("unexpected non-zero value %#v", v)
+	}
+
+	var pg TypedGroup[string, *typedProfile]
+	p, err, _ := pg.Do("key", 9*time.Second, func() (*typedProfile, error) {
+		return nil, someErr
+	})
+	if err != someErr || p != nil {
+		t.Errorf("Do = %v, %v; want nil, someErr", p, err)
+	}
+
+	var ig TypedGroup[string, error]
+	e, err, _ := ig.Do("key", 9*time.Second, func() (error, error) {
+		return nil, someErr
+	})
+	if err != someErr || e != nil {
+		t.Errorf("Do = %v, %v; want nil, someErr", e, err)
+	}
+}
+
+func TestTypedDoChan(t *testing.T) {
+	var g TypedGroup[string, int]
+	r := <-g.DoChan("key", 9*time.Second, func() (int, error) {
+		return 42, nil
+	})
+	if r.Err != nil || r.Val != 42 || r.Shared {
+		t.Errorf("DoChan = %+v; want {42 <nil> false}", r)
+	}
+}
+
+func TestTypedDoChanClosed(t *testing.T) {
+	var g TypedGroup[string, int]
+	release := make(chan struct{})
+	fn := func() (int, error) {
+		<-release
+		return 42, nil
+	}
+	chans := []<-chan TypedResult[int]{g.DoChan("key", time.Hour, fn), g.DoChan("key", time.Hour, fn)}
+	close(release)
+	chans = append(chans, g.DoChan("key", time.Hour, fn)) // 命中
+	for i, ch := range chans {
+		var got []int
+		for r := range ch {
+			got = append(got, r.Val)
+		}
+		if len(got) != 1 || got[0] != 42 {
+			t.Errorf("range over chans[%d] = %v; want exactly [42]", i, got)
+		}
+	}
+}
+
+func TestTypedDoDupSuppress(t *testing.T) {
+	type key struct {
+		A string
+		B int
+	}
+	var g TypedGroup[key, string]
+	var calls int32
+	release := make(chan struct{})
+	fn := func() (string, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return "bar", nil
+	}
+
+	const n = 10
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err, _ := g.Do(key{"a b", 1}, 100*time.Second, fn)
+			if err != nil || v != "bar" {
+				t.Errorf("Do = %q, %v; want %q, nil", v, err, "bar")
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("number of calls = %d; want 1", got)
+	}
+}
+
+func TestTypedKeyDistinct(t *testing.T) {
+	type key struct {
+		A, B string
+	}
+	var g TypedGroup[key, string]
+	for _, k := range []key{{"a b", "c"}, {"a", "b c"}} {
+		v, _, _ := g.Do(k, time.Hour, func() (string, error) {
+			return k.A + "|" + k.B, nil
+		})
+		if want := k.A + "|" + k.B; v != want {
+			t.Errorf("Do(%#v) = %q; want %q", k, v, want)
+		}
+	}
+}
+
+func TestTypedSweep(t *testing.T) {
+	var g TypedGroup[int, int]
+	fn := func() (int, error) {
+		return 0, nil
+	}
+	for i := 0; i < 10*minTypedSweep; i++ {
+		g.Do(i, time.Hour, fn)
+		g.Forget(i)
+	}
+	g.Do(-1, time.Hour, fn)
+	if n := len(g.ids); n > minTypedSweep {
+		t.Errorf("ids holds %d keys after forgetting them; want at most %d", n, minTypedSweep)
+	}
+	if _, _, ok := g.Peek(-1); !ok {
+		t.Errorf("Peek(-1) after sweeping = false")
+	}
+
+	// 正在进行的调用使用的编号不会被清理，否则同一个键会执行两次。
+	var wg sync.WaitGroup
+	var calls atomic.Int32
+	unblock := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		g.Do(-2, time.Hour, func() (int, error) {
+			calls.Add(1)
+			<-unblock
+			return 0, nil
+		})
+	}()
+	waitFor(t, func() bool { return calls.Load() == 1 })
+	for i := 0; i < 10*minTypedSweep; i++ {
+		g.Do(i, time.Hour, fn)
+		g.Forget(i)
+	}
+	ch := g.DoChan(-2, time.Hour, func() (int, error) {
+		calls.Add(1)
+		return 0, nil
+	})
+	close(unblock)
+	<-ch
+	wg.Wait()
+	if n := calls.Load(); n != 1 {
+		t.Errorf("in-flight key ran %d times across a sweep; want 1", n)
+	}
+}
+
+func TestTypedForget(t *testing.T) {
+	var g TypedGroup[string, int]
+	var calls int32
+	fn := func() (int, error) {
+		return int(atomic.AddInt32(&calls, 1)), nil
+	}
+	g.Do("key", 100*time.Second, fn)
+	g.Forget("key")
+	v, _, _ := g.Do("key", 100*time.Second, fn)
+	if v != 2 {
+		t.Errorf("Do after Forget = %d; want 2", v)
+	}
+}
+
+func BenchmarkTypedDoHit(b *testing.B) {
+	type pair struct {
+		Tenant, Object uint64
+	}
+	b.Run("string", func(b *testing.B) {
+		var g Group
+		fn := func() (interface{}, error) {
+			return nil, nil
+		}
+		key := fmt.Sprintf("%d:%d", uint64(1<<40), uint64(1<<41))
+		g.Do(key, time.Hour, fn)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			g.Do(fmt.Sprintf("%d:%d", uint64(1<<40), uint64(1<<41)), time.Hour, fn)
+		}
+	})
+	b.Run("uint64", func(b *testing.B) {
+		var g TypedGroup[uint64, int]
+		fn := func() (int, error) {
+			return 0, nil
+		}
+		g.Do(1<<40, time.Hour, fn)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			g.Do(1<<40, time.Hour, fn)
+		}
+	})
+	b.Run("struct", func(b *testing.B) {
+		var g TypedGroup[pair, int]
+		fn := func() (int, error) {
+			return 0, nil
+		}
+		g.Do(pair{1 << 40, 1 << 41}, time.Hour, fn)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			g.Do(pair{1 << 40, 1 << 41}, time.Hour, fn)
+		}
+	})
+}
+
+func TestTypedPeek(t *testing.T) {
+	var g TypedGroup[uint64, string]
+	if _, _, ok := g.Peek(42); ok || g.Has(42) {
+		t.Fatal("Peek on empty group ok = true; want false")
+	}
+	g.Do(42, time.Hour, func() (string, error) {
+		return "bar", nil
+	})
+	if v, err, ok := g.Peek(42); v != "bar" || err != nil || !ok || !g.Has(42) {
+		t.Errorf("Peek = %q, %v, %v; want bar, nil, true", v, err, ok)
+	}
+	if _, _, shared := g.Do(42, time.Hour, nil); !shared {
+		t.Error("hit shared = false; want true")
+	}
+	if s := g.Stats(); s.Hits != 1 || s.Misses != 1 {
+		t.Errorf("Stats = %+v; want one hit, one miss", s)
+	}
+}
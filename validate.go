@@ -0,0 +1,37 @@
+package timesf
+
+import "time"
+
+// DoValidated 像Do方法，但是拿到结果之后用valid检查其值是否仍然有效，比如比较版本号：
+// 等待了其他调用者开始的调用，或者命中已完成的结果时，调用期间到来的失效事件可能已经让
+// 结果过时。valid返回false时，如果key的结果仍然是这个结果，则将其遗忘，然后重新走一次
+// 正常的单飞流程，同时进行的其他调用者仍然共享新的调用。为了避免活锁最多重试一次，新的
+// 结果仍然没有通过检查时也将其返回，ok为false。返回错误的结果不会被检查。valid在不持有
+// 锁时被调用。
+func (g *Group) DoValidated(key string, validTime time.Duration, fn func() (interface{}, error), valid func(v interface{}) bool) (v interface{}, err error, shared, ok bool) {
+	p := params{validTime: validTime}
+	r, _, _ := g.do(key, p, fn)
+	if r.Err != nil || valid(r.Val) {
+		return r.Val, r.Err, r.Shared, true
+	}
+	g.forgetResult(key, r)
+	r, _, _ = g.do(key, p, fn)
+	if r.Err != nil {
+		return r.Val, r.Err, r.Shared, true
+	}
+	return r.Val, r.Err, r.Shared, valid(r.Val)
+}
+
+// forgetResult 在key已完成的结果仍然是产生r的调用时将其遗忘。已经被其他调用者替换或者
+// 正在重新调用时什么都不做。按照调用本身而不是完成时间比较，同一时刻完成的不同调用不会
+// 被混淆。
+func (g *Group) forgetResult(key string, r Result) {
+	key = g.normalize(key)
+	var evs []eviction
+	g.mu.Lock()
+	if c, ok := g.m[key]; ok && c.done && c == r.from {
+		evs = g.forget(evs, key, c)
+	}
+	g.mu.Unlock()
+	g.notifyEvicted(evs)
+}
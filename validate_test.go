@@ -0,0 +1,70 @@
+package timesf
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDoValidated(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithClock(clock))
+	var version, calls int32
+	fn := func() (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		clock.Advance(time.Millisecond)
+		return atomic.LoadInt32(&version), nil
+	}
+	current := func(v interface{}) bool {
+		return v.(int32) == atomic.LoadInt32(&version)
+	}
+
+	if v, _, _, ok := g.DoValidated("key", time.Hour, fn, current); v != int32(0) || !ok || calls != 1 {
+		t.Fatalf("DoValidated = %v, ok %v (calls %d); want 0, true, 1 call", v, ok, calls)
+	}
+	// 缓存的结果有效时直接返回
+	if v, _, shared, ok := g.DoValidated("key", time.Hour, fn, current); v != int32(0) || !shared || !ok || calls != 1 {
+		t.Errorf("DoValidated hit = %v, shared %v, ok %v (calls %d); want 0, true, true, 1 call", v, shared, ok, calls)
+	}
+
+	// 版本变化之后旧的结果没有通过检查，重新执行
+	atomic.StoreInt32(&version, 1)
+	if v, _, _, ok := g.DoValidated("key", time.Hour, fn, current); v != int32(1) || !ok || calls != 2 {
+		t.Errorf("DoValidated after invalidation = %v, ok %v (calls %d); want 1, true, 2 calls", v, ok, calls)
+	}
+	if v, _, _ := g.Do("key", time.Hour, fn); v != int32(1) {
+		t.Errorf("Do after revalidation = %v; want 1", v)
+	}
+
+	// 最多重试一次
+	never := func(interface{}) bool { return false }
+	if v, _, _, ok := g.DoValidated("key", time.Hour, fn, never); v != int32(1) || ok || calls != 3 {
+		t.Errorf("DoValidated never valid = %v, ok %v (calls %d); want 1, false, 3 calls", v, ok, calls)
+	}
+}
+
+func TestDoValidatedSameInstant(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithClock(clock))
+	var calls int32
+	fn := func() (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		return "old", nil
+	}
+	g.Do("key", time.Hour, fn)
+
+	// 检查期间key被同一时刻完成的新结果替换，新结果不应该被当作旧结果遗忘
+	valid := func(v interface{}) bool {
+		if v == "old" {
+			g.Set("key", "new", time.Hour)
+			return false
+		}
+		return true
+	}
+	if v, _, _, ok := g.DoValidated("key", time.Hour, fn, valid); v != "new" || !ok {
+		t.Errorf("DoValidated = %v, ok %v; want new, true", v, ok)
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("fn called %d times; want 1, the replacing result was forgotten", n)
+	}
+}
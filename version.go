@@ -0,0 +1,52 @@
+package timesf
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrNotModified 由DoIfChanged的方法返回，表示结果和lastVersion相同，不需要重新获取。
+var ErrNotModified = errors.New("timesf: not modified")
+
+// DoIfChanged 像Do方法，但是结果带有版本，比如HTTP的ETag。Group保存结果的版本，执行方法
+// 时将key上一次结果的版本作为lastVersion传给fn，fn可以据此进行条件请求；还没有结果时
+// 传入currentVersion。fn返回ErrNotModified时继续使用上一次的值和版本，只更新有效期，
+// revalidated为true。没有上一次的值时调用者拿到ErrNotModified，表示currentVersion仍然
+// 是最新的，这个结果不会被缓存。被遗忘的key同时失去其版本。
+func (g *Group) DoIfChanged(key string, ttl time.Duration, currentVersion string, fn func(lastVersion string) (val interface{}, newVersion string, err error)) (v interface{}, err error, revalidated bool) {
+	r, _, _ := g.do(key, params{validTime: ttl, versionFn: fn, version: currentVersion}, nil)
+	return r.Val, r.Err, r.Revalidated
+}
+
+// revalidating 在DoIfChanged或者DoOrDefault开始的调用c替换之前的结果old时记下old，方法
+// 返回ErrNotModified或者错误时使用其值。调用者需要持有锁，并且c刚刚被创建。
+func (c *call) revalidating(old *call) {
+	if old == nil || !old.done || old.err != nil {
+		return
+	}
+	if c.versionFn != nil && old.versionFn != nil || c.fallback {
+		c.kept = old
+	}
+}
+
+// versioned 返回执行调用c的versionFn的方法，只由执行方法的协程调用。
+func (c *call) versioned() func() (interface{}, error) {
+	return func() (v interface{}, err error) {
+		kept := c.kept
+		c.kept = nil
+		last := c.version
+		if kept != nil {
+			last = kept.resultVersion
+		}
+		v, c.resultVersion, err = c.versionFn(last)
+		if !errors.Is(err, ErrNotModified) {
+			return v, err
+		}
+		if kept == nil {
+			c.hasErrorTTL, c.errorTTL = true, 0
+			return nil, err
+		}
+		c.resultVersion, c.revalidated = kept.resultVersion, true
+		return kept.val, nil
+	}
+}
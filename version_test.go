@@ -0,0 +1,61 @@
+package timesf
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDoIfChanged(t *testing.T) {
+	clock := newFakeClock()
+	g := New(WithClock(clock), WithErrorCaching(true))
+	var lasts []string
+	version, payload := "v1", "big"
+	fn := func(last string) (interface{}, string, error) {
+		lasts = append(lasts, last)
+		if last == version {
+			return nil, "", ErrNotModified
+		}
+		return payload, version, nil
+	}
+
+	// 调用者已知的版本仍然是最新的，结果不会被缓存
+	if v, err, _ := g.DoIfChanged("key", time.Minute, "v1", fn); v != nil || err != ErrNotModified {
+		t.Errorf("DoIfChanged with current version = %v, %v; want ErrNotModified", v, err)
+	}
+	if g.Has("key") {
+		t.Error("ErrNotModified without a cached value was cached")
+	}
+
+	if v, err, revalidated := g.DoIfChanged("key", time.Minute, "", fn); v != "big" || err != nil || revalidated {
+		t.Errorf("first DoIfChanged = %v, %v, %v; want big, nil, false", v, err, revalidated)
+	}
+	clock.Advance(time.Minute)
+	payload = "unused"
+	v, err, revalidated := g.DoIfChanged("key", time.Minute, "", fn)
+	if v != "big" || err != nil || !revalidated {
+		t.Errorf("DoIfChanged after expiry = %v, %v, %v; want the kept value revalidated", v, err, revalidated)
+	}
+	if ttl, _ := g.TTL("key"); ttl != time.Minute {
+		t.Errorf("TTL after revalidation = %v; want a fresh minute", ttl)
+	}
+
+	clock.Advance(time.Minute)
+	version, payload = "v2", "bigger"
+	if v, _, revalidated := g.DoIfChanged("key", time.Minute, "", fn); v != "bigger" || revalidated {
+		t.Errorf("DoIfChanged after change = %v, %v; want bigger, not revalidated", v, revalidated)
+	}
+
+	// 被遗忘的key失去其版本
+	g.Forget("key")
+	g.DoIfChanged("key", time.Minute, "v0", fn)
+	want := []string{"v1", "", "v1", "v1", "v0"}
+	if len(lasts) != len(want) {
+		t.Fatalf("lastVersions = %q; want %q", lasts, want)
+	}
+	for i := range want {
+		if lasts[i] != want[i] {
+			t.Errorf("lastVersions = %q; want %q", lasts, want)
+			break
+		}
+	}
+}
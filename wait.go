@@ -0,0 +1,141 @@
+package timesf
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrWaitTimeout 在重复的调用者等待正在进行的调用超过maxWait时返回，见DoWithWait。
+var ErrWaitTimeout = errors.New("timesf: timed out waiting for in-flight call")
+
+// DoWithWait 像Do方法，但是重复的调用者最多等待正在进行的调用maxWait时长，超时后返回
+// ErrWaitTimeout，正在进行的调用不受影响，其结果仍然会被缓存。maxWait不大于0时像Do
+// 方法一样一直等待。执行方法的调用者不受maxWait的限制。
+func (g *Group) DoWithWait(key string, validTime, maxWait time.Duration, fn func() (interface{}, error)) (v interface{}, err error, shared bool) {
+	r, _, _ := g.do(key, params{validTime: validTime, maxWait: maxWait}, fn)
+	return r.Val, r.Err, r.Shared
+}
+
+// DoTimeout 像DoWithWait方法，但是执行方法的调用者同样最多等待timeout，超时后返回
+// ErrWaitTimeout。方法在后台继续执行，完成后结果仍然会被缓存，之后的调用者可以直接
+// 拿到。timeout不大于0时像Do方法一样一直等待。
+func (g *Group) DoTimeout(key string, validTime, timeout time.Duration, fn func() (interface{}, error)) (v interface{}, err error, shared bool) {
+	r, _, _ := g.do(key, params{validTime: validTime, maxWait: timeout, ownerWait: true}, fn)
+	return r.Val, r.Err, r.Shared
+}
+
+// TryDo 像Do方法，但是不等待正在进行的调用：key已经有正在执行的方法时立即返回，ok为
+// false，v和err为nil，不计入统计数据。命中已完成的结果或者执行了fn时ok为true。
+func (g *Group) TryDo(key string, validTime time.Duration, fn func() (interface{}, error)) (v interface{}, err error, ok bool) {
+	r, _, _ := g.do(key, params{validTime: validTime, noWait: true}, fn)
+	if r.Err == errBusy {
+		return nil, nil, false
+	}
+	return r.Val, r.Err, true
+}
+
+// DoChanShared 像DoChan方法，但是返回key的调用完成时被关闭的通道done，共享同一次调用的
+// 调用者拿到同一个通道，可以和ctx、定时器等一起select。result返回调用者的结果，在结果
+// 发送之前会阻塞，done被关闭之后只会短暂等待；调用因为WithoutSharedErrors或者
+// ForgetReattach被接替时等待接替的调用。命中已完成的结果时done已经被关闭。
+func (g *Group) DoChanShared(key string, validTime time.Duration, fn func() (interface{}, error)) (done <-chan struct{}, result func() Result) {
+	ch, c := g.doChan(key, params{validTime: validTime}, fn)
+	var once sync.Once
+	var r Result
+	result = func() Result {
+		once.Do(func() { r = <-ch })
+		return r
+	}
+	if c == nil {
+		return closedChan, result
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if c.done || c.aborted {
+		return closedChan, result
+	}
+	return c.readyChan(), result
+}
+
+// errBusy 是TryDo遇到正在进行的调用时do返回的错误，不会返回给调用者。
+var errBusy = errors.New("timesf: call in flight")
+
+// doCallTimeout 在后台协程中执行调用c，最多等待timeout。超时不会影响调用本身，其结果
+// 仍然按照doCall的规则保存。
+func (g *Group) doCallTimeout(c *call, key string, fn func() (interface{}, error), timeout time.Duration) Result {
+	done := make(chan Result, 1)
+	go func() {
+		done <- g.doCall(c, key, fn)
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case r := <-done:
+		return r
+	case <-timer.C:
+		return Result{Err: ErrWaitTimeout}
+	}
+}
+
+// readyChan 返回调用完成时被关闭的通道。调用者需要持有锁，并且调用还没有完成。
+func (c *call) readyChan() <-chan struct{} {
+	if c.ready == nil {
+		c.ready = make(chan struct{})
+	}
+	return c.ready
+}
+
+// closeReady 在调用完成或者被中止时关闭readyChan创建的通道，调用者需要持有锁。
+func (c *call) closeReady() {
+	if c.ready != nil {
+		close(c.ready)
+		c.ready = nil
+	}
+}
+
+// wait 等待ready被关闭，并调用OnWait钩子和输出日志，ready是调用c的readyChan，start
+// 是开始等待的时间。maxWait大于0时最多等待这么长时间，超时返回ErrWaitTimeout；ctx不为
+// nil并且被取消时返回ctx.Err()；调用被ForgetAndNotify中止时返回ErrForgotten。c因为
+// WithoutSharedErrors被接替时继续等待接替的调用。返回最后等待的调用，调用者不能持有锁。
+func (g *Group) wait(ctx context.Context, c *call, key string, start int64, ready <-chan struct{}, maxWait time.Duration) (*call, error) {
+	var timeout <-chan time.Time
+	if maxWait > 0 {
+		timer := time.NewTimer(maxWait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	var cancel <-chan struct{}
+	if ctx != nil {
+		cancel = ctx.Done()
+	}
+	for {
+		select {
+		case <-ready:
+		case <-timeout:
+			return c, ErrWaitTimeout
+		case <-cancel:
+			return c, ctx.Err()
+		}
+		if c.aborted {
+			return c, ErrForgotten
+		}
+		if c.next == nil {
+			break
+		}
+		c, ready = g.successor(c)
+	}
+	h, l := g.hooks, g.logger
+	if l == nil && (h == nil || h.OnWait == nil) {
+		return c, nil
+	}
+	waited := time.Duration(g.now() - start)
+	if l != nil {
+		l.Logf("timesf: duplicate call for key %q suppressed, waited %v", key, waited)
+	}
+	if h != nil && h.OnWait != nil {
+		h.OnWait(key, waited)
+	}
+	return c, nil
+}
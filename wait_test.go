@@ -0,0 +1,182 @@
+package timesf
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDoWithWait(t *testing.T) {
+	var g Group
+	release := make(chan struct{})
+	started := make(chan struct{})
+	fn := func() (interface{}, error) {
+		close(started)
+		<-release
+		return "bar", nil
+	}
+	leader := make(chan interface{})
+	go func() {
+		v, _, _ := g.DoWithWait("key", time.Hour, time.Millisecond, fn)
+		leader <- v
+	}()
+	<-started
+
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	vals := make([]interface{}, 4)
+	for i, maxWait := range []time.Duration{time.Millisecond, 10 * time.Millisecond, 0, time.Hour} {
+		wg.Add(1)
+		go func(i int, maxWait time.Duration) {
+			defer wg.Done()
+			vals[i], errs[i], _ = g.DoWithWait("key", time.Hour, maxWait, fn)
+		}(i, maxWait)
+	}
+	// 等待前两个调用者超时
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	for i, want := range []error{ErrWaitTimeout, ErrWaitTimeout, nil, nil} {
+		if errs[i] != want {
+			t.Errorf("waiter %d err = %v; want %v", i, errs[i], want)
+		}
+	}
+	if vals[2] != "bar" || vals[3] != "bar" {
+		t.Errorf("waiter values = %v; want bar for the waiters that did not time out", vals)
+	}
+	// 执行方法的调用者不受maxWait限制，结果仍然被缓存
+	if v := <-leader; v != "bar" {
+		t.Errorf("leader value = %v; want bar", v)
+	}
+	if v, _, ok := g.Peek("key"); !ok || v != "bar" {
+		t.Errorf("Peek = %v, %v; want bar, true", v, ok)
+	}
+}
+
+func TestDoTimeout(t *testing.T) {
+	var g Group
+	release := make(chan struct{})
+	var calls int32
+	fn := func() (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return "bar", nil
+	}
+
+	// 执行方法的调用者和重复的调用者都会超时
+	owner := make(chan error)
+	go func() {
+		_, err, _ := g.DoTimeout("key", time.Hour, 10*time.Millisecond, fn)
+		owner <- err
+	}()
+	waitFor(t, func() bool { return atomic.LoadInt32(&calls) == 1 })
+	if _, err, _ := g.DoTimeout("key", time.Hour, 10*time.Millisecond, fn); err != ErrWaitTimeout {
+		t.Errorf("waiter err = %v; want ErrWaitTimeout", err)
+	}
+	if err := <-owner; err != ErrWaitTimeout {
+		t.Errorf("owner err = %v; want ErrWaitTimeout", err)
+	}
+
+	// 方法继续执行，完成后结果被缓存
+	if !g.Has("key") {
+		t.Error("timed-out owner removed the in-flight call")
+	}
+	close(release)
+	waitFor(t, func() bool {
+		_, _, ok := g.Peek("key")
+		return ok
+	})
+	if v, err, _ := g.DoTimeout("key", time.Hour, 10*time.Millisecond, fn); v != "bar" || err != nil {
+		t.Errorf("DoTimeout after completion = %v, %v; want bar, nil", v, err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("calls = %d; want 1", n)
+	}
+}
+
+func TestTryDo(t *testing.T) {
+	var g Group
+	release := make(chan struct{})
+	started := make(chan struct{})
+	ch := g.DoChan("key", time.Hour, func() (interface{}, error) {
+		close(started)
+		<-release
+		return "bar", nil
+	})
+	<-started
+
+	done := make(chan bool)
+	go func() {
+		v, err, ok := g.TryDo("key", time.Hour, nil)
+		done <- ok || v != nil || err != nil
+	}()
+	select {
+	case bad := <-done:
+		if bad {
+			t.Error("TryDo while in flight returned a result; want nil, nil, false")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("TryDo blocked behind the in-flight call")
+	}
+	if n := g.InFlight()["key"]; n != 0 {
+		t.Errorf("dups after TryDo = %d; want 0", n)
+	}
+
+	close(release)
+	<-ch
+	if v, err, ok := g.TryDo("key", time.Hour, nil); v != "bar" || err != nil || !ok {
+		t.Errorf("TryDo after completion = %v, %v, %v; want bar, nil, true", v, err, ok)
+	}
+	if v, _, ok := g.TryDo("cold", time.Hour, func() (interface{}, error) {
+		return "computed", nil
+	}); v != "computed" || !ok {
+		t.Errorf("TryDo on cold key = %v, %v; want computed, true", v, ok)
+	}
+}
+
+func TestDoChanShared(t *testing.T) {
+	var g Group
+	release := make(chan struct{})
+	leaderDone, leaderResult := g.DoChanShared("key", time.Minute, func() (interface{}, error) {
+		<-release
+		return "v", nil
+	})
+	done, result := g.DoChanShared("key", time.Minute, nil)
+	if done != leaderDone {
+		t.Error("sharers got different done channels")
+	}
+
+	select {
+	case <-done:
+		t.Fatal("done closed before the leader finished")
+	case <-time.After(10 * time.Millisecond):
+	}
+	close(release)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("done not closed after the leader finished")
+	}
+	if r := result(); r.Val != "v" || !r.Shared {
+		t.Errorf("result = %+v; want shared v", r)
+	}
+	if r := leaderResult(); r.Val != "v" || r.Role != RoleLeader {
+		t.Errorf("leader result = %+v; want v as leader", r)
+	}
+	if r := result(); r.Val != "v" {
+		t.Errorf("second result() = %+v; want the same v", r)
+	}
+
+	// 命中已完成的结果时done已经被关闭
+	done, result = g.DoChanShared("key", time.Minute, nil)
+	select {
+	case <-done:
+	default:
+		t.Error("done for a cached hit is not closed")
+	}
+	if r := result(); r.Val != "v" {
+		t.Errorf("cached result = %+v; want v", r)
+	}
+}
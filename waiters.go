@@ -0,0 +1,60 @@
+package timesf
+
+import "errors"
+
+// ErrTooManyWaiters 在key正在进行的调用已经有WithMaxWaiters设置的数量的重复调用者时，
+// 返回给之后的调用者。
+var ErrTooManyWaiters = errors.New("timesf: too many waiters for in-flight call")
+
+// WithMaxWaiters 限制每一个正在进行的调用最多有n个重复的调用者等待其结果，为0时不限制。
+// 超过之后的调用者不再等待，而是立即拿到ErrTooManyWaiters，并且不计入统计数据。执行
+// 方法的调用者不计入数量，调用完成之后不再限制。
+func WithMaxWaiters(n int) Option {
+	return func(g *Group) {
+		g.maxWaiters = n
+	}
+}
+
+// tooManyWaiters 报告正在进行的调用c是否已经有足够多的重复调用者，调用者需要持有锁。
+func (g *Group) tooManyWaiters(c *call) bool {
+	return g.maxWaiters > 0 && !c.done && c.dups >= g.maxWaiters
+}
+
+// WithHotKeyThreshold 在正在进行的调用的重复调用者超过n个时调用Hooks的OnHotKey钩子，
+// 每一次调用最多触发一次，用于及早发现热点key或者迟迟没有完成的调用。n不大于0时不检查。
+func WithHotKeyThreshold(n int) Option {
+	return func(g *Group) {
+		g.hotKeyThreshold = n
+	}
+}
+
+// crowded 在正在进行的调用c的重复调用者刚刚超过WithHotKeyThreshold时返回其数量，否则
+// 返回0。调用者需要持有锁，并在释放锁之后调用hotKey。
+func (g *Group) crowded(c *call) int {
+	if g.hotKeyThreshold <= 0 || c.hot || c.done || c.dups <= g.hotKeyThreshold {
+		return 0
+	}
+	c.hot = true
+	return c.dups
+}
+
+// hotKey 在dups大于0时调用OnHotKey钩子，调用者不能持有锁。
+func (g *Group) hotKey(key string, dups int) {
+	if h := g.hooks; dups > 0 && h != nil && h.OnHotKey != nil {
+		h.OnHotKey(key, dups)
+	}
+}
+
+// crowdedKey 是DoMulti释放锁之后需要触发OnHotKey的一个key。
+type crowdedKey struct {
+	key  string
+	dups int
+}
+
+// appendHot 在dups大于0时将key记录到hot中。
+func appendHot(hot []crowdedKey, key string, dups int) []crowdedKey {
+	if dups > 0 {
+		hot = append(hot, crowdedKey{key, dups})
+	}
+	return hot
+}
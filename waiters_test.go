@@ -0,0 +1,103 @@
+package timesf
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithMaxWaiters(t *testing.T) {
+	g := New(WithMaxWaiters(10))
+	release := make(chan struct{})
+	started := make(chan struct{})
+	fn := func() (interface{}, error) {
+		close(started)
+		<-release
+		return "bar", nil
+	}
+	owner := g.DoChan("key", time.Hour, fn)
+	<-started
+
+	const n = 100
+	var delivered, rejected int32
+	var wg sync.WaitGroup
+	for i := 0; i < n-1; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var err error
+			if i%2 == 0 {
+				_, err, _ = g.Do("key", time.Hour, fn)
+			} else {
+				err = (<-g.DoChan("key", time.Hour, fn)).Err
+			}
+			switch err {
+			case nil:
+				atomic.AddInt32(&delivered, 1)
+			case ErrTooManyWaiters:
+				atomic.AddInt32(&rejected, 1)
+			default:
+				t.Errorf("err = %v", err)
+			}
+		}(i)
+	}
+	// 被拒绝的调用者不需要等待调用完成
+	waitFor(t, func() bool { return atomic.LoadInt32(&rejected) == n-11 })
+	close(release)
+	wg.Wait()
+	if r := <-owner; r.Err == nil {
+		delivered++
+	}
+	if delivered != 11 || rejected != n-11 {
+		t.Errorf("delivered, rejected = %d, %d; want 11, %d", delivered, rejected, n-11)
+	}
+
+	// 调用完成之后不再限制
+	for i := 0; i < 20; i++ {
+		if _, err, _ := g.Do("key", time.Hour, fn); err != nil {
+			t.Errorf("Do after completion err = %v", err)
+		}
+	}
+}
+
+func TestHotKeyThreshold(t *testing.T) {
+	type hotKey struct {
+		key  string
+		dups int
+	}
+	hot := make(chan hotKey, 10)
+	g := New(WithHotKeyThreshold(3), WithHooks(Hooks{
+		OnHotKey: func(key string, dups int) {
+			hot <- hotKey{key, dups}
+		},
+	}))
+	release := make(chan struct{})
+	ch := g.DoChan("key", time.Hour, func() (interface{}, error) {
+		<-release
+		return nil, nil
+	})
+	var chans []<-chan Result
+	for i := 0; i < 3; i++ {
+		chans = append(chans, g.DoChan("key", time.Hour, nil))
+	}
+	if len(hot) != 0 {
+		t.Fatalf("OnHotKey fired at the threshold: %v", <-hot)
+	}
+	go g.Do("key", time.Hour, nil)
+	if h := <-hot; h != (hotKey{"key", 4}) {
+		t.Errorf("OnHotKey = %+v; want key with 4 dups", h)
+	}
+	// 每一次调用只触发一次
+	for i := 0; i < 3; i++ {
+		chans = append(chans, g.DoChan("key", time.Hour, nil))
+	}
+	close(release)
+	<-ch
+	for _, c := range chans {
+		<-c
+	}
+	if len(hot) != 0 {
+		t.Errorf("OnHotKey fired again: %v", <-hot)
+	}
+}
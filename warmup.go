@@ -0,0 +1,37 @@
+package timesf
+
+import (
+	"sync"
+	"time"
+)
+
+// Warmup 提前获取keys的结果并保存，比如在服务启动时填充热点key。最多同时为maxParallel个
+// key执行fn，maxParallel小于1时不限制。每一个key都通过DoKey获取，同时进行的其他调用者
+// 和Warmup共享同一个调用，还未过期的结果不会被重新获取。返回获取失败的key的错误，全部
+// 成功时返回空的map。
+func (g *Group) Warmup(keys []string, validTime time.Duration, maxParallel int, fn func(key string) (interface{}, error)) map[string]error {
+	if maxParallel < 1 || maxParallel > len(keys) {
+		maxParallel = len(keys)
+	}
+	errs := make(map[string]error)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxParallel)
+	for _, key := range keys {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(key string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if _, err, _ := g.DoKey(key, validTime, fn); err != nil {
+				mu.Lock()
+				errs[key] = err
+				mu.Unlock()
+			}
+		}(key)
+	}
+	wg.Wait()
+	return errs
+}
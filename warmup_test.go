@@ -0,0 +1,59 @@
+package timesf
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWarmup(t *testing.T) {
+	g := New()
+	errBoom := errors.New("boom")
+	var mu sync.Mutex
+	calls := make(map[string]int)
+	var running, peak int32
+	fn := func(key string) (interface{}, error) {
+		cur := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if cur <= p || atomic.CompareAndSwapInt32(&peak, p, cur) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		mu.Lock()
+		calls[key]++
+		mu.Unlock()
+		if key == "bad" {
+			return nil, errBoom
+		}
+		return "v" + key, nil
+	}
+
+	keys := []string{"a", "b", "c", "d", "e", "a", "bad"}
+	errs := g.Warmup(keys, time.Hour, 2, fn)
+	if len(errs) != 1 || errs["bad"] != errBoom {
+		t.Errorf("Warmup errs = %v; want only bad: boom", errs)
+	}
+	if p := atomic.LoadInt32(&peak); p > 2 {
+		t.Errorf("peak concurrent computes = %d; want at most 2", p)
+	}
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		if calls[key] != 1 {
+			t.Errorf("calls[%q] = %d; want 1", key, calls[key])
+		}
+		v, err, _ := g.Do(key, time.Hour, func() (interface{}, error) {
+			t.Errorf("Do(%q) recomputed after Warmup", key)
+			return nil, nil
+		})
+		if v != "v"+key || err != nil {
+			t.Errorf("Do(%q) = %v, %v; want v%s, nil", key, v, err, key)
+		}
+	}
+	if st := g.Stats(); st.Hits < 5 {
+		t.Errorf("Hits = %d; want at least 5", st.Hits)
+	}
+}